@@ -61,7 +61,7 @@ export const fetchTweetsFromServer = async () => {
     if (response.status === 200) {
       const data = await response.data;
       console.log('data:', data);
-      return data;
+      return data.tweets || [];
     }
     else {
       console.error('Failed to fetch tweets.');
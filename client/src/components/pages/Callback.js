@@ -1,16 +1,36 @@
 import React, { useEffect } from 'react';
 import CallbackStyles from "../../styles/pages/Callback.module.css"
-import { useNavigate } from 'react-router-dom';
+import { Link, useNavigate, useSearchParams } from 'react-router-dom';
 import { useAuth } from '../auth/AuthContext.tsx';
 
+const loginErrors = {
+    invalid_state: 'The login request could not be verified. Please try again.',
+    access_denied: 'Access was denied by the identity provider.',
+    login_failed: 'Login failed. Please try again.',
+};
+
 function Callback() {
     const { checkAuth } = useAuth();
     const navigate = useNavigate();
+    const [searchParams] = useSearchParams();
+    const error = searchParams.get('error');
 
     useEffect(() => {
+        if (error) {
+            return;
+        }
+
         checkAuth();
         navigate("/"); // Redirect to root
-    }, [checkAuth, navigate]);
+    }, [checkAuth, navigate, error]);
+
+    if (error) {
+        return (
+            <div className={CallbackStyles.container}>
+                {loginErrors[error] || loginErrors.login_failed} <Link to="/account/login">Back to login</Link>
+            </div>
+        );
+    }
 
     return (
         <div className={CallbackStyles.container}>
@@ -19,4 +39,4 @@ function Callback() {
     );
 };
 
-export default Callback;
\ No newline at end of file
+export default Callback;
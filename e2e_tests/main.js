@@ -87,8 +87,8 @@ describe('E2E Tweet API Tests', () => {
             });
 
             expect(response.status).to.equal(200);
-            expect(response.data).to.be.an('array');
-            expect(response.data.some(tweet => tweet.id === createdTweetId)).to.be.true;
+            expect(response.data).to.have.property('tweets').that.is.an('array');
+            expect(response.data.tweets.some(tweet => tweet.id === createdTweetId)).to.be.true;
         } catch (error) {
             console.error("Error retrieving all tweets: ", error.response ? error.response.data : error.message);
             throw error;
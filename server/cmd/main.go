@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"twitter-clone/internal/api"
 	"twitter-clone/internal/authn"
 	"twitter-clone/internal/config"
@@ -12,6 +14,22 @@ import (
 func main() {
 	configuration := config.ReadConfiguration()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(configuration, os.Args[2:]); err != nil {
+			fmt.Println("Failed to migrate tweets database: ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-feeds" {
+		if err := runRebuildFeeds(configuration, os.Args[2:]); err != nil {
+			fmt.Println("Failed to rebuild feeds: ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	tweetRepo, err := repositories.CreateTweetRepository(configuration)
 	if err != nil {
 		fmt.Println("Failed to create tweet repository: ", err)
@@ -24,15 +42,37 @@ func main() {
 		return
 	}
 
+	tokenRepo, err := repositories.CreateTokenRepository(configuration)
+	if err != nil {
+		fmt.Println("Failed to create token repository: ", err)
+		return
+	}
+
+	eventStore, err := repositories.CreateEventStore(configuration)
+	if err != nil {
+		fmt.Println("Failed to create event store: ", err)
+		return
+	}
+
+	deadLetterStore, err := repositories.CreateDeadLetterStore(configuration)
+	if err != nil {
+		fmt.Println("Failed to create dead letter store: ", err)
+		return
+	}
+
 	messageHandler, err := messaging.CreateMessageHandler(configuration)
 	if err != nil {
 		fmt.Println("Failed to create message handler: ", err)
 		return
 	}
 
-	authenticationValidator := authn.AuthenticationValidator{
-		Authentication: configuration.Authentication,
+	identityProviders, err := authn.NewIdentityProviders(context.Background(), configuration.Authentication)
+	if err != nil {
+		fmt.Println("Failed to create identity providers: ", err)
+		return
 	}
 
-	api.StartRouter(configuration, tweetRepo, feedRepo, messageHandler, authenticationValidator)
+	authenticationValidator := authn.NewAuthenticationValidator(configuration.Authentication, identityProviders, tokenRepo)
+
+	api.StartRouter(configuration, tweetRepo, feedRepo, tokenRepo, eventStore, deadLetterStore, messageHandler, identityProviders, authenticationValidator)
 }
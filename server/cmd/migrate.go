@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/repositories/mysqldb"
+)
+
+const migrateUsage = `usage: server migrate <command> [flags]
+
+Commands:
+  up              apply every pending migration
+  down [-steps n] roll back the latest n applied migrations (default 1)
+  status          list the migrations and whether they are applied`
+
+// runMigrate applies or rolls back the schema migrations of the mysql tweets storage
+func runMigrate(configuration config.Configuration, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", migrateUsage)
+	}
+
+	if configuration.TweetsStorage.Driver != config.MySQLDriver {
+		return fmt.Errorf("migrations are only supported by the %q tweets storage driver, got %q", config.MySQLDriver, configuration.TweetsStorage.Driver)
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	db, err := mysqldb.Open(configuration.TweetsStorage.ConnectionString, configuration.TweetsStorage.DatabaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := mysqldb.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migrations\n", len(applied))
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("steps must be at least 1, got %d", *steps)
+		}
+		rolledBack, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migrations\n", len(rolledBack))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/feedrebuild"
+	"twitter-clone/internal/repositories"
+)
+
+// runRebuildFeeds recomputes the feeds from the stored tweets and prints the changes
+func runRebuildFeeds(configuration config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("rebuild-feeds", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only print the changes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	tweetRepo, err := repositories.CreateTweetRepository(configuration)
+	if err != nil {
+		return err
+	}
+
+	feedRepo, err := repositories.CreateFeedRepository(configuration)
+	if err != nil {
+		return err
+	}
+
+	report, err := feedrebuild.Rebuild(context.Background(), tweetRepo, feedRepo, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range report.Changes {
+		fmt.Printf("%s\t%s\t%s\n", change.Action, change.Feed, change.TweetID)
+	}
+
+	summary := fmt.Sprintf("%d changes after comparing %d feeds with %d tweets", len(report.Changes), report.Feeds, report.Tweets)
+	if report.DryRun {
+		fmt.Println(summary + ", nothing was changed")
+	} else {
+		fmt.Println("Applied " + summary)
+	}
+	return nil
+}
@@ -5,8 +5,10 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 	models "twitter-clone/internal/models"
+	repositories "twitter-clone/internal/repositories/tweet"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,57 +37,148 @@ func (m *MockTweetRepository) EXPECT() *MockTweetRepositoryMockRecorder {
 }
 
 // CreateTweet mocks base method.
-func (m *MockTweetRepository) CreateTweet(tweet models.CreateTweetRequest, user models.User) *models.Tweet {
+func (m *MockTweetRepository) CreateTweet(ctx context.Context, tweet models.CreateTweetRequest, user models.User) (*models.Tweet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTweet", tweet, user)
+	ret := m.ctrl.Call(m, "CreateTweet", ctx, tweet, user)
 	ret0, _ := ret[0].(*models.Tweet)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CreateTweet indicates an expected call of CreateTweet.
-func (mr *MockTweetRepositoryMockRecorder) CreateTweet(tweet, user interface{}) *gomock.Call {
+func (mr *MockTweetRepositoryMockRecorder) CreateTweet(ctx, tweet, user interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTweet", reflect.TypeOf((*MockTweetRepository)(nil).CreateTweet), tweet, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTweet", reflect.TypeOf((*MockTweetRepository)(nil).CreateTweet), ctx, tweet, user)
+}
+
+// DeleteOutboxEvents mocks base method.
+func (m *MockTweetRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOutboxEvents", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOutboxEvents indicates an expected call of DeleteOutboxEvents.
+func (mr *MockTweetRepositoryMockRecorder) DeleteOutboxEvents(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOutboxEvents", reflect.TypeOf((*MockTweetRepository)(nil).DeleteOutboxEvents), ctx, ids)
 }
 
 // DeleteTweet mocks base method.
-func (m *MockTweetRepository) DeleteTweet(id string) bool {
+func (m *MockTweetRepository) DeleteTweet(ctx context.Context, id string, check repositories.TweetCheck) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteTweet", id)
-	ret0, _ := ret[0].(bool)
+	ret := m.ctrl.Call(m, "DeleteTweet", ctx, id, check)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteTweet indicates an expected call of DeleteTweet.
-func (mr *MockTweetRepositoryMockRecorder) DeleteTweet(id interface{}) *gomock.Call {
+func (mr *MockTweetRepositoryMockRecorder) DeleteTweet(ctx, id, check interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTweet", reflect.TypeOf((*MockTweetRepository)(nil).DeleteTweet), ctx, id, check)
+}
+
+// ForwardOutboxEvents mocks base method.
+func (m *MockTweetRepository) ForwardOutboxEvents(ctx context.Context, limit int, forward func([]models.OutboxEvent) ([]string, error)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForwardOutboxEvents", ctx, limit, forward)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForwardOutboxEvents indicates an expected call of ForwardOutboxEvents.
+func (mr *MockTweetRepositoryMockRecorder) ForwardOutboxEvents(ctx, limit, forward interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForwardOutboxEvents", reflect.TypeOf((*MockTweetRepository)(nil).ForwardOutboxEvents), ctx, limit, forward)
+}
+
+// GetOutboxEvents mocks base method.
+func (m *MockTweetRepository) GetOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutboxEvents", ctx, limit)
+	ret0, _ := ret[0].([]models.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutboxEvents indicates an expected call of GetOutboxEvents.
+func (mr *MockTweetRepositoryMockRecorder) GetOutboxEvents(ctx, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTweet", reflect.TypeOf((*MockTweetRepository)(nil).DeleteTweet), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxEvents", reflect.TypeOf((*MockTweetRepository)(nil).GetOutboxEvents), ctx, limit)
 }
 
 // GetTweetById mocks base method.
-func (m *MockTweetRepository) GetTweetById(id string) *models.Tweet {
+func (m *MockTweetRepository) GetTweetById(ctx context.Context, id string) (*models.Tweet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTweetById", id)
+	ret := m.ctrl.Call(m, "GetTweetById", ctx, id)
 	ret0, _ := ret[0].(*models.Tweet)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetTweetById indicates an expected call of GetTweetById.
-func (mr *MockTweetRepositoryMockRecorder) GetTweetById(id interface{}) *gomock.Call {
+func (mr *MockTweetRepositoryMockRecorder) GetTweetById(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTweetById", reflect.TypeOf((*MockTweetRepository)(nil).GetTweetById), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTweetById", reflect.TypeOf((*MockTweetRepository)(nil).GetTweetById), ctx, id)
 }
 
 // GetTweets mocks base method.
-func (m *MockTweetRepository) GetTweets() []models.Tweet {
+func (m *MockTweetRepository) GetTweets(ctx context.Context) ([]models.Tweet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTweets")
+	ret := m.ctrl.Call(m, "GetTweets", ctx)
 	ret0, _ := ret[0].([]models.Tweet)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetTweets indicates an expected call of GetTweets.
-func (mr *MockTweetRepositoryMockRecorder) GetTweets() *gomock.Call {
+func (mr *MockTweetRepositoryMockRecorder) GetTweets(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTweets", reflect.TypeOf((*MockTweetRepository)(nil).GetTweets), ctx)
+}
+
+// GetTweetsByTag mocks base method.
+func (m *MockTweetRepository) GetTweetsByTag(ctx context.Context, tag string, page models.PageRequest) (*models.TweetsPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTweetsByTag", ctx, tag, page)
+	ret0, _ := ret[0].(*models.TweetsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTweetsByTag indicates an expected call of GetTweetsByTag.
+func (mr *MockTweetRepositoryMockRecorder) GetTweetsByTag(ctx, tag, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTweetsByTag", reflect.TypeOf((*MockTweetRepository)(nil).GetTweetsByTag), ctx, tag, page)
+}
+
+// GetTweetsPage mocks base method.
+func (m *MockTweetRepository) GetTweetsPage(ctx context.Context, page models.PageRequest) (*models.TweetsPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTweetsPage", ctx, page)
+	ret0, _ := ret[0].(*models.TweetsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTweetsPage indicates an expected call of GetTweetsPage.
+func (mr *MockTweetRepositoryMockRecorder) GetTweetsPage(ctx, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTweetsPage", reflect.TypeOf((*MockTweetRepository)(nil).GetTweetsPage), ctx, page)
+}
+
+// UpdateTweet mocks base method.
+func (m *MockTweetRepository) UpdateTweet(ctx context.Context, id string, tweet models.UpdateTweetRequest, check repositories.TweetCheck) (*models.Tweet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTweet", ctx, id, tweet, check)
+	ret0, _ := ret[0].(*models.Tweet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTweet indicates an expected call of UpdateTweet.
+func (mr *MockTweetRepositoryMockRecorder) UpdateTweet(ctx, id, tweet, check interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTweets", reflect.TypeOf((*MockTweetRepository)(nil).GetTweets))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTweet", reflect.TypeOf((*MockTweetRepository)(nil).UpdateTweet), ctx, id, tweet, check)
 }
@@ -1,9 +1,11 @@
 package api
 
 import (
-	"encoding/json"
 	"net/http"
+	"strconv"
+	"twitter-clone/internal/hashtags"
 	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
 	feedrepo "twitter-clone/internal/repositories/feed"
 	repositories "twitter-clone/internal/repositories/tweet"
 	tweetrepo "twitter-clone/internal/repositories/tweet"
@@ -19,16 +21,18 @@ type FeedStreamAdapter struct {
 }
 
 func (adapter FeedStreamAdapter) GetResponse(w http.ResponseWriter, r *http.Request) (response interface{}, ok bool) {
-	feedName := chi.URLParam(r, "name")
+	feedName := feedNameParam(r)
 
-	feed, err := adapter.repo.GetFeedByName(feedName)
+	page, err := parsePageRequest(r)
 	if err != nil {
-		logAndWriteError(adapter.logger, w, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return nil, false
 	}
 
-	if feed == nil {
-		w.WriteHeader(404)
+	feed, err := adapter.repo.GetFeedPage(r.Context(), feedName, page)
+	if err != nil {
+		writeRepositoryError(adapter.logger, w, err)
+		return nil, false
 	}
 
 	return feed, true
@@ -37,14 +41,17 @@ func (adapter FeedStreamAdapter) GetResponse(w http.ResponseWriter, r *http.Requ
 func (f FeedStreamAdapter) Validate(r *http.Request, msg *message.Message) (ok bool) {
 	feedUpdated := messaging.FeedUpdated{}
 
-	err := json.Unmarshal(msg.Payload, &feedUpdated)
+	err := messaging.DecodeMessage(msg, messaging.FeedUpdatedTopic, &feedUpdated)
 	if err != nil {
 		return false
 	}
 
-	feedName := chi.URLParam(r, "name")
+	return feedUpdated.Name == feedNameParam(r)
+}
 
-	return feedUpdated.Name == feedName
+// feedNameParam normalizes the feed name like the tags of the tweets, so /feeds/Go is the go feed
+func feedNameParam(r *http.Request) string {
+	return hashtags.Normalize(chi.URLParam(r, "name"))
 }
 
 type TweetStreamAdapter struct {
@@ -55,9 +62,9 @@ type TweetStreamAdapter struct {
 func (adapter TweetStreamAdapter) GetResponse(w http.ResponseWriter, r *http.Request) (response interface{}, ok bool) {
 	tweetID := chi.URLParam(r, "tweetId")
 
-	tweet := adapter.repo.GetTweetById(tweetID)
-	if tweet == nil {
-		w.WriteHeader(404)
+	tweet, err := adapter.repo.GetTweetById(r.Context(), tweetID)
+	if err != nil {
+		writeRepositoryError(adapter.logger, w, err)
 		return nil, false
 	}
 
@@ -67,7 +74,7 @@ func (adapter TweetStreamAdapter) GetResponse(w http.ResponseWriter, r *http.Req
 func (adapter TweetStreamAdapter) Validate(r *http.Request, msg *message.Message) (ok bool) {
 	postUpdated := messaging.TweetUpdated{}
 
-	err := json.Unmarshal(msg.Payload, &postUpdated)
+	err := messaging.DecodeMessage(msg, messaging.TweetUpdatedTopic, &postUpdated)
 	if err != nil {
 		return false
 	}
@@ -92,9 +99,9 @@ type AllFeedsStreamAdapter struct {
 }
 
 func (adapter AllFeedsStreamAdapter) GetResponse(w http.ResponseWriter, r *http.Request) (interface{}, bool) {
-	feeds, err := adapter.repo.GetFeeds()
+	feeds, err := adapter.repo.GetFeeds(r.Context())
 	if err != nil {
-		logAndWriteError(adapter.logger, w, err)
+		writeRepositoryError(adapter.logger, w, err)
 		return nil, false
 	}
 
@@ -117,12 +124,38 @@ func (f AllFeedsStreamAdapter) Validate(r *http.Request, msg *message.Message) (
 }
 
 type AllTweetsStreamAdapter struct {
-	repo repositories.TweetRepository
+	repo   repositories.TweetRepository
+	logger watermill.LoggerAdapter
 }
 
 func (adapter AllTweetsStreamAdapter) GetResponse(w http.ResponseWriter, r *http.Request) (interface{}, bool) {
-	tweets := adapter.repo.GetTweets()
-	return tweets, true
+	page, err := parsePageRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	// The tag query parameter narrows the tweets down to those carrying the tag, normalized like the
+	// tags of the tweets
+	rawTag := r.URL.Query().Get("tag")
+	tag := hashtags.Normalize(rawTag)
+	if rawTag != "" && tag == "" {
+		http.Error(w, "tag must contain more than a #, got "+strconv.Quote(rawTag), http.StatusBadRequest)
+		return nil, false
+	}
+
+	var tweetsPage *models.TweetsPage
+	if tag != "" {
+		tweetsPage, err = adapter.repo.GetTweetsByTag(r.Context(), tag, page)
+	} else {
+		tweetsPage, err = adapter.repo.GetTweetsPage(r.Context(), page)
+	}
+	if err != nil {
+		writeRepositoryError(adapter.logger, w, err)
+		return nil, false
+	}
+
+	return tweetsPage, true
 }
 
 func (f AllTweetsStreamAdapter) Validate(r *http.Request, msg *message.Message) (ok bool) {
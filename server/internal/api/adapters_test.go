@@ -0,0 +1,71 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"twitter-clone/internal/api"
+	"twitter-clone/internal/models"
+	feedrepo "twitter-clone/internal/repositories/feed"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFeedAndTagNormalization tests that feed names and tag queries are normalized like the tags of the tweets.
+func TestFeedAndTagNormalization(t *testing.T) {
+	ctx := context.Background()
+	tweetRepo := &tweetrepo.InMemoryTweetRepository{}
+	feedRepo := &feedrepo.InMemoryFeedRepository{}
+
+	tweet, err := tweetRepo.CreateTweet(ctx, models.CreateTweetRequest{Content: "Hello #Go"}, tweetrepo.TestUser)
+	require.NoError(t, err)
+	_, err = tweetRepo.CreateTweet(ctx, models.CreateTweetRequest{Content: "untagged"}, tweetrepo.TestUser)
+	require.NoError(t, err)
+	require.NoError(t, feedRepo.CreateFeed(ctx, "go"))
+	_, err = feedRepo.AppendTweet(ctx, *tweet)
+	require.NoError(t, err)
+
+	pubSub := gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{})
+	defer pubSub.Close()
+
+	mux := api.Router{
+		Subscriber: pubSub,
+		TweetRepo:  tweetRepo,
+		FeedRepo:   feedRepo,
+		Logger:     watermill.NopLogger{},
+	}.Mux()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		return rr
+	}
+
+	for _, target := range []string{"/api/feeds/go", "/api/feeds/Go", "/api/feeds/%23GO"} {
+		rr := get(target)
+		require.Equal(t, http.StatusOK, rr.Code, target)
+		var feed models.FeedPage
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &feed))
+		assert.Equal(t, "go", feed.Name, target)
+		require.Len(t, feed.Tweets, 1, target)
+		assert.Equal(t, tweet.ID, feed.Tweets[0].ID, target)
+	}
+
+	for _, target := range []string{"/api/tweets?tag=go", "/api/tweets?tag=GO", "/api/tweets?tag=%23Go"} {
+		rr := get(target)
+		require.Equal(t, http.StatusOK, rr.Code, target)
+		var page models.TweetsPage
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+		require.Len(t, page.Tweets, 1, target)
+		assert.Equal(t, tweet.ID, page.Tweets[0].ID, target)
+	}
+
+	rr := get("/api/tweets?tag=%23")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+	"twitter-clone/internal/authn"
+	"twitter-clone/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+func (router Router) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := router.authorizeAPITokens(w, r)
+	if user == nil {
+		return
+	}
+
+	var createAPITokenRequest models.CreateAPITokenRequest
+	err := render.Decode(r, &createAPITokenRequest)
+	if err != nil {
+		logAndWriteError(router.Logger, w, err)
+		return
+	}
+
+	if err := validateCreateAPITokenRequest(createAPITokenRequest); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, hash := authn.GenerateAPIToken()
+	apiToken := models.APIToken{
+		ID:        uuid.NewString(),
+		Name:      createAPITokenRequest.Name,
+		Scopes:    createAPITokenRequest.Scopes,
+		User:      *user,
+		Hash:      hash,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if createAPITokenRequest.ExpiresInDays > 0 {
+		expiresAt := apiToken.CreatedAt.AddDate(0, 0, createAPITokenRequest.ExpiresInDays)
+		apiToken.ExpiresAt = &expiresAt
+	}
+
+	createdToken, err := router.TokenRepo.CreateToken(r.Context(), apiToken)
+	if err != nil {
+		writeRepositoryError(router.Logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(models.CreatedAPIToken{APIToken: *createdToken, Token: token}); err != nil {
+		logAndWriteError(router.Logger, w, err)
+	}
+}
+
+func (router Router) GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	user := router.authorizeAPITokens(w, r)
+	if user == nil {
+		return
+	}
+
+	tokens, err := router.TokenRepo.GetTokensByUser(r.Context(), user.ID)
+	if err != nil {
+		writeRepositoryError(router.Logger, w, err)
+		return
+	}
+	if tokens == nil {
+		tokens = []models.APIToken{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tokens); err != nil {
+		logAndWriteError(router.Logger, w, err)
+	}
+}
+
+func (router Router) DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := router.authorizeAPITokens(w, r)
+	if user == nil {
+		return
+	}
+
+	// Tokens of other users are reported as missing
+	err := router.TokenRepo.DeleteToken(r.Context(), chi.URLParam(r, "tokenId"), user.ID)
+	if err != nil {
+		writeRepositoryError(router.Logger, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizeAPITokens authenticates the user and checks that they may manage their API tokens
+func (router Router) authorizeAPITokens(w http.ResponseWriter, r *http.Request) *models.User {
+	user := router.AuthenticationValidator.ValidateAuthentication(w, r)
+	if user == nil {
+		return nil
+	}
+
+	if !router.Authorizer.CanManageAPITokens(*user) {
+		w.WriteHeader(http.StatusForbidden)
+		return nil
+	}
+
+	return user
+}
+
+func validateCreateAPITokenRequest(createAPITokenRequest models.CreateAPITokenRequest) error {
+	if createAPITokenRequest.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	for _, scope := range createAPITokenRequest.Scopes {
+		if !slices.Contains(models.APITokenScopes, scope) {
+			return fmt.Errorf("unknown scope %q, expected one of %v", scope, models.APITokenScopes)
+		}
+	}
+
+	if createAPITokenRequest.ExpiresInDays < 0 {
+		return fmt.Errorf("expires_in_days must not be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	authnmock "twitter-clone/internal/__mocks__/authn"
+	tweetmock "twitter-clone/internal/__mocks__/repositories/tweet"
+	"twitter-clone/internal/api"
+	"twitter-clone/internal/authn"
+	"twitter-clone/internal/authz"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAPITokensRouter(validator authn.IAuthenticationValidator, tokenRepo tweetrepo.TokenRepository) api.Router {
+	return api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: validator,
+		Authorizer:              authz.Authorizer{},
+		TokenRepo:               tokenRepo,
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+}
+
+// TestAPITokens tests creating, listing and revoking personal API tokens.
+func TestAPITokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&tweetAuthor).AnyTimes()
+
+	tokenRepo := &tweetrepo.InMemoryTokenRepository{}
+	router := newAPITokensRouter(mockAuthValidator, tokenRepo)
+
+	// Create a token
+	body, _ := json.Marshal(models.CreateAPITokenRequest{Name: "bot", Scopes: []string{models.ScopeTweetsWrite}, ExpiresInDays: 30})
+	req := httptest.NewRequest("POST", "/api/tokens", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.CreateAPIToken(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var createdToken models.CreatedAPIToken
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &createdToken))
+	assert.True(t, authn.IsAPIToken(createdToken.Token))
+	assert.NotNil(t, createdToken.ExpiresAt)
+
+	// Only the hash is stored
+	storedToken, err := tokenRepo.GetTokenByHash(context.Background(), authn.HashAPIToken(createdToken.Token))
+	require.NoError(t, err)
+	assert.Equal(t, tweetAuthor.ID, storedToken.User.ID)
+	assert.NotContains(t, storedToken.Hash, createdToken.Token)
+
+	// List tokens, the token itself is not returned again
+	rr = httptest.NewRecorder()
+	router.GetAPITokens(rr, httptest.NewRequest("GET", "/api/tokens", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), createdToken.Token)
+	var tokens []models.APIToken
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &tokens))
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "bot", tokens[0].Name)
+
+	// Revoke the token
+	rr = httptest.NewRecorder()
+	router.DeleteAPIToken(rr, withURLParam(httptest.NewRequest("DELETE", "/api/tokens/"+createdToken.ID, nil), "tokenId", createdToken.ID))
+
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	_, err = tokenRepo.GetTokenByHash(context.Background(), authn.HashAPIToken(createdToken.Token))
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound)
+}
+
+// TestDeleteAPITokenOfOtherUser tests that users cannot revoke tokens of others.
+func TestDeleteAPITokenOfOtherUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&otherUser)
+
+	tokenRepo := &tweetrepo.InMemoryTokenRepository{}
+	tokenRepo.CreateToken(context.Background(), models.APIToken{ID: "token1", Hash: "hash", User: tweetAuthor})
+	router := newAPITokensRouter(mockAuthValidator, tokenRepo)
+
+	rr := httptest.NewRecorder()
+	router.DeleteAPIToken(rr, withURLParam(httptest.NewRequest("DELETE", "/api/tokens/token1", nil), "tokenId", "token1"))
+
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	_, err := tokenRepo.GetTokenByHash(context.Background(), "hash")
+	assert.NoError(t, err)
+}
+
+// TestCreateAPITokenValidation tests that invalid token requests and token authenticated users are rejected.
+func TestCreateAPITokenValidation(t *testing.T) {
+	testCases := []struct {
+		name         string
+		user         models.User
+		request      models.CreateAPITokenRequest
+		expectedCode int
+	}{
+		{name: "missing name", user: tweetAuthor, request: models.CreateAPITokenRequest{}, expectedCode: http.StatusBadRequest},
+		{name: "unknown scope", user: tweetAuthor, request: models.CreateAPITokenRequest{Name: "bot", Scopes: []string{"admin"}}, expectedCode: http.StatusBadRequest},
+		{name: "negative expiry", user: tweetAuthor, request: models.CreateAPITokenRequest{Name: "bot", ExpiresInDays: -1}, expectedCode: http.StatusBadRequest},
+		{name: "authenticated with API token", user: withAPIToken(tweetAuthor), request: models.CreateAPITokenRequest{Name: "bot"}, expectedCode: http.StatusForbidden},
+		{name: "anonymous when authentication is disabled", user: models.User{IsAnonymous: true}, request: models.CreateAPITokenRequest{Name: "bot"}, expectedCode: http.StatusForbidden},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+			mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&testCase.user)
+
+			tokenRepo := &tweetrepo.InMemoryTokenRepository{}
+			router := newAPITokensRouter(mockAuthValidator, tokenRepo)
+
+			body, _ := json.Marshal(testCase.request)
+			req := httptest.NewRequest("POST", "/api/tokens", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.CreateAPIToken(rr, req)
+
+			require.Equal(t, testCase.expectedCode, rr.Code)
+			tokens, err := tokenRepo.GetTokensByUser(context.Background(), testCase.user.ID)
+			assert.NoError(t, err)
+			assert.Empty(t, tokens)
+		})
+	}
+}
+
+// TestCreateTweetWithoutWriteScope tests that API tokens without the write scope cannot tweet.
+func TestCreateTweetWithoutWriteScope(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := withAPIToken(tweetAuthor, models.ScopeTweetsDelete)
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&user)
+
+	router := newAPITokensRouter(mockAuthValidator, nil)
+	router.TweetRepo = tweetmock.NewMockTweetRepository(ctrl) // No calls expected
+
+	req := httptest.NewRequest("POST", "/api/tweets", strings.NewReader(`{"content":"Hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.CreateTweet(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
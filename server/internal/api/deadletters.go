@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"twitter-clone/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (router Router) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	user := router.authorizeDeadLetters(w, r)
+	if user == nil {
+		return
+	}
+
+	deadLetters, err := router.DeadLetters.List(r.Context())
+	if err != nil {
+		writeRepositoryError(router.Logger, w, err)
+		return
+	}
+	if deadLetters == nil {
+		deadLetters = []models.DeadLetter{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(deadLetters); err != nil {
+		logAndWriteError(router.Logger, w, err)
+	}
+}
+
+func (router Router) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	user := router.authorizeDeadLetters(w, r)
+	if user == nil {
+		return
+	}
+
+	err := router.DeadLetters.Replay(r.Context(), chi.URLParam(r, "deadLetterId"))
+	if err != nil {
+		writeRepositoryError(router.Logger, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// authorizeDeadLetters authenticates the user and checks that they may manage dead letters
+func (router Router) authorizeDeadLetters(w http.ResponseWriter, r *http.Request) *models.User {
+	user := router.AuthenticationValidator.ValidateAuthentication(w, r)
+	if user == nil {
+		return nil
+	}
+
+	if !router.Authorizer.CanManageDeadLetters(*user) {
+		w.WriteHeader(http.StatusForbidden)
+		return nil
+	}
+
+	return user
+}
@@ -0,0 +1,110 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	authnmock "twitter-clone/internal/__mocks__/authn"
+	"twitter-clone/internal/api"
+	"twitter-clone/internal/authz"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeadLetters tests listing and replaying dead letters as an admin.
+func TestDeadLetters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	admin := models.User{ID: "admin-id", Email: "admin@gmail.com"}
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&admin).AnyTimes()
+
+	pubSub := gochannel.NewGoChannel(gochannel.Config{Persistent: true}, watermill.NopLogger{})
+	store := &tweetrepo.InMemoryDeadLetterStore{}
+	deadLetters := messaging.NewDeadLetterQueue(store, pubSub)
+
+	poisoned := message.NewMessage("message1", []byte(`{"tweet":{"id":"tweet1"}}`))
+	poisoned.Metadata.Set(middleware.PoisonedTopicKey, messaging.TweetCreatedTopic)
+	poisoned.Metadata.Set(middleware.ReasonForPoisonedKey, "feed storage is down")
+	require.NoError(t, store.AddDeadLetter(context.Background(), messaging.NewDeadLetter(poisoned)))
+
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{Authorization: config.Authorization{Admins: []string{"admin@gmail.com"}}},
+		DeadLetters:             deadLetters,
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	// List dead letters
+	rr := httptest.NewRecorder()
+	router.GetDeadLetters(rr, httptest.NewRequest("GET", "/api/admin/dead-letters", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var listed []models.DeadLetter
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, "message1", listed[0].ID)
+	assert.Equal(t, messaging.TweetCreatedTopic, listed[0].Topic)
+	assert.Equal(t, "feed storage is down", listed[0].Reason)
+
+	// Replay it to the original topic
+	rr = httptest.NewRecorder()
+	router.ReplayDeadLetter(rr, withURLParam(httptest.NewRequest("POST", "/api/admin/dead-letters/message1/replay", nil), "deadLetterId", "message1"))
+
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	remaining, err := store.GetDeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, remaining, "Replayed letter should be deleted")
+
+	replayed, err := pubSub.Subscribe(context.Background(), messaging.TweetCreatedTopic)
+	require.NoError(t, err)
+	msg := <-replayed
+	assert.JSONEq(t, `{"tweet":{"id":"tweet1"}}`, string(msg.Payload))
+	assert.NotEqual(t, "message1", msg.UUID, "Replayed messages get a new id")
+	assert.Empty(t, msg.Metadata.Get(middleware.ReasonForPoisonedKey))
+
+	// Replaying again finds nothing
+	rr = httptest.NewRecorder()
+	router.ReplayDeadLetter(rr, withURLParam(httptest.NewRequest("POST", "/api/admin/dead-letters/message1/replay", nil), "deadLetterId", "message1"))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+// TestDeadLettersForbidden tests that only admins can manage dead letters.
+func TestDeadLettersForbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&tweetAuthor).Times(2)
+
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{},
+		DeadLetters:             messaging.NewDeadLetterQueue(&tweetrepo.InMemoryDeadLetterStore{}, &messaging.NullPublisher{}),
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	rr := httptest.NewRecorder()
+	router.GetDeadLetters(rr, httptest.NewRequest("GET", "/api/admin/dead-letters", nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ReplayDeadLetter(rr, withURLParam(httptest.NewRequest("POST", "/api/admin/dead-letters/message1/replay", nil), "deadLetterId", "message1"))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/go-chi/render"
+)
+
+// GetEvents returns the stored tweet events after the since sequence, oldest first
+func (router Router) GetEvents(w http.ResponseWriter, r *http.Request) {
+	user := router.authorizeEvents(w, r)
+	if user == nil {
+		return
+	}
+
+	query := r.URL.Query()
+
+	var since int64
+	if value := query.Get("since"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "since must be a sequence, got "+strconv.Quote(value), http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	limit := models.DefaultPageLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "limit must be a positive integer, got "+strconv.Quote(value), http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, models.MaxPageLimit)
+	}
+
+	events, err := router.EventStore.GetEvents(r.Context(), since, limit)
+	if err != nil {
+		writeRepositoryError(router.Logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		logAndWriteError(router.Logger, w, err)
+	}
+}
+
+// ReplayEvents publishes a range of the stored tweet events again
+func (router Router) ReplayEvents(w http.ResponseWriter, r *http.Request) {
+	user := router.authorizeEvents(w, r)
+	if user == nil {
+		return
+	}
+
+	var replayEventsRequest models.ReplayEventsRequest
+	err := render.Decode(r, &replayEventsRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	replayed, err := router.EventReplayer.Replay(r.Context(), replayEventsRequest.From, replayEventsRequest.To, replayEventsRequest.Topic)
+	if errors.Is(err, messaging.ErrInvalidReplayRange) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		router.Logger.Error("Replay stopped", err, watermill.LogFields{"replayed": replayed})
+		writeRepositoryError(router.Logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(models.ReplayedEvents{Replayed: replayed}); err != nil {
+		logAndWriteError(router.Logger, w, err)
+	}
+}
+
+// authorizeEvents authenticates the user and checks that they may manage the stored events
+func (router Router) authorizeEvents(w http.ResponseWriter, r *http.Request) *models.User {
+	user := router.AuthenticationValidator.ValidateAuthentication(w, r)
+	if user == nil {
+		return nil
+	}
+
+	if !router.Authorizer.CanManageEvents(*user) {
+		w.WriteHeader(http.StatusForbidden)
+		return nil
+	}
+
+	return user
+}
@@ -0,0 +1,141 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	authnmock "twitter-clone/internal/__mocks__/authn"
+	"twitter-clone/internal/api"
+	"twitter-clone/internal/authz"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEvents tests listing and replaying stored events as an admin.
+func TestEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	admin := models.User{ID: "admin-id", Email: "admin@gmail.com"}
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&admin).AnyTimes()
+
+	eventStore := &tweetrepo.InMemoryEventStore{}
+	require.NoError(t, eventStore.AppendEvents(context.Background(), []models.OutboxEvent{
+		{ID: "event1", Topic: messaging.TweetCreatedTopic, Payload: []byte(`{"tweet":{"id":"tweet1"}}`), CreatedAt: time.Now()},
+		{ID: "event2", Topic: messaging.TweetDeletedTopic, Payload: []byte(`{"tweet":{"id":"tweet1"}}`), CreatedAt: time.Now()},
+	}))
+
+	pubSub := gochannel.NewGoChannel(gochannel.Config{Persistent: true}, watermill.NopLogger{})
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{Authorization: config.Authorization{Admins: []string{"admin@gmail.com"}}},
+		EventStore:              eventStore,
+		EventReplayer:           messaging.NewEventReplayer(eventStore, pubSub, messaging.JSONCodec{}),
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	// List the events after the first one
+	rr := httptest.NewRecorder()
+	router.GetEvents(rr, httptest.NewRequest("GET", "/api/admin/events?since=1&limit=10", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var listed []models.StoredEvent
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, "event2", listed[0].ID)
+	assert.Equal(t, int64(2), listed[0].Sequence)
+	assert.Equal(t, messaging.TweetDeletedTopic, listed[0].Topic)
+
+	// Replay everything to a new projection
+	req := httptest.NewRequest("POST", "/api/admin/events/replay", strings.NewReader(`{"from":1,"topic":"projection.bootstrap"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	router.ReplayEvents(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	assert.JSONEq(t, `{"replayed":2}`, rr.Body.String())
+
+	replayed, err := pubSub.Subscribe(context.Background(), "projection.bootstrap")
+	require.NoError(t, err)
+	var ids []string
+	for range 2 {
+		msg := <-replayed
+		msg.Ack()
+		ids = append(ids, msg.Metadata.Get(messaging.EventIDKey))
+	}
+	assert.ElementsMatch(t, []string{"event1", "event2"}, ids)
+}
+
+// TestEventsBadRequests tests that invalid queries and replay ranges are rejected.
+func TestEventsBadRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&models.User{ID: "admin-id", Email: "admin@gmail.com"}).AnyTimes()
+
+	eventStore := &tweetrepo.InMemoryEventStore{}
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{Authorization: config.Authorization{Admins: []string{"admin@gmail.com"}}},
+		EventStore:              eventStore,
+		EventReplayer:           messaging.NewEventReplayer(eventStore, &messaging.NullPublisher{}, messaging.JSONCodec{}),
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	for _, query := range []string{"since=-1", "since=abc", "limit=0", "limit=abc"} {
+		rr := httptest.NewRecorder()
+		router.GetEvents(rr, httptest.NewRequest("GET", "/api/admin/events?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+
+	for _, body := range []string{`{"from":0}`, `{"from":3,"to":2}`, `not json`} {
+		req := httptest.NewRequest("POST", "/api/admin/events/replay", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ReplayEvents(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+// TestEventsForbidden tests that only admins can manage stored events.
+func TestEventsForbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&tweetAuthor).Times(2)
+
+	eventStore := &tweetrepo.InMemoryEventStore{}
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{},
+		EventStore:              eventStore,
+		EventReplayer:           messaging.NewEventReplayer(eventStore, &messaging.NullPublisher{}, messaging.JSONCodec{}),
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	rr := httptest.NewRecorder()
+	router.GetEvents(rr, httptest.NewRequest("GET", "/api/admin/events", nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ReplayEvents(rr, httptest.NewRequest("POST", "/api/admin/events/replay", strings.NewReader(`{"from":1}`)))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"twitter-clone/internal/feedrebuild"
+)
+
+// RebuildFeeds recomputes the feeds from the stored tweets, with dry_run=true it only reports the changes
+func (router Router) RebuildFeeds(w http.ResponseWriter, r *http.Request) {
+	user := router.AuthenticationValidator.ValidateAuthentication(w, r)
+	if user == nil {
+		return
+	}
+
+	if !router.Authorizer.CanRebuildFeeds(*user) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "dry_run must be a boolean", http.StatusBadRequest)
+			return
+		}
+		dryRun = parsed
+	}
+
+	report, err := feedrebuild.Rebuild(r.Context(), router.TweetRepo, router.FeedRepo, dryRun)
+	if err != nil {
+		writeRepositoryError(router.Logger, w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logAndWriteError(router.Logger, w, err)
+	}
+}
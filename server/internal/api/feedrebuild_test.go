@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	authnmock "twitter-clone/internal/__mocks__/authn"
+	"twitter-clone/internal/api"
+	"twitter-clone/internal/authz"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/feedrebuild"
+	"twitter-clone/internal/models"
+	feedrepo "twitter-clone/internal/repositories/feed"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRebuildFeeds tests reporting and applying the feed changes as an admin.
+func TestRebuildFeeds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&adminUser).Times(3)
+
+	tweetRepo := &tweetrepo.InMemoryTweetRepository{}
+	feedRepo := &feedrepo.InMemoryFeedRepository{}
+	tweet, err := tweetRepo.CreateTweet(context.Background(), models.CreateTweetRequest{Content: "content", Tags: []string{"golang"}}, tweetAuthor)
+	require.NoError(t, err)
+
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{Authorization: config.Authorization{Admins: []string{adminUser.ID}}},
+		TweetRepo:               tweetRepo,
+		FeedRepo:                feedRepo,
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	rebuild := func(target string) (int, feedrebuild.Report) {
+		rr := httptest.NewRecorder()
+		router.RebuildFeeds(rr, httptest.NewRequest("POST", target, nil))
+
+		var report feedrebuild.Report
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		}
+		return rr.Code, report
+	}
+
+	// A dry run reports the missing feed without creating it
+	code, report := rebuild("/api/admin/feeds/rebuild?dry_run=true")
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, report.DryRun)
+	assert.Len(t, report.Changes, 2)
+	_, err = feedRepo.GetFeedByName(context.Background(), "golang")
+	assert.Error(t, err)
+
+	code, report = rebuild("/api/admin/feeds/rebuild")
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, report.DryRun)
+	feed, err := feedRepo.GetFeedByName(context.Background(), "golang")
+	require.NoError(t, err)
+	require.Len(t, feed.Tweets, 1)
+	assert.Equal(t, tweet.ID, feed.Tweets[0].ID)
+
+	code, _ = rebuild("/api/admin/feeds/rebuild?dry_run=maybe")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+// TestRebuildFeedsForbidden tests that only admins can rebuild feeds.
+func TestRebuildFeedsForbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&tweetAuthor)
+
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{Authorization: config.Authorization{Admins: []string{adminUser.ID}}},
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	rr := httptest.NewRecorder()
+	router.RebuildFeeds(rr, httptest.NewRequest("POST", "/api/admin/feeds/rebuild", nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
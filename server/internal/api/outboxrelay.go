@@ -0,0 +1,14 @@
+package api
+
+// IOutboxRelay forwards the tweet events the repository recorded together with the tweet changes
+type IOutboxRelay interface {
+	// Notify asks the relay to forward the pending events now instead of on its next poll
+	Notify()
+}
+
+// notifyOutboxRelay wakes the relay up, if any, after a tweet change was stored
+func (router Router) notifyOutboxRelay() {
+	if router.OutboxRelay != nil {
+		router.OutboxRelay.Notify()
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"twitter-clone/internal/models"
+)
+
+// parsePageRequest reads the limit and cursor query parameters,
+// falling back to the first page with the default limit
+func parsePageRequest(r *http.Request) (models.PageRequest, error) {
+	page := models.PageRequest{Limit: models.DefaultPageLimit}
+
+	query := r.URL.Query()
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			return page, fmt.Errorf("limit must be a positive integer, got %q", limit)
+		}
+		page.Limit = min(value, models.MaxPageLimit)
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		decoded, err := models.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = decoded
+	}
+
+	return page, nil
+}
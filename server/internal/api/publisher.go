@@ -1,27 +0,0 @@
-package api
-
-import (
-	"encoding/json"
-
-	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill/message"
-)
-
-type IPublisher interface {
-	Publish(topic string, event interface{}) error
-}
-
-type Publisher struct {
-	Publisher message.Publisher
-}
-
-func (p Publisher) Publish(topic string, event interface{}) error {
-	payload, err := json.Marshal(event)
-	if err != nil {
-		return err
-	}
-
-	msg := message.NewMessage(watermill.NewUUID(), payload)
-
-	return p.Publisher.Publish(topic, msg)
-}
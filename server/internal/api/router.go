@@ -3,14 +3,17 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
-	"time"
 	"twitter-clone/internal/authn"
+	"twitter-clone/internal/authz"
 	"twitter-clone/internal/config"
 	"twitter-clone/internal/messaging"
 	"twitter-clone/internal/models"
+	"twitter-clone/internal/outbox"
 	feedrepo "twitter-clone/internal/repositories/feed"
+	"twitter-clone/internal/repositories/repoerrors"
 	tweetrepo "twitter-clone/internal/repositories/tweet"
 
 	"github.com/ThreeDotsLabs/watermill"
@@ -25,15 +28,27 @@ import (
 func StartRouter(configuration config.Configuration,
 	tweetRepo tweetrepo.TweetRepository,
 	feedRepo feedrepo.FeedRepository,
+	tokenRepo tweetrepo.TokenRepository,
+	eventStore tweetrepo.EventStore,
+	deadLetterStore messaging.DeadLetterStore,
 	messageHandler messaging.MessageHandler,
+	identityProviders authn.IdentityProviders,
 	authenticationValidator authn.IAuthenticationValidator) {
 	logger := watermill.NewStdLogger(false, false)
 
-	pub, sub, err := messageHandler.SetupMessageRouter(configuration, feedRepo, logger)
+	pub, sub, err := messageHandler.SetupMessageRouter(configuration, feedRepo, deadLetterStore, logger)
 	if err != nil {
 		panic(err)
 	}
 
+	codec, err := messaging.NewEventCodec(configuration.Messaging.Encoding)
+	if err != nil {
+		panic(err)
+	}
+
+	relay := outbox.NewRelay(tweetRepo, eventStore, pub, codec, configuration.Messaging.Outbox, logger)
+	go relay.Run(context.Background())
+
 	normalizedDomain := strings.TrimPrefix(strings.TrimPrefix(configuration.AllowOrigin, "http://"), "https://")
 
 	oauth2Router := authn.OAuth2Router{
@@ -42,16 +57,22 @@ func StartRouter(configuration config.Configuration,
 		AllowOrigin:             configuration.AllowOrigin,
 		Domain:                  normalizedDomain,
 		AuthenticationValidator: authenticationValidator,
+		IdentityProviders:       identityProviders,
 	}
 
 	httpRouter := Router{
 		Config:                  configuration,
 		AuthenticationValidator: authenticationValidator,
+		Authorizer:              authz.Authorizer{Authorization: configuration.Authorization},
 		OAuth2Router:            oauth2Router,
 		Subscriber:              sub,
-		Publisher:               Publisher{Publisher: pub},
+		OutboxRelay:             relay,
 		TweetRepo:               tweetRepo,
 		FeedRepo:                feedRepo,
+		TokenRepo:               tokenRepo,
+		DeadLetters:             messaging.NewDeadLetterQueue(deadLetterStore, pub),
+		EventStore:              eventStore,
+		EventReplayer:           messaging.NewEventReplayer(eventStore, pub, codec),
 		Logger:                  logger,
 	}
 
@@ -66,11 +87,16 @@ func StartRouter(configuration config.Configuration,
 type Router struct {
 	Config                  config.Configuration
 	AuthenticationValidator authn.IAuthenticationValidator
+	Authorizer              authz.IAuthorizer
 	OAuth2Router            authn.OAuth2Router
 	Subscriber              message.Subscriber
-	Publisher               IPublisher
+	OutboxRelay             IOutboxRelay
 	TweetRepo               tweetrepo.TweetRepository
 	FeedRepo                feedrepo.FeedRepository
+	TokenRepo               tweetrepo.TokenRepository
+	DeadLetters             *messaging.DeadLetterQueue
+	EventStore              tweetrepo.EventStore
+	EventReplayer           *messaging.EventReplayer
 	Logger                  watermill.LoggerAdapter
 }
 
@@ -106,32 +132,42 @@ func (router Router) Mux() *chi.Mux {
 		logger: router.Logger,
 	}
 	allTweetsStream := AllTweetsStreamAdapter{
-		repo: router.TweetRepo,
+		repo:   router.TweetRepo,
+		logger: router.Logger,
 	}
 	allFeedsStream := AllFeedsStreamAdapter{
 		repo:   router.FeedRepo,
 		logger: router.Logger,
 	}
 
-	tweetHandler := sseRouter.AddHandler(messaging.TweetCreatedTopic, tweetStream)
+	tweetHandler := sseRouter.AddHandler(messaging.TweetUpdatedTopic, tweetStream)
 	feedHandler := sseRouter.AddHandler(messaging.FeedUpdatedTopic, feedStream)
 	allTweetsHandler := sseRouter.AddHandler(messaging.TweetUpdatedTopic, allTweetsStream)
 	allFeedsHandler := sseRouter.AddHandler(messaging.FeedUpdatedTopic, allFeedsStream)
 
 	r.Route("/", func(r chi.Router) {
-		r.Get("/auth/google/login", router.OAuth2Router.OauthGoogleLogin)
-		r.Get("/auth/google/logout", router.OAuth2Router.OauthGoogleLogout)
-		r.Get("/auth/google/callback", router.OAuth2Router.OauthGoogleCallback)
-		r.Get("/auth/google/userinfo", router.OAuth2Router.OauthUserInfo)
+		r.Get("/auth/{provider}/login", router.OAuth2Router.OauthLogin)
+		r.Get("/auth/{provider}/logout", router.OAuth2Router.OauthLogout)
+		r.Get("/auth/{provider}/callback", router.OAuth2Router.OauthCallback)
+		r.Get("/auth/{provider}/userinfo", router.OAuth2Router.OauthUserInfo)
 	})
 
 	r.Route("/api", func(r chi.Router) {
 		r.Post("/tweets", router.CreateTweet)
 		r.Get("/tweets", allTweetsHandler)
 		r.Get("/tweets/{tweetId}", tweetHandler)
+		r.Put("/tweets/{tweetId}", router.UpdateTweet)
 		r.Delete("/tweets/{tweetId}", router.DeleteTweet)
 		r.Get("/feeds/{name}", feedHandler)
 		r.Get("/feeds", allFeedsHandler)
+		r.Post("/tokens", router.CreateAPIToken)
+		r.Get("/tokens", router.GetAPITokens)
+		r.Delete("/tokens/{tokenId}", router.DeleteAPIToken)
+		r.Get("/admin/dead-letters", router.GetDeadLetters)
+		r.Post("/admin/dead-letters/{deadLetterId}/replay", router.ReplayDeadLetter)
+		r.Post("/admin/feeds/rebuild", router.RebuildFeeds)
+		r.Get("/admin/events", router.GetEvents)
+		r.Post("/admin/events/replay", router.ReplayEvents)
 	})
 
 	go func() {
@@ -152,6 +188,11 @@ func (router Router) CreateTweet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !router.Authorizer.CanCreateTweet(*user) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
 	var createTweetRequest models.CreateTweetRequest
 	err := render.Decode(r, &createTweetRequest)
 	if err != nil {
@@ -159,22 +200,14 @@ func (router Router) CreateTweet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdTweet := router.TweetRepo.CreateTweet(createTweetRequest, *user)
-	if createdTweet == nil {
-		return
-	}
-
-	event := messaging.TweetCreated{
-		Tweet:      *createdTweet,
-		OccurredAt: time.Now().UTC(),
-	}
-
-	err = router.Publisher.Publish(messaging.TweetCreatedTopic, event)
+	createdTweet, err := router.TweetRepo.CreateTweet(r.Context(), createTweetRequest, *user)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeRepositoryError(router.Logger, w, err)
 		return
 	}
 
+	router.notifyOutboxRelay()
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(createdTweet); err != nil {
@@ -182,39 +215,61 @@ func (router Router) CreateTweet(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (router Router) DeleteTweet(w http.ResponseWriter, r *http.Request) {
+func (router Router) UpdateTweet(w http.ResponseWriter, r *http.Request) {
 	user := router.AuthenticationValidator.ValidateAuthentication(w, r)
 	if user == nil {
 		return
 	}
 
+	var updateTweetRequest models.UpdateTweetRequest
+	err := render.Decode(r, &updateTweetRequest)
+	if err != nil {
+		logAndWriteError(router.Logger, w, err)
+		return
+	}
+
+	// The authorization is checked on the tweet which is updated, within the transaction of the update
 	tweetId := chi.URLParam(r, "tweetId")
-	tweetToDelete := router.TweetRepo.GetTweetById(tweetId)
-	if tweetToDelete == nil {
-		w.WriteHeader(http.StatusNotFound)
+	updatedTweet, err := router.TweetRepo.UpdateTweet(r.Context(), tweetId, updateTweetRequest, func(originalTweet models.Tweet) error {
+		if !router.Authorizer.CanUpdateTweet(*user, originalTweet) {
+			return errForbidden
+		}
+		return nil
+	})
+	if err != nil {
+		writeRepositoryError(router.Logger, w, err)
 		return
 	}
 
-	var deleted = router.TweetRepo.DeleteTweet(tweetId)
+	router.notifyOutboxRelay()
 
-	if !deleted {
-		w.WriteHeader(http.StatusNotFound)
-		return
-	} else {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(updatedTweet); err != nil {
+		logAndWriteError(router.Logger, w, err)
+	}
+}
 
-		event := messaging.TweetDeleted{
-			DeletedTweet: *tweetToDelete,
-			OccurredAt:   time.Now().UTC(),
-		}
+func (router Router) DeleteTweet(w http.ResponseWriter, r *http.Request) {
+	user := router.AuthenticationValidator.ValidateAuthentication(w, r)
+	if user == nil {
+		return
+	}
 
-		router.Logger.Info("Publishing tweet deleted event", watermill.LogFields{"event": event})
-		err := router.Publisher.Publish(messaging.TweetDeletedTopic, event)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			return
+	tweetId := chi.URLParam(r, "tweetId")
+	err := router.TweetRepo.DeleteTweet(r.Context(), tweetId, func(tweetToDelete models.Tweet) error {
+		if !router.Authorizer.CanDeleteTweet(*user, tweetToDelete) {
+			return errForbidden
 		}
+		return nil
+	})
+	if err != nil {
+		writeRepositoryError(router.Logger, w, err)
+		return
 	}
 
+	router.notifyOutboxRelay()
+
 	w.WriteHeader(204)
 }
 
@@ -222,3 +277,26 @@ func logAndWriteError(logger watermill.LoggerAdapter, w http.ResponseWriter, err
 	logger.Error("Error", err, nil)
 	w.WriteHeader(http.StatusInternalServerError)
 }
+
+// errForbidden denies a change from within the repository transaction
+var errForbidden = errors.New("forbidden")
+
+// writeRepositoryError maps the repository errors onto status codes
+func writeRepositoryError(logger watermill.LoggerAdapter, w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errForbidden):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, repoerrors.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, repoerrors.ErrConflict):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, context.Canceled):
+		// The client disconnected, nobody is left to read the response
+		logger.Debug("Request cancelled", watermill.LogFields{"error": err})
+	case errors.Is(err, repoerrors.ErrUnavailable):
+		logger.Error("Storage unavailable", err, nil)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		logAndWriteError(logger, w, err)
+	}
+}
@@ -2,6 +2,7 @@ package api_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -12,11 +13,14 @@ import (
 	authnmock "twitter-clone/internal/__mocks__/authn"
 	tweetmock "twitter-clone/internal/__mocks__/repositories/tweet"
 	"twitter-clone/internal/api"
+	"twitter-clone/internal/authz"
 	"twitter-clone/internal/config"
-	"twitter-clone/internal/messaging"
 	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
 
 	"github.com/ThreeDotsLabs/watermill"
+	"github.com/go-chi/chi/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,7 +34,7 @@ func TestCreateTweet(t *testing.T) {
 
 	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
 	mockTweetRepo := tweetmock.NewMockTweetRepository(ctrl)
-	mockPublisher := apimock.NewMockIPublisher(ctrl)
+	mockOutboxRelay := apimock.NewMockIOutboxRelay(ctrl)
 
 	// Mock configuration and logger
 	config := config.Configuration{AllowOrigin: "*"}
@@ -55,15 +59,16 @@ func TestCreateTweet(t *testing.T) {
 
 	// Configure mocks
 	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(user)
-	mockTweetRepo.EXPECT().CreateTweet(tweetRequest, *user).Return(createdTweet)
-	mockPublisher.EXPECT().Publish(messaging.TweetCreatedTopic, gomock.Any()).Return(nil)
+	mockTweetRepo.EXPECT().CreateTweet(gomock.Any(), tweetRequest, *user).Return(createdTweet, nil)
+	mockOutboxRelay.EXPECT().Notify()
 
 	// Set up the router
 	router := api.Router{
 		Config:                  config,
 		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{},
 		TweetRepo:               mockTweetRepo,
-		Publisher:               mockPublisher,
+		OutboxRelay:             mockOutboxRelay,
 		Logger:                  logger,
 	}
 
@@ -87,19 +92,15 @@ func TestCreateTweet(t *testing.T) {
 	assert.Equal(t, createdTweet.Tags, responseTweet.Tags)
 }
 
-// TestCreateTweetPublishError tests the CreateTweet endpoint when the Publish operation fails.
-func TestCreateTweetPublishError(t *testing.T) {
+// TestCreateTweetRepositoryError tests that the outbox relay is not notified when the tweet could not be stored.
+func TestCreateTweetRepositoryError(t *testing.T) {
 	// Initialize mocks
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
 	mockTweetRepo := tweetmock.NewMockTweetRepository(ctrl)
-	mockPublisher := apimock.NewMockIPublisher(ctrl)
-
-	// Mock configuration and logger
-	config := config.Configuration{AllowOrigin: "*"}
-	logger := watermill.NewStdLogger(false, false)
+	mockOutboxRelay := apimock.NewMockIOutboxRelay(ctrl)
 
 	// Define a test tweet request
 	tweetRequest := models.CreateTweetRequest{
@@ -110,39 +111,324 @@ func TestCreateTweetPublishError(t *testing.T) {
 	// Set up the authenticated user
 	user := &models.User{IsAnonymous: true}
 
-	// Expected created tweet response
-	createdTweet := &models.Tweet{
+	// Configure mocks, neither the tweet nor its event were stored
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(user)
+	mockTweetRepo.EXPECT().CreateTweet(gomock.Any(), tweetRequest, *user).Return(nil, repoerrors.Unavailable(errors.New("connection refused")))
+
+	// Set up the router
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{},
+		TweetRepo:               mockTweetRepo,
+		OutboxRelay:             mockOutboxRelay,
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	// Create the HTTP request
+	body, _ := json.Marshal(tweetRequest)
+	req := httptest.NewRequest("POST", "/api/tweets", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	// Create the HTTP response recorder
+	rr := httptest.NewRecorder()
+
+	// Call the CreateTweet endpoint
+	router.CreateTweet(rr, req)
+
+	// Validate the response
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+// TestUpdateTweet tests the UpdateTweet endpoint for successful tweet update.
+func TestUpdateTweet(t *testing.T) {
+	// Initialize mocks
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockTweetRepo := tweetmock.NewMockTweetRepository(ctrl)
+	mockOutboxRelay := apimock.NewMockIOutboxRelay(ctrl)
+
+	// Mock configuration and logger
+	config := config.Configuration{AllowOrigin: "*"}
+	logger := watermill.NewStdLogger(false, false)
+
+	// Define a test tweet update request
+	updateTweetRequest := models.UpdateTweetRequest{
+		Content: "Hello, gophers!",
+		Tags:    []string{"golang"},
+	}
+
+	// Set up the authenticated user
+	user := &models.User{IsAnonymous: true}
+
+	originalTweet := &models.Tweet{
 		ID:        "tweet1",
-		Content:   tweetRequest.Content,
-		Tags:      tweetRequest.Tags,
+		Content:   "Hello, world!",
+		Tags:      []string{"test"},
 		CreatedAt: models.MySQLTimestamp{Time: time.Now()},
 	}
+	updatedTweet := &models.Tweet{
+		ID:        originalTweet.ID,
+		Content:   updateTweetRequest.Content,
+		Tags:      updateTweetRequest.Tags,
+		CreatedAt: originalTweet.CreatedAt,
+	}
 
 	// Configure mocks
 	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(user)
-	mockTweetRepo.EXPECT().CreateTweet(tweetRequest, *user).Return(createdTweet)
-	mockPublisher.EXPECT().Publish(messaging.TweetCreatedTopic, gomock.Any()).Return(errors.New("publish error"))
+	mockTweetRepo.EXPECT().UpdateTweet(gomock.Any(), originalTweet.ID, updateTweetRequest, gomock.Any()).DoAndReturn(updateStoredTweet(*originalTweet, updatedTweet))
+	mockOutboxRelay.EXPECT().Notify()
 
 	// Set up the router
 	router := api.Router{
 		Config:                  config,
 		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{},
 		TweetRepo:               mockTweetRepo,
-		Publisher:               mockPublisher,
+		OutboxRelay:             mockOutboxRelay,
 		Logger:                  logger,
 	}
 
 	// Create the HTTP request
-	body, _ := json.Marshal(tweetRequest)
-	req := httptest.NewRequest("POST", "/api/tweets", bytes.NewBuffer(body))
+	body, _ := json.Marshal(updateTweetRequest)
+	req := httptest.NewRequest("PUT", "/api/tweets/tweet1", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = withURLParam(req, "tweetId", originalTweet.ID)
 
 	// Create the HTTP response recorder
 	rr := httptest.NewRecorder()
 
-	// Call the CreateTweet endpoint
-	router.CreateTweet(rr, req)
+	// Call the UpdateTweet endpoint
+	router.UpdateTweet(rr, req)
 
 	// Validate the response
-	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var responseTweet models.Tweet
+	err := json.Unmarshal(rr.Body.Bytes(), &responseTweet)
+	require.NoError(t, err)
+	assert.Equal(t, updatedTweet.Content, responseTweet.Content)
+	assert.Equal(t, updatedTweet.Tags, responseTweet.Tags)
+}
+
+// TestUpdateTweetNotFound tests the UpdateTweet endpoint when the tweet does not exist.
+func TestUpdateTweetNotFound(t *testing.T) {
+	// Initialize mocks
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockTweetRepo := tweetmock.NewMockTweetRepository(ctrl)
+	mockOutboxRelay := apimock.NewMockIOutboxRelay(ctrl)
+
+	// Configure mocks
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&models.User{IsAnonymous: true})
+	mockTweetRepo.EXPECT().UpdateTweet(gomock.Any(), "missing", gomock.Any(), gomock.Any()).Return(nil, repoerrors.ErrNotFound)
+
+	// Set up the router
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{},
+		TweetRepo:               mockTweetRepo,
+		OutboxRelay:             mockOutboxRelay,
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	// Create the HTTP request
+	body, _ := json.Marshal(models.UpdateTweetRequest{Content: "Hello, gophers!"})
+	req := httptest.NewRequest("PUT", "/api/tweets/missing", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = withURLParam(req, "tweetId", "missing")
+
+	rr := httptest.NewRecorder()
+
+	router.UpdateTweet(rr, req)
+
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+// TestDeleteTweetRepositoryErrors tests that repository errors are mapped onto status codes.
+func TestDeleteTweetRepositoryErrors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{name: "not found", err: repoerrors.ErrNotFound, expectedCode: http.StatusNotFound},
+		{name: "conflict", err: repoerrors.ErrConflict, expectedCode: http.StatusConflict},
+		{name: "storage unavailable", err: repoerrors.Unavailable(errors.New("connection refused")), expectedCode: http.StatusServiceUnavailable},
+		{name: "unexpected error", err: errors.New("boom"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+			mockTweetRepo := tweetmock.NewMockTweetRepository(ctrl)
+			mockOutboxRelay := apimock.NewMockIOutboxRelay(ctrl)
+
+			// The relay is not notified when the repository fails
+			mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&tweetAuthor)
+			mockTweetRepo.EXPECT().DeleteTweet(gomock.Any(), "tweet1", gomock.Any()).Return(testCase.err)
+
+			router := api.Router{
+				Config:                  config.Configuration{AllowOrigin: "*"},
+				AuthenticationValidator: mockAuthValidator,
+				Authorizer:              authz.Authorizer{},
+				TweetRepo:               mockTweetRepo,
+				OutboxRelay:             mockOutboxRelay,
+				Logger:                  watermill.NewStdLogger(false, false),
+			}
+
+			req := withURLParam(httptest.NewRequest("DELETE", "/api/tweets/tweet1", nil), "tweetId", "tweet1")
+			rr := httptest.NewRecorder()
+
+			router.DeleteTweet(rr, req)
+
+			assert.Equal(t, testCase.expectedCode, rr.Code)
+		})
+	}
+}
+
+// TestUpdateTweetForbidden tests that only the author can update a tweet.
+func TestUpdateTweetForbidden(t *testing.T) {
+	// Initialize mocks
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+	mockTweetRepo := tweetmock.NewMockTweetRepository(ctrl)
+	mockOutboxRelay := apimock.NewMockIOutboxRelay(ctrl)
+
+	tweet := &models.Tweet{ID: "tweet1", User: tweetAuthor}
+
+	// Configure mocks, the check on the stored tweet denies the update
+	mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&otherUser)
+	mockTweetRepo.EXPECT().UpdateTweet(gomock.Any(), tweet.ID, gomock.Any(), gomock.Any()).DoAndReturn(updateStoredTweet(*tweet, tweet))
+
+	// Set up the router
+	router := api.Router{
+		Config:                  config.Configuration{AllowOrigin: "*"},
+		AuthenticationValidator: mockAuthValidator,
+		Authorizer:              authz.Authorizer{},
+		TweetRepo:               mockTweetRepo,
+		OutboxRelay:             mockOutboxRelay,
+		Logger:                  watermill.NewStdLogger(false, false),
+	}
+
+	// Create the HTTP request
+	body, _ := json.Marshal(models.UpdateTweetRequest{Content: "Hijacked"})
+	req := httptest.NewRequest("PUT", "/api/tweets/tweet1", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = withURLParam(req, "tweetId", tweet.ID)
+
+	rr := httptest.NewRecorder()
+
+	router.UpdateTweet(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+var (
+	tweetAuthor = models.User{ID: "author-id", Email: "alice@gmail.com"}
+	otherUser   = models.User{ID: "other-id", Email: "bob@gmail.com"}
+	adminUser   = models.User{ID: "admin-id", Email: "carol@gmail.com"}
+)
+
+// TestDeleteTweetAuthorization tests that tweets can only be deleted by their author or an admin.
+func TestDeleteTweetAuthorization(t *testing.T) {
+	testCases := []struct {
+		name         string
+		user         models.User
+		tweetUser    models.User
+		expectedCode int
+	}{
+		{name: "author", user: tweetAuthor, tweetUser: tweetAuthor, expectedCode: http.StatusNoContent},
+		{name: "non-owner", user: otherUser, tweetUser: tweetAuthor, expectedCode: http.StatusForbidden},
+		{name: "non-owner with same email", user: models.User{ID: "other-id", Email: tweetAuthor.Email}, tweetUser: tweetAuthor, expectedCode: http.StatusForbidden},
+		{name: "author of a tweet stored without ID", user: tweetAuthor, tweetUser: models.User{Email: tweetAuthor.Email}, expectedCode: http.StatusNoContent},
+		{name: "author of a tweet stored without provider prefix", user: models.User{ID: "google|author-id", Email: tweetAuthor.Email}, tweetUser: tweetAuthor, expectedCode: http.StatusNoContent},
+		{name: "non-owner of a tweet stored without provider prefix", user: models.User{ID: "github|author-id", Email: otherUser.Email}, tweetUser: tweetAuthor, expectedCode: http.StatusForbidden},
+		{name: "admin", user: adminUser, tweetUser: tweetAuthor, expectedCode: http.StatusNoContent},
+		{name: "anonymous when authentication is disabled", user: models.User{IsAnonymous: true}, tweetUser: tweetAuthor, expectedCode: http.StatusNoContent},
+		{name: "author with API token without delete scope", user: withAPIToken(tweetAuthor, models.ScopeTweetsWrite), tweetUser: tweetAuthor, expectedCode: http.StatusForbidden},
+		{name: "author with API token with delete scope", user: withAPIToken(tweetAuthor, models.ScopeTweetsDelete), tweetUser: tweetAuthor, expectedCode: http.StatusNoContent},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Initialize mocks
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthValidator := authnmock.NewMockIAuthenticationValidator(ctrl)
+			mockTweetRepo := tweetmock.NewMockTweetRepository(ctrl)
+			mockOutboxRelay := apimock.NewMockIOutboxRelay(ctrl)
+
+			tweet := &models.Tweet{ID: "tweet1", Tags: []string{"test"}, User: testCase.tweetUser}
+
+			// Configure mocks, the relay notification only happens when the check lets the deletion through
+			mockAuthValidator.EXPECT().ValidateAuthentication(gomock.Any(), gomock.Any()).Return(&testCase.user)
+			mockTweetRepo.EXPECT().DeleteTweet(gomock.Any(), tweet.ID, gomock.Any()).DoAndReturn(deleteStoredTweet(*tweet))
+			if testCase.expectedCode == http.StatusNoContent {
+				mockOutboxRelay.EXPECT().Notify()
+			}
+
+			// Set up the router with a configured admin
+			router := api.Router{
+				Config:                  config.Configuration{AllowOrigin: "*"},
+				AuthenticationValidator: mockAuthValidator,
+				Authorizer: authz.Authorizer{
+					Authorization: config.Authorization{Admins: []string{adminUser.ID}},
+				},
+				TweetRepo:   mockTweetRepo,
+				OutboxRelay: mockOutboxRelay,
+				Logger:      watermill.NewStdLogger(false, false),
+			}
+
+			req := httptest.NewRequest("DELETE", "/api/tweets/tweet1", nil)
+			req = withURLParam(req, "tweetId", tweet.ID)
+
+			rr := httptest.NewRecorder()
+
+			router.DeleteTweet(rr, req)
+
+			require.Equal(t, testCase.expectedCode, rr.Code)
+		})
+	}
+}
+
+// updateStoredTweet returns a mocked UpdateTweet which runs the check on the stored tweet like the repositories do.
+func updateStoredTweet(stored models.Tweet, updated *models.Tweet) func(context.Context, string, models.UpdateTweetRequest, tweetrepo.TweetCheck) (*models.Tweet, error) {
+	return func(_ context.Context, _ string, _ models.UpdateTweetRequest, check tweetrepo.TweetCheck) (*models.Tweet, error) {
+		if err := check(stored); err != nil {
+			return nil, err
+		}
+		return updated, nil
+	}
+}
+
+// deleteStoredTweet returns a mocked DeleteTweet which runs the check on the stored tweet like the repositories do.
+func deleteStoredTweet(stored models.Tweet) func(context.Context, string, tweetrepo.TweetCheck) error {
+	return func(_ context.Context, _ string, check tweetrepo.TweetCheck) error {
+		return check(stored)
+	}
+}
+
+// withAPIToken returns the user as authenticated by a personal API token with the given scopes.
+func withAPIToken(user models.User, scopes ...string) models.User {
+	user.APITokenID = "token1"
+	user.Scopes = scopes
+	return user
+}
+
+// withURLParam attaches a chi URL parameter to the request as the chi router would.
+func withURLParam(r *http.Request, key, value string) *http.Request {
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add(key, value)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
 }
@@ -0,0 +1,30 @@
+package authn
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// APITokenPrefix tells personal API tokens apart from ID tokens in the Authorization header
+const APITokenPrefix = "tcpat_"
+
+// GenerateAPIToken returns a new personal API token and the hash to store in its place
+func GenerateAPIToken() (string, string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+
+	token := APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashAPIToken(token)
+}
+
+func HashAPIToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
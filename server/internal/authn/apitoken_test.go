@@ -0,0 +1,66 @@
+package authn_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"twitter-clone/internal/authn"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticationValidator_APIToken(t *testing.T) {
+	tokenRepo := &tweetrepo.InMemoryTokenRepository{}
+	validator := authn.AuthenticationValidator{
+		Authentication: config.Authentication{Enable: true},
+		Verifier:       authn.MultiIssuerVerifier{},
+		TokenRepo:      tokenRepo,
+	}
+
+	user := models.User{ID: "user-subject", Email: "alice@gmail.com"}
+	validToken, validHash := authn.GenerateAPIToken()
+	tokenRepo.CreateToken(context.Background(), models.APIToken{ID: "valid", Hash: validHash, User: user, Scopes: []string{models.ScopeTweetsWrite}})
+
+	expiredAt := time.Now().Add(-time.Minute)
+	expiredToken, expiredHash := authn.GenerateAPIToken()
+	tokenRepo.CreateToken(context.Background(), models.APIToken{ID: "expired", Hash: expiredHash, User: user, ExpiresAt: &expiredAt})
+
+	unknownToken, _ := authn.GenerateAPIToken()
+
+	testCases := []struct {
+		name          string
+		token         string
+		expectedScope []string
+	}{
+		{name: "valid token", token: validToken, expectedScope: []string{models.ScopeTweetsWrite}},
+		{name: "expired token", token: expiredToken},
+		{name: "unknown token", token: unknownToken},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/tweets", nil)
+			req.Header.Set("Authorization", "Bearer "+testCase.token)
+			rr := httptest.NewRecorder()
+
+			authenticatedUser := validator.ValidateAuthentication(rr, req)
+
+			if testCase.expectedScope == nil {
+				assert.Nil(t, authenticatedUser)
+				assert.Equal(t, http.StatusUnauthorized, rr.Code)
+				return
+			}
+
+			require.NotNil(t, authenticatedUser)
+			assert.Equal(t, user.ID, authenticatedUser.ID)
+			assert.Equal(t, "valid", authenticatedUser.APITokenID)
+			assert.Equal(t, testCase.expectedScope, authenticatedUser.Scopes)
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package authn
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"time"
 	"twitter-clone/internal/config"
 	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
 )
 
 type IAuthenticationValidator interface {
@@ -15,6 +17,18 @@ type IAuthenticationValidator interface {
 
 type AuthenticationValidator struct {
 	Authentication config.Authentication
+	Verifier       TokenVerifier
+	TokenRepo      tweetrepo.TokenRepository // Looks up personal API tokens, these are rejected when nil
+}
+
+// NewAuthenticationValidator verifies the ID tokens of every configured identity provider locally
+// and personal API tokens against their stored hashes
+func NewAuthenticationValidator(authentication config.Authentication, identityProviders IdentityProviders, tokenRepo tweetrepo.TokenRepository) AuthenticationValidator {
+	return AuthenticationValidator{
+		Authentication: authentication,
+		Verifier:       identityProviders.Verifier(),
+		TokenRepo:      tokenRepo,
+	}
 }
 
 func (validator AuthenticationValidator) ValidateAuthentication(w http.ResponseWriter, r *http.Request) *models.User {
@@ -36,52 +50,63 @@ func (validator AuthenticationValidator) ValidateAuthentication(w http.ResponseW
 		id_token = cookie.Value
 	}
 
-	url := fmt.Sprintf("%s?id_token=%s", config.GOOGLE_TOKENINFO_URL, id_token)
-	resp, err := http.Get(url)
-	if err != nil {
-		http.Error(w, "Failed to validate id_token", http.StatusUnauthorized)
-		return nil
+	if IsAPIToken(id_token) {
+		return validator.validateAPIToken(w, r, id_token)
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+	claims, err := validator.Verifier.Verify(r.Context(), id_token)
 	if err != nil {
-		http.Error(w, "Failed to read response from Google", http.StatusUnauthorized)
+		http.Error(w, fmt.Sprintf("Failed to validate id_token: %v", err), http.StatusUnauthorized)
 		return nil
 	}
 
-	// Parse the response body (which contains user info)
-	var response map[string]any
-	if err := json.Unmarshal(body, &response); err != nil {
-		http.Error(w, "Failed to parse user info", http.StatusUnauthorized)
+	user := readUserFromClaims(*claims)
+
+	return &user
+}
+
+func (validator AuthenticationValidator) validateAPIToken(w http.ResponseWriter, r *http.Request, apiToken string) *models.User {
+	if validator.TokenRepo == nil {
+		http.Error(w, "Unauthorized: API tokens are not supported", http.StatusUnauthorized)
 		return nil
 	}
 
-	errordesc, haserror := response["error_description"]
-	if haserror {
-		http.Error(w, errordesc.(string), http.StatusUnauthorized)
+	token, err := validator.TokenRepo.GetTokenByHash(r.Context(), HashAPIToken(apiToken))
+	if err != nil && !errors.Is(err, repoerrors.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("Failed to validate API token: %v", err), http.StatusServiceUnavailable)
+		return nil
+	}
+	if token == nil || token.IsExpired(time.Now()) {
+		http.Error(w, "Unauthorized: Invalid or expired API token", http.StatusUnauthorized)
 		return nil
 	}
 
-	user := readUserFromResponse(response)
+	user := token.User
+	user.APITokenID = token.ID
+	user.Scopes = token.Scopes
 
 	return &user
 }
 
-func readUserFromResponse(response map[string]any) models.User {
-	return models.User{
-		IsAnonymous: false,
-		FirstName:   getString(response["given_name"]),
-		LastName:    getString(response["family_name"]),
-		Email:       getString(response["email"]),
-		Picture:     getString(response["picture"]),
+// readUserFromClaims identifies the user by the provider name and subject, like the GitHub provider,
+// as subjects are only unique per issuer. Unverified emails are dropped, admins may be matched by email.
+func readUserFromClaims(claims IDTokenClaims) models.User {
+	id := claims.Subject
+	if claims.Provider != "" {
+		id = claims.Provider + "|" + claims.Subject
 	}
-}
 
-func getString(value any) string {
-	if str, ok := value.(string); ok {
-		return str
+	email := ""
+	if claims.EmailVerified {
+		email = claims.Email
+	}
+
+	return models.User{
+		IsAnonymous: false,
+		ID:          id,
+		FirstName:   claims.GivenName,
+		LastName:    claims.FamilyName,
+		Email:       email,
+		Picture:     claims.Picture,
 	}
-	return ""
 }
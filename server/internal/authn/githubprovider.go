@@ -0,0 +1,131 @@
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"twitter-clone/internal/models"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const githubAPIURL = "https://api.github.com"
+
+// GitHubProvider signs users in with GitHub OAuth. GitHub issues no ID tokens,
+// so the user is read from the REST API and a session token is issued instead.
+type GitHubProvider struct {
+	name     string
+	config   oauth2.Config
+	sessions SessionTokens
+	APIURL   string
+}
+
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+func NewGitHubProvider(name string, oauth2Config oauth2.Config, sessions SessionTokens) *GitHubProvider {
+	if oauth2Config.Endpoint.AuthURL == "" {
+		oauth2Config.Endpoint = github.Endpoint
+	}
+
+	if len(oauth2Config.Scopes) == 0 {
+		oauth2Config.Scopes = []string{"read:user", "user:email"}
+	}
+
+	return &GitHubProvider{
+		name:     name,
+		config:   oauth2Config,
+		sessions: sessions,
+		APIURL:   githubAPIURL,
+	}
+}
+
+func (provider *GitHubProvider) Name() string {
+	return provider.name
+}
+
+func (provider *GitHubProvider) OAuth2Config() *oauth2.Config {
+	return &provider.config
+}
+
+func (provider *GitHubProvider) Verifier() TokenVerifier {
+	return provider.sessions
+}
+
+func (provider *GitHubProvider) Identify(ctx context.Context, token *oauth2.Token) (*Identity, error) {
+	client := provider.config.Client(ctx, token)
+
+	var user githubUser
+	if err := provider.get(client, "/user", &user); err != nil {
+		return nil, err
+	}
+
+	// The profile email is empty unless the user made it public
+	if user.Email == "" {
+		var emails []githubEmail
+		if err := provider.get(client, "/user/emails", &emails); err != nil {
+			return nil, err
+		}
+		for _, email := range emails {
+			if email.Primary && email.Verified {
+				user.Email = email.Email
+			}
+		}
+	}
+
+	firstName, lastName, _ := strings.Cut(user.Name, " ")
+	if firstName == "" {
+		firstName = user.Login
+	}
+
+	identity := Identity{
+		User: models.User{
+			ID:        provider.name + "|" + strconv.FormatInt(user.ID, 10),
+			FirstName: firstName,
+			LastName:  lastName,
+			Email:     user.Email,
+			Picture:   user.AvatarURL,
+		},
+	}
+
+	idToken, err := provider.sessions.Issue(identity.User)
+	if err != nil {
+		return nil, err
+	}
+	identity.IDToken = idToken
+
+	return &identity, nil
+}
+
+func (provider *GitHubProvider) get(client *http.Client, path string, target any) error {
+	resp, err := client.Get(provider.APIURL + path)
+	if err != nil {
+		return fmt.Errorf("failed getting %s from GitHub: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed getting %s from GitHub: unexpected status %d", path, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed decoding %s from GitHub: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package authn
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken   = errors.New("malformed id_token")
+	ErrInvalidSignature = errors.New("invalid id_token signature")
+	ErrInvalidIssuer    = errors.New("invalid id_token issuer")
+	ErrInvalidAudience  = errors.New("invalid id_token audience")
+	ErrTokenExpired     = errors.New("id_token expired")
+)
+
+// Audience is either a single string or an array of strings in a JWT
+type Audience []string
+
+func (audience *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*audience = Audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*audience = multiple
+	return nil
+}
+
+// ClaimBool is a boolean claim, some providers send it as the string "true" or "false"
+type ClaimBool bool
+
+func (claim *ClaimBool) UnmarshalJSON(data []byte) error {
+	var value bool
+	if err := json.Unmarshal(data, &value); err == nil {
+		*claim = ClaimBool(value)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*claim = ClaimBool(text == "true")
+	return nil
+}
+
+type IDTokenClaims struct {
+	Issuer        string    `json:"iss"`
+	Subject       string    `json:"sub"`
+	Audience      Audience  `json:"aud"`
+	ExpiresAt     int64     `json:"exp"`
+	IssuedAt      int64     `json:"iat"`
+	Email         string    `json:"email"`
+	EmailVerified ClaimBool `json:"email_verified"`
+	GivenName     string    `json:"given_name"`
+	FamilyName    string    `json:"family_name"`
+	Picture       string    `json:"picture"`
+	// Provider is the name of the identity provider which issued the token, it is
+	// empty for session tokens whose subject names the provider already
+	Provider string `json:"-"`
+}
+
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// TokenVerifier checks ID tokens of the issuers it accepts
+type TokenVerifier interface {
+	Accepts(issuer string) bool
+	Verify(ctx context.Context, token string) (*IDTokenClaims, error)
+}
+
+// IDTokenVerifier checks RS256 signed ID tokens locally against the keys of KeySource
+type IDTokenVerifier struct {
+	KeySource KeySource
+	Issuers   []string
+	Audience  string
+	Provider  string           // Name of the identity provider, set on the verified claims
+	Leeway    time.Duration    // Tolerated clock skew when checking expiry
+	Now       func() time.Time // Defaults to time.Now
+}
+
+func (verifier IDTokenVerifier) Accepts(issuer string) bool {
+	return slices.Contains(verifier.Issuers, issuer)
+}
+
+func (verifier IDTokenVerifier) Verify(ctx context.Context, token string) (*IDTokenClaims, error) {
+	header, signingInput, signature, err := splitToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrMalformedToken, header.Algorithm)
+	}
+
+	key, err := verifier.KeySource.PublicKey(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(signingInput))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	claims, err := verifyClaims(signingInput, verifier.Issuers, verifier.Audience, verifier.Leeway, verifier.Now)
+	if err != nil {
+		return nil, err
+	}
+	claims.Provider = verifier.Provider
+
+	return claims, nil
+}
+
+// MultiIssuerVerifier dispatches a token to the verifier accepting its issuer
+type MultiIssuerVerifier []TokenVerifier
+
+func (verifiers MultiIssuerVerifier) Accepts(issuer string) bool {
+	return slices.ContainsFunc(verifiers, func(verifier TokenVerifier) bool { return verifier.Accepts(issuer) })
+}
+
+func (verifiers MultiIssuerVerifier) Verify(ctx context.Context, token string) (*IDTokenClaims, error) {
+	// The issuer is read before the signature is checked only to pick the verifier,
+	// the chosen verifier checks the signature and the issuer again
+	issuer, err := unverifiedIssuer(token)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, verifier := range verifiers {
+		if verifier.Accepts(issuer) {
+			return verifier.Verify(ctx, token)
+		}
+	}
+
+	return nil, ErrInvalidIssuer
+}
+
+func splitToken(token string) (header tokenHeader, signingInput string, signature []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header, "", nil, ErrMalformedToken
+	}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return header, "", nil, err
+	}
+
+	signature, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header, "", nil, ErrMalformedToken
+	}
+
+	return header, parts[0] + "." + parts[1], signature, nil
+}
+
+func unverifiedIssuer(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrMalformedToken
+	}
+
+	var claims IDTokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", err
+	}
+	return claims.Issuer, nil
+}
+
+// verifyClaims decodes the payload of a token whose signature was already checked
+func verifyClaims(signingInput string, issuers []string, audience string, leeway time.Duration, now func() time.Time) (*IDTokenClaims, error) {
+	_, payload, _ := strings.Cut(signingInput, ".")
+
+	var claims IDTokenClaims
+	if err := decodeSegment(payload, &claims); err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(issuers, claims.Issuer) {
+		return nil, ErrInvalidIssuer
+	}
+
+	if !slices.Contains(claims.Audience, audience) {
+		return nil, ErrInvalidAudience
+	}
+
+	if now == nil {
+		now = time.Now
+	}
+	if now().Add(-leeway).After(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+func decodeSegment(segment string, target any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return ErrMalformedToken
+	}
+
+	return nil
+}
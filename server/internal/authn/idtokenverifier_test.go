@@ -0,0 +1,249 @@
+package authn_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"twitter-clone/internal/authn"
+	"twitter-clone/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKeyID    = "test-key"
+	testIssuer   = "https://accounts.google.com"
+	testAudience = "client-id.apps.googleusercontent.com"
+)
+
+func TestIDTokenVerifier_Verify(t *testing.T) {
+	key := generateKey(t)
+	verifier := authn.IDTokenVerifier{
+		KeySource: authn.StaticKeySource{testKeyID: &key.PublicKey},
+		Issuers:   config.GOOGLE_ISSUERS,
+		Audience:  testAudience,
+	}
+
+	testCases := []struct {
+		name        string
+		token       string
+		expectedErr error
+	}{
+		{name: "valid token", token: signToken(t, key, testKeyID, validClaims())},
+		{name: "expired token", token: signToken(t, key, testKeyID, withClaim(validClaims(), "exp", time.Now().Add(-time.Hour).Unix())), expectedErr: authn.ErrTokenExpired},
+		{name: "wrong audience", token: signToken(t, key, testKeyID, withClaim(validClaims(), "aud", "other-client")), expectedErr: authn.ErrInvalidAudience},
+		{name: "wrong issuer", token: signToken(t, key, testKeyID, withClaim(validClaims(), "iss", "https://evil.example.com")), expectedErr: authn.ErrInvalidIssuer},
+		{name: "unknown key", token: signToken(t, key, "rotated-key", validClaims()), expectedErr: authn.ErrUnknownKey},
+		{name: "signed by another key", token: signToken(t, generateKey(t), testKeyID, validClaims()), expectedErr: authn.ErrInvalidSignature},
+		{name: "malformed token", token: "not-a-jwt", expectedErr: authn.ErrMalformedToken},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), testCase.token)
+
+			if testCase.expectedErr != nil {
+				assert.ErrorIs(t, err, testCase.expectedErr)
+				assert.Nil(t, claims)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "user-subject", claims.Subject)
+			assert.Equal(t, "alice@gmail.com", claims.Email)
+		})
+	}
+}
+
+func TestIDTokenVerifier_AudienceArray(t *testing.T) {
+	key := generateKey(t)
+	verifier := authn.IDTokenVerifier{
+		KeySource: authn.StaticKeySource{testKeyID: &key.PublicKey},
+		Issuers:   config.GOOGLE_ISSUERS,
+		Audience:  testAudience,
+	}
+
+	token := signToken(t, key, testKeyID, withClaim(validClaims(), "aud", []string{"other-client", testAudience}))
+
+	_, err := verifier.Verify(context.Background(), token)
+	assert.NoError(t, err)
+}
+
+func TestRemoteKeySource_CachesKeySet(t *testing.T) {
+	key := generateKey(t)
+
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(authn.JSONWebKeySet{
+			Keys: []authn.JSONWebKey{authn.NewJSONWebKey(testKeyID, &key.PublicKey)},
+		})
+	}))
+	defer server.Close()
+
+	verifier := authn.IDTokenVerifier{
+		KeySource: authn.NewRemoteKeySource(server.URL),
+		Issuers:   config.GOOGLE_ISSUERS,
+		Audience:  testAudience,
+	}
+
+	token := signToken(t, key, testKeyID, validClaims())
+	for range 3 {
+		_, err := verifier.Verify(context.Background(), token)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "Key set should be fetched once and served from cache")
+
+	// An unknown key within the minimum refresh interval must not trigger another fetch
+	_, err := verifier.Verify(context.Background(), signToken(t, key, "rotated-key", validClaims()))
+	assert.ErrorIs(t, err, authn.ErrUnknownKey)
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+func TestRemoteKeySource_RateLimitsFailedFetches(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	source := authn.NewRemoteKeySource(server.URL)
+	for range 3 {
+		_, err := source.PublicKey(context.Background(), testKeyID)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, authn.ErrUnknownKey, "The fetch error should be returned while no keys are cached")
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "Failed fetches should not be retried within the minimum refresh interval")
+}
+
+func TestRemoteKeySource_SharesConcurrentFetch(t *testing.T) {
+	key := generateKey(t)
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(authn.JSONWebKeySet{
+			Keys: []authn.JSONWebKey{authn.NewJSONWebKey(testKeyID, &key.PublicKey)},
+		})
+	}))
+	defer server.Close()
+
+	source := authn.NewRemoteKeySource(server.URL)
+	results := make(chan error)
+	for range 5 {
+		go func() {
+			_, err := source.PublicKey(context.Background(), testKeyID)
+			results <- err
+		}()
+	}
+
+	require.Eventually(t, func() bool { return fetches.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	// A request giving up does not cancel the fetch the others wait for
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := source.PublicKey(ctx, testKeyID)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	for range 5 {
+		require.NoError(t, <-results)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "Concurrent requests should share a single fetch")
+}
+
+func TestAuthenticationValidator_ValidateAuthentication(t *testing.T) {
+	key := generateKey(t)
+	validator := authn.AuthenticationValidator{
+		Authentication: config.Authentication{Enable: true},
+		Verifier: authn.IDTokenVerifier{
+			KeySource: authn.StaticKeySource{testKeyID: &key.PublicKey},
+			Issuers:   config.GOOGLE_ISSUERS,
+			Audience:  testAudience,
+			Provider:  config.GoogleProvider,
+		},
+	}
+
+	// Valid token in the Authorization header
+	req := httptest.NewRequest("GET", "/api/tweets", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, key, testKeyID, validClaims()))
+	rr := httptest.NewRecorder()
+
+	user := validator.ValidateAuthentication(rr, req)
+	require.NotNil(t, user)
+	assert.Equal(t, config.GoogleProvider+"|user-subject", user.ID, "Subject should be prefixed with the provider")
+	assert.Equal(t, "Alice", user.FirstName)
+	assert.Empty(t, user.Email, "Unverified email should be dropped")
+	assert.False(t, user.IsAnonymous)
+
+	// Verified email, sent as a string by some providers
+	for _, verified := range []any{true, "true"} {
+		req = httptest.NewRequest("GET", "/api/tweets", nil)
+		req.Header.Set("Authorization", "Bearer "+signToken(t, key, testKeyID, withClaim(validClaims(), "email_verified", verified)))
+		rr = httptest.NewRecorder()
+
+		user = validator.ValidateAuthentication(rr, req)
+		require.NotNil(t, user)
+		assert.Equal(t, "alice@gmail.com", user.Email)
+	}
+
+	// Invalid token in the cookie
+	req = httptest.NewRequest("GET", "/api/tweets", nil)
+	req.AddCookie(&http.Cookie{Name: "id_token", Value: signToken(t, generateKey(t), testKeyID, validClaims())})
+	rr = httptest.NewRecorder()
+
+	user = validator.ValidateAuthentication(rr, req)
+	assert.Nil(t, user)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":        testIssuer,
+		"sub":        "user-subject",
+		"aud":        testAudience,
+		"exp":        time.Now().Add(time.Hour).Unix(),
+		"iat":        time.Now().Unix(),
+		"email":      "alice@gmail.com",
+		"given_name": "Alice",
+	}
+}
+
+func withClaim(claims map[string]any, name string, value any) map[string]any {
+	claims[name] = value
+	return claims
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, keyID string, claims map[string]any) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
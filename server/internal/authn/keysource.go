@@ -0,0 +1,193 @@
+package authn
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySource resolves the public key an ID token was signed with
+type KeySource interface {
+	PublicKey(ctx context.Context, keyID string) (*rsa.PublicKey, error)
+}
+
+// StaticKeySource serves a fixed key set, e.g. a locally generated one in tests
+type StaticKeySource map[string]*rsa.PublicKey
+
+func (source StaticKeySource) PublicKey(_ context.Context, keyID string) (*rsa.PublicKey, error) {
+	key, ok := source[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// RemoteKeySource fetches a JWKS document and caches it for RefreshInterval.
+// Unknown key IDs trigger an early refresh. Fetches, including failed ones, happen
+// at most once per MinRefreshInterval, so rotated keys are picked up without hammering
+// the provider. Concurrent requests share a single fetch.
+type RemoteKeySource struct {
+	URL                string
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+	Client             *http.Client
+
+	mutex       sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetchErr    error
+	// fetching is closed when the running fetch completes, nil while none runs
+	fetching chan struct{}
+}
+
+func NewRemoteKeySource(url string) *RemoteKeySource {
+	return &RemoteKeySource{
+		URL:                url,
+		RefreshInterval:    time.Hour,
+		MinRefreshInterval: time.Minute,
+		Client:             &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (source *RemoteKeySource) PublicKey(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	_, ok := source.keys[keyID]
+	stale := source.keys == nil || time.Since(source.fetchedAt) > source.RefreshInterval
+	due := time.Since(source.attemptedAt) > source.MinRefreshInterval
+	if (stale || !ok) && (due || source.fetching != nil) {
+		if err := source.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	key, ok := source.keys[keyID]
+	switch {
+	case ok:
+		// Keep serving the cached keys while the provider is unavailable
+		return key, nil
+	case source.fetchErr != nil:
+		return nil, source.fetchErr
+	default:
+		return nil, ErrUnknownKey
+	}
+}
+
+// refresh fetches the key set, or waits for the fetch already running. The mutex is held on
+// entry and return but released during the fetch, so requests for cached keys are not blocked.
+func (source *RemoteKeySource) refresh(ctx context.Context) error {
+	if fetching := source.fetching; fetching != nil {
+		source.mutex.Unlock()
+		defer source.mutex.Lock()
+
+		select {
+		case <-fetching:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	fetching := make(chan struct{})
+	source.fetching = fetching
+	source.attemptedAt = time.Now()
+	source.mutex.Unlock()
+
+	// The waiting requests share the result, so it is not cancelled along with this request
+	keys, err := source.fetch(context.WithoutCancel(ctx))
+
+	source.mutex.Lock()
+	if err == nil {
+		source.keys = keys
+		source.fetchedAt = time.Now()
+	}
+	source.fetchErr = err
+	source.fetching = nil
+	close(fetching)
+	return nil
+}
+
+func (source *RemoteKeySource) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := source.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed fetching key set: unexpected status %d", resp.StatusCode)
+	}
+
+	var keySet JSONWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&keySet); err != nil {
+		return nil, fmt.Errorf("failed decoding key set: %w", err)
+	}
+
+	return keySet.RSAPublicKeys()
+}
+
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// RSAPublicKeys returns the RSA signing keys of the set indexed by key ID
+func (keySet JSONWebKeySet) RSAPublicKeys() (map[string]*rsa.PublicKey, error) {
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range keySet.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		modulus, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %q: %w", jwk.KeyID, err)
+		}
+		exponent, err := base64.RawURLEncoding.DecodeString(jwk.Exponent)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %q: %w", jwk.KeyID, err)
+		}
+
+		keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// NewJSONWebKey describes an RSA public key as a JWK, e.g. to serve a local key set
+func NewJSONWebKey(keyID string, key *rsa.PublicKey) JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "RSA",
+		KeyID:     keyID,
+		Algorithm: "RS256",
+		Use:       "sig",
+		Modulus:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
@@ -1,16 +1,16 @@
 package authn
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"time"
 	"twitter-clone/internal/config"
 
+	"github.com/go-chi/chi/v5"
 	"golang.org/x/oauth2"
 )
 
@@ -20,6 +20,7 @@ type OAuth2Router struct {
 	AllowOrigin             string
 	Domain                  string
 	AuthenticationValidator IAuthenticationValidator
+	IdentityProviders       IdentityProviders
 }
 
 func enableCors(w *http.ResponseWriter, allowOrigin string) {
@@ -27,20 +28,25 @@ func enableCors(w *http.ResponseWriter, allowOrigin string) {
 	(*w).Header().Set("Access-Control-Allow-Credentials", "true")
 }
 
-type AuthenticationResult struct {
-	Contents []byte
-	IdToken  string
-}
-
-func (router OAuth2Router) OauthGoogleLogin(w http.ResponseWriter, r *http.Request) {
+func (router OAuth2Router) OauthLogin(w http.ResponseWriter, r *http.Request) {
 	enableCors(&w, router.AllowOrigin)
 
-	oauthState := router.generateStateOauthCookie(w)
-	u := router.Authentication.OAuth2.AuthCodeURL(oauthState, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	provider := router.getProvider(w, r)
+	if provider == nil {
+		return
+	}
+
+	oauthState := router.generateStateOauthCookie(w, provider.Name())
+	u := provider.OAuth2Config().AuthCodeURL(
+		oauthState.State,
+		oauth2.AccessTypeOffline,
+		oauth2.ApprovalForce,
+		oauth2.S256ChallengeOption(oauthState.CodeVerifier),
+	)
 	http.Redirect(w, r, u, http.StatusTemporaryRedirect)
 }
 
-func (router OAuth2Router) OauthGoogleLogout(w http.ResponseWriter, r *http.Request) {
+func (router OAuth2Router) OauthLogout(w http.ResponseWriter, r *http.Request) {
 	http.SetCookie(w, &http.Cookie{
 		Name:   "id_token",
 		Value:  "",
@@ -52,13 +58,43 @@ func (router OAuth2Router) OauthGoogleLogout(w http.ResponseWriter, r *http.Requ
 	http.Redirect(w, r, router.RedirectURI, http.StatusFound)
 }
 
-func (router OAuth2Router) OauthGoogleCallback(w http.ResponseWriter, r *http.Request) {
+func (router OAuth2Router) OauthCallback(w http.ResponseWriter, r *http.Request) {
 	enableCors(&w, router.AllowOrigin)
 
-	data, err := router.getUserDataFromGoogle(r.FormValue("code"))
+	provider := router.getProvider(w, r)
+	if provider == nil {
+		return
+	}
+
+	// The state cookie is single use
+	oauthState, err := readStateCookie(r, router.IdentityProviders.Sessions.Secret, r.FormValue("state"))
+	clearStateCookie(w)
+	if err == nil && oauthState.Provider != provider.Name() {
+		err = ErrInvalidState
+	}
 	if err != nil {
 		log.Println(err.Error())
-		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		router.redirectWithError(w, r, "invalid_state")
+		return
+	}
+
+	if providerError := r.FormValue("error"); providerError != "" {
+		log.Println("OAuth2 provider returned error: ", providerError)
+		router.redirectWithError(w, r, "access_denied")
+		return
+	}
+
+	token, err := provider.OAuth2Config().Exchange(r.Context(), r.FormValue("code"), oauth2.VerifierOption(oauthState.CodeVerifier))
+	if err != nil {
+		log.Println("code exchange failed: ", err.Error())
+		router.redirectWithError(w, r, "login_failed")
+		return
+	}
+
+	identity, err := provider.Identify(r.Context(), token)
+	if err != nil {
+		log.Println("failed identifying user: ", err.Error())
+		router.redirectWithError(w, r, "login_failed")
 		return
 	}
 
@@ -66,7 +102,7 @@ func (router OAuth2Router) OauthGoogleCallback(w http.ResponseWriter, r *http.Re
 	// Additionally token could be encrypted with AES encryption
 	http.SetCookie(w, &http.Cookie{
 		Name:     "id_token",
-		Value:    data.IdToken,
+		Value:    identity.IDToken,
 		Path:     "/",
 		HttpOnly: true, // Prevent JavaScript access
 		Secure:   true, // Ensure it's sent only over HTTPS
@@ -74,12 +110,8 @@ func (router OAuth2Router) OauthGoogleCallback(w http.ResponseWriter, r *http.Re
 		Domain:   router.Domain,
 	})
 
-	fmt.Printf("Set-Cookie: %s\n", w.Header().Get("Set-Cookie"))
-
 	// Redirect to the frontend or some protected page
 	http.Redirect(w, r, router.RedirectURI, http.StatusFound)
-
-	fmt.Fprintf(w, "UserInfo: %s\n", data.Contents)
 }
 
 func (router OAuth2Router) OauthUserInfo(w http.ResponseWriter, r *http.Request) {
@@ -95,38 +127,46 @@ func (router OAuth2Router) OauthUserInfo(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(user)
 }
 
-func (router OAuth2Router) getUserDataFromGoogle(code string) (*AuthenticationResult, error) {
-	// Use code to get token and get user info from Google.
-	token, err := router.Authentication.OAuth2.Exchange(context.Background(), code)
-	if err != nil {
-		return nil, fmt.Errorf("code exchange wrong: %s", err.Error())
+// getProvider looks up the provider named in the route and responds with 404 for unknown providers
+func (router OAuth2Router) getProvider(w http.ResponseWriter, r *http.Request) IdentityProvider {
+	name := chi.URLParam(r, "provider")
+	provider, ok := router.IdentityProviders.Providers[name]
+	if !ok {
+		http.Error(w, "Unknown identity provider: "+name, http.StatusNotFound)
+		return nil
 	}
 
-	response, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + token.AccessToken)
-	if err != nil {
-		return nil, fmt.Errorf("failed getting user info: %s", err.Error())
-	}
-	defer response.Body.Close()
-	contents, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed read response: %s", err.Error())
-	}
+	return provider
+}
 
-	log.Println("contents: ", contents)
-	log.Println("token: ", token)
+// generateStateOauthCookie creates the state and PKCE code verifier of a login attempt
+// and stores both in a short-lived signed cookie to be checked on callback
+func (router OAuth2Router) generateStateOauthCookie(w http.ResponseWriter, provider string) oauthState {
+	b := make([]byte, 16)
+	rand.Read(b)
 
-	result := AuthenticationResult{
-		Contents: contents,
-		IdToken:  token.Extra("id_token").(string),
+	state := oauthState{
+		Provider:     provider,
+		State:        base64.URLEncoding.EncodeToString(b),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		ExpiresAt:    time.Now().Add(oauthStateLifetime).Unix(),
 	}
+	setStateCookie(w, router.IdentityProviders.Sessions.Secret, state)
 
-	return &result, nil
+	return state
 }
 
-func (router OAuth2Router) generateStateOauthCookie(_ http.ResponseWriter) string {
-	b := make([]byte, 16)
-	rand.Read(b)
-	state := base64.URLEncoding.EncodeToString(b)
+// redirectWithError sends the user back to the client which shows the login error
+func (router OAuth2Router) redirectWithError(w http.ResponseWriter, r *http.Request, code string) {
+	redirectURL, err := url.Parse(router.RedirectURI)
+	if err != nil {
+		http.Error(w, "Login failed: "+code, http.StatusBadRequest)
+		return
+	}
 
-	return state
+	query := redirectURL.Query()
+	query.Set("error", code)
+	redirectURL.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
 }
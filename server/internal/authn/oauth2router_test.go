@@ -0,0 +1,144 @@
+package authn_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"twitter-clone/internal/authn"
+	"twitter-clone/internal/config"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func newTestOAuth2Router() authn.OAuth2Router {
+	authentication := config.Authentication{
+		Enable: true,
+		OAuth2: oauth2.Config{
+			ClientID:    testAudience,
+			RedirectURL: "http://localhost:8016/auth/google/callback",
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  "https://provider.example.com/auth",
+				TokenURL: "https://provider.example.com/token",
+			},
+		},
+	}
+	sessions := authn.SessionTokens{Secret: []byte("test-secret")}
+
+	return authn.OAuth2Router{
+		Authentication: authentication,
+		RedirectURI:    "http://localhost:3000/callback",
+		IdentityProviders: authn.IdentityProviders{
+			Providers: map[string]authn.IdentityProvider{
+				"google": authn.NewGoogleProvider("google", authentication.OAuth2),
+				"github": authn.NewGitHubProvider("github", oauth2.Config{ClientID: "github-client"}, sessions),
+			},
+			Sessions: sessions,
+		},
+	}
+}
+
+// newTestHandler mounts the router on the same routes as the API so the provider is read from the path
+func newTestHandler(router authn.OAuth2Router) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/auth/{provider}/login", router.OauthLogin)
+	r.Get("/auth/{provider}/callback", router.OauthCallback)
+	return r
+}
+
+func TestOauthLogin_SetsStateCookieAndPKCEChallenge(t *testing.T) {
+	router := newTestOAuth2Router()
+
+	rr := httptest.NewRecorder()
+	newTestHandler(router).ServeHTTP(rr, httptest.NewRequest("GET", "/auth/google/login", nil))
+
+	require.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+
+	location, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, location.Query().Get("state"), "Expected state in the authorization URL")
+	assert.NotEmpty(t, location.Query().Get("code_challenge"), "Expected PKCE challenge in the authorization URL")
+	assert.Equal(t, "S256", location.Query().Get("code_challenge_method"))
+
+	stateCookie := findCookie(rr.Result().Cookies(), "oauth_state")
+	require.NotNil(t, stateCookie, "Expected state cookie to be set")
+	assert.True(t, stateCookie.HttpOnly)
+	assert.Positive(t, stateCookie.MaxAge)
+}
+
+func TestOauthLogin_UnknownProvider(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTestHandler(newTestOAuth2Router()).ServeHTTP(rr, httptest.NewRequest("GET", "/auth/unknown/login", nil))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Nil(t, findCookie(rr.Result().Cookies(), "oauth_state"))
+}
+
+func TestOauthCallback_RejectsInvalidState(t *testing.T) {
+	router := newTestOAuth2Router()
+
+	// Start a login to obtain a genuine state cookie
+	stateCookie, authorization := startLogin(t, newTestHandler(router), "google")
+	state := authorization.Get("state")
+	githubStateCookie, githubAuthorization := startLogin(t, newTestHandler(router), "github")
+	githubState := githubAuthorization.Get("state")
+
+	otherRouter := newTestOAuth2Router()
+	otherRouter.IdentityProviders.Sessions.Secret = []byte("other-secret")
+
+	testCases := []struct {
+		name   string
+		router authn.OAuth2Router
+		state  string
+		cookie *http.Cookie
+	}{
+		{name: "missing cookie", router: router, state: state},
+		{name: "mismatching state", router: router, state: "forged-state", cookie: stateCookie},
+		{name: "missing state", router: router, cookie: stateCookie},
+		{name: "tampered cookie", router: router, state: state, cookie: &http.Cookie{Name: "oauth_state", Value: stateCookie.Value + "x"}},
+		{name: "cookie signed with another secret", router: otherRouter, state: state, cookie: stateCookie},
+		{name: "state issued for another provider", router: router, state: githubState, cookie: githubStateCookie},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/auth/google/callback?code=code&state="+url.QueryEscape(testCase.state), nil)
+			if testCase.cookie != nil {
+				req.AddCookie(testCase.cookie)
+			}
+			rr := httptest.NewRecorder()
+
+			newTestHandler(testCase.router).ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, "http://localhost:3000/callback?error=invalid_state", rr.Header().Get("Location"))
+			assert.Nil(t, findCookie(rr.Result().Cookies(), "id_token"), "No session must be established")
+		})
+	}
+}
+
+// startLogin returns the state cookie and the authorization URL parameters of a login attempt
+func startLogin(t *testing.T, handler http.Handler, provider string) (*http.Cookie, url.Values) {
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/auth/"+provider+"/login", nil))
+
+	stateCookie := findCookie(rr.Result().Cookies(), "oauth_state")
+	require.NotNil(t, stateCookie)
+
+	location, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+
+	return stateCookie, location.Query()
+}
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, cookie := range cookies {
+		if cookie.Name == name && cookie.MaxAge >= 0 {
+			return cookie
+		}
+	}
+	return nil
+}
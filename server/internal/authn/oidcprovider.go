@@ -0,0 +1,136 @@
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"twitter-clone/internal/config"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+var ErrMissingIDToken = errors.New("token response does not contain an id_token")
+
+// OIDCDiscovery is the subset of the OpenID provider metadata used for signing in
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider signs users in with the ID token returned by an OpenID Connect provider
+type OIDCProvider struct {
+	name     string
+	config   oauth2.Config
+	verifier IDTokenVerifier
+}
+
+func NewOIDCProvider(name string, oauth2Config oauth2.Config, discovery OIDCDiscovery, issuers ...string) *OIDCProvider {
+	if oauth2Config.Endpoint.AuthURL == "" {
+		oauth2Config.Endpoint = oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		}
+	}
+
+	if !slices.Contains(oauth2Config.Scopes, "openid") {
+		oauth2Config.Scopes = append([]string{"openid", "email", "profile"}, oauth2Config.Scopes...)
+	}
+
+	return &OIDCProvider{
+		name:   name,
+		config: oauth2Config,
+		verifier: IDTokenVerifier{
+			KeySource: NewRemoteKeySource(discovery.JWKSURI),
+			Issuers:   append([]string{discovery.Issuer}, issuers...),
+			Audience:  oauth2Config.ClientID,
+			Provider:  name,
+			Leeway:    time.Minute,
+		},
+	}
+}
+
+// NewGoogleProvider needs no discovery, Google's endpoints and keys are well known
+func NewGoogleProvider(name string, oauth2Config oauth2.Config) *OIDCProvider {
+	if oauth2Config.Endpoint.AuthURL == "" {
+		oauth2Config.Endpoint = google.Endpoint
+	}
+
+	provider := NewOIDCProvider(name, oauth2Config, OIDCDiscovery{
+		Issuer:  config.GOOGLE_ISSUERS[0],
+		JWKSURI: config.GOOGLE_CERTS_URL,
+	}, config.GOOGLE_ISSUERS[1:]...)
+
+	// Google hands out ID tokens for the userinfo scopes, no need to request openid explicitly
+	provider.config.Scopes = oauth2Config.Scopes
+
+	return provider
+}
+
+// DiscoverOIDCProvider reads the provider metadata from the issuer's well-known discovery document
+func DiscoverOIDCProvider(ctx context.Context, name string, issuer string, oauth2Config oauth2.Config) (*OIDCProvider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed fetching discovery document: unexpected status %d", resp.StatusCode)
+	}
+
+	var discovery OIDCDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, fmt.Errorf("failed decoding discovery document: %w", err)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document issuer %q does not match %q", discovery.Issuer, issuer)
+	}
+
+	return NewOIDCProvider(name, oauth2Config, discovery), nil
+}
+
+func (provider *OIDCProvider) Name() string {
+	return provider.name
+}
+
+func (provider *OIDCProvider) OAuth2Config() *oauth2.Config {
+	return &provider.config
+}
+
+func (provider *OIDCProvider) Verifier() TokenVerifier {
+	return provider.verifier
+}
+
+func (provider *OIDCProvider) Identify(ctx context.Context, token *oauth2.Token) (*Identity, error) {
+	idToken, ok := token.Extra("id_token").(string)
+	if !ok || idToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	claims, err := provider.verifier.Verify(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		User:    readUserFromClaims(*claims),
+		IDToken: idToken,
+	}, nil
+}
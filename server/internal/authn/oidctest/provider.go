@@ -0,0 +1,202 @@
+// Package oidctest provides a local OpenID Connect provider for tests
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+	"twitter-clone/internal/authn"
+)
+
+const keyID = "oidctest"
+
+// Provider signs in every user with the configured claims. Authorization requests are
+// approved without interaction, so a test can follow the login redirects end to end.
+type Provider struct {
+	*httptest.Server
+	ClientID string
+	Claims   map[string]any // Claims of the signed-in user, e.g. sub, email and given_name
+
+	key   *rsa.PrivateKey
+	mutex sync.Mutex
+	codes map[string]authorization
+}
+
+type authorization struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+}
+
+func NewProvider(clientID string, claims map[string]any) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &Provider{
+		ClientID: clientID,
+		Claims:   claims,
+		key:      key,
+		codes:    map[string]authorization{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("GET /authorize", provider.authorize)
+	mux.HandleFunc("POST /token", provider.token)
+	mux.HandleFunc("GET /jwks", provider.jwks)
+	provider.Server = httptest.NewServer(mux)
+
+	return provider, nil
+}
+
+// Issuer is the discovery URL base of the provider
+func (provider *Provider) Issuer() string {
+	return provider.URL
+}
+
+// SignIDToken signs an ID token for the client with the provider's key
+func (provider *Provider) SignIDToken(claims map[string]any) (string, error) {
+	now := time.Now()
+	payload := map[string]any{
+		"iss": provider.Issuer(),
+		"aud": provider.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		payload[name] = value
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	encodedPayload, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(encodedPayload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, provider.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (provider *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(authn.OIDCDiscovery{
+		Issuer:                provider.Issuer(),
+		AuthorizationEndpoint: provider.URL + "/authorize",
+		TokenEndpoint:         provider.URL + "/token",
+		JWKSURI:               provider.URL + "/jwks",
+	})
+}
+
+func (provider *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(authn.JSONWebKeySet{
+		Keys: []authn.JSONWebKey{authn.NewJSONWebKey(keyID, &provider.key.PublicKey)},
+	})
+}
+
+// authorize redirects straight back to the client with a single use authorization code
+func (provider *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != provider.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURL.Host == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	provider.mutex.Lock()
+	provider.codes[code] = authorization{
+		redirectURI:   query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		nonce:         query.Get("nonce"),
+	}
+	provider.mutex.Unlock()
+
+	callbackQuery := redirectURL.Query()
+	callbackQuery.Set("code", code)
+	callbackQuery.Set("state", query.Get("state"))
+	redirectURL.RawQuery = callbackQuery.Encode()
+
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+// token exchanges an authorization code for an ID token after checking the PKCE code verifier
+func (provider *Provider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.FormValue("client_id")
+	}
+
+	provider.mutex.Lock()
+	authorization, found := provider.codes[r.FormValue("code")]
+	delete(provider.codes, r.FormValue("code"))
+	provider.mutex.Unlock()
+
+	verifierDigest := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !found ||
+		clientID != provider.ClientID ||
+		r.FormValue("grant_type") != "authorization_code" ||
+		r.FormValue("redirect_uri") != authorization.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(verifierDigest[:]) != authorization.codeChallenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]any{}
+	for name, value := range provider.Claims {
+		claims[name] = value
+	}
+	if authorization.nonce != "" {
+		claims["nonce"] = authorization.nonce
+	}
+
+	idToken, err := provider.SignIDToken(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
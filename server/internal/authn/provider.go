@@ -0,0 +1,93 @@
+package authn
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+
+	"golang.org/x/oauth2"
+)
+
+// IdentityProvider signs users in through the OAuth2 authorization code flow
+type IdentityProvider interface {
+	Name() string
+	OAuth2Config() *oauth2.Config
+	// Identify maps the token response of the provider onto the signed-in user
+	// and the ID token presented on subsequent requests
+	Identify(ctx context.Context, token *oauth2.Token) (*Identity, error)
+	// Verifier checks the ID tokens handed out by Identify
+	Verifier() TokenVerifier
+}
+
+type Identity struct {
+	User    models.User
+	IDToken string
+}
+
+type IdentityProviders struct {
+	Providers map[string]IdentityProvider
+	Sessions  SessionTokens
+}
+
+// NewIdentityProviders creates the Google provider from Authentication.OAuth2 and
+// every provider listed in Authentication.Providers
+func NewIdentityProviders(ctx context.Context, authentication config.Authentication) (IdentityProviders, error) {
+	identityProviders := IdentityProviders{
+		Providers: map[string]IdentityProvider{},
+		Sessions:  SessionTokens{Secret: CookieSecret(authentication)},
+	}
+
+	if !authentication.Enable {
+		return identityProviders, nil
+	}
+
+	if authentication.OAuth2.ClientID != "" {
+		identityProviders.Providers[config.GoogleProvider] = NewGoogleProvider(config.GoogleProvider, authentication.OAuth2)
+	}
+
+	for _, providerConfig := range authentication.Providers {
+		provider, err := newIdentityProvider(ctx, providerConfig, identityProviders.Sessions)
+		if err != nil {
+			return identityProviders, fmt.Errorf("failed to create identity provider %q: %w", providerConfig.Name, err)
+		}
+		identityProviders.Providers[providerConfig.Name] = provider
+	}
+
+	return identityProviders, nil
+}
+
+func newIdentityProvider(ctx context.Context, providerConfig config.IdentityProvider, sessions SessionTokens) (IdentityProvider, error) {
+	switch providerConfig.Type {
+	case config.GoogleProvider:
+		return NewGoogleProvider(providerConfig.Name, providerConfig.OAuth2), nil
+	case config.OIDCProvider:
+		return DiscoverOIDCProvider(ctx, providerConfig.Name, providerConfig.Issuer, providerConfig.OAuth2)
+	case config.GitHubProvider:
+		return NewGitHubProvider(providerConfig.Name, providerConfig.OAuth2, sessions), nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", providerConfig.Type)
+	}
+}
+
+// Verifier accepts the ID tokens of every provider and the session tokens
+func (identityProviders IdentityProviders) Verifier() MultiIssuerVerifier {
+	verifiers := MultiIssuerVerifier{identityProviders.Sessions}
+	for _, provider := range identityProviders.Providers {
+		verifiers = append(verifiers, provider.Verifier())
+	}
+	return verifiers
+}
+
+// CookieSecret returns the configured key for signing cookies and session tokens.
+// Without one a random key is generated, which only works for a single replica.
+func CookieSecret(authentication config.Authentication) []byte {
+	if authentication.CookieSecret != "" {
+		return []byte(authentication.CookieSecret)
+	}
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}
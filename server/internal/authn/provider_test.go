@@ -0,0 +1,168 @@
+package authn_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"twitter-clone/internal/authn"
+	"twitter-clone/internal/authn/oidctest"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+const testCode = "authorization-code"
+
+func TestOauthCallback_OIDCProvider(t *testing.T) {
+	oidcProvider, err := oidctest.NewProvider("corp-client", map[string]any{
+		"sub":            "corp-user",
+		"email":          "alice@corp.example.com",
+		"email_verified": true,
+		"given_name":     "Alice",
+	})
+	require.NoError(t, err)
+	defer oidcProvider.Close()
+
+	provider, err := authn.DiscoverOIDCProvider(context.Background(), "corp", oidcProvider.Issuer(), oauth2.Config{
+		ClientID:    "corp-client",
+		RedirectURL: "http://localhost:8016/auth/corp/callback",
+	})
+	require.NoError(t, err)
+
+	router := newTestOAuth2Router()
+	router.IdentityProviders.Providers["corp"] = provider
+	handler := newTestHandler(router)
+
+	stateCookie, authorization := startLogin(t, handler, "corp")
+	assert.Contains(t, authorization.Get("scope"), "openid")
+
+	// Follow the redirect to the provider, which approves the login and redirects back with a code
+	loginURL := oidcProvider.URL + "/authorize?" + authorization.Encode()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(loginURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callbackURL, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	idToken := completeLogin(t, handler, callbackURL, stateCookie)
+
+	user := validateIDToken(t, router.IdentityProviders, idToken)
+	assert.Equal(t, "corp|corp-user", user.ID)
+	assert.Equal(t, "Alice", user.FirstName)
+	assert.Equal(t, "alice@corp.example.com", user.Email)
+
+	// The authorization code is single use
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", callbackURL.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "http://localhost:3000/callback?error=login_failed", rr.Header().Get("Location"))
+}
+
+func TestOauthCallback_GitHubProvider(t *testing.T) {
+	// Mock GitHub OAuth and REST API
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != testCode || r.FormValue("code_verifier") == "" {
+			http.Error(w, `{"error":"bad_verification_code"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "github-token", "token_type": "bearer"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer github-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "login": "octocat", "name": "Mona Lisa"})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"email": "octocat@users.noreply.github.com", "primary": false, "verified": true},
+			{"email": "mona@example.com", "primary": true, "verified": true},
+		})
+	})
+
+	router := newTestOAuth2Router()
+	provider := authn.NewGitHubProvider("github", oauth2.Config{
+		ClientID: "github-client",
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  server.URL + "/login/oauth/authorize",
+			TokenURL: server.URL + "/login/oauth/access_token",
+		},
+	}, router.IdentityProviders.Sessions)
+	provider.APIURL = server.URL
+	router.IdentityProviders.Providers["github"] = provider
+	handler := newTestHandler(router)
+
+	stateCookie, authorization := startLogin(t, handler, "github")
+	callbackURL, err := url.Parse("/auth/github/callback?code=" + testCode + "&state=" + url.QueryEscape(authorization.Get("state")))
+	require.NoError(t, err)
+	idToken := completeLogin(t, handler, callbackURL, stateCookie)
+
+	user := validateIDToken(t, router.IdentityProviders, idToken)
+	assert.Equal(t, "github|42", user.ID)
+	assert.Equal(t, "Mona", user.FirstName)
+	assert.Equal(t, "Lisa", user.LastName)
+	assert.Equal(t, "mona@example.com", user.Email)
+}
+
+func TestSessionTokens_Verify(t *testing.T) {
+	now := time.Now()
+	sessions := authn.SessionTokens{Secret: []byte("test-secret"), Now: func() time.Time { return now }}
+
+	token, err := sessions.Issue(models.User{ID: "github|42", Email: "mona@example.com"})
+	require.NoError(t, err)
+
+	claims, err := sessions.Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "github|42", claims.Subject)
+
+	_, err = authn.SessionTokens{Secret: []byte("other-secret")}.Verify(context.Background(), token)
+	assert.ErrorIs(t, err, authn.ErrInvalidSignature)
+
+	later := authn.SessionTokens{Secret: sessions.Secret, Now: func() time.Time { return now.Add(24 * time.Hour) }}
+	_, err = later.Verify(context.Background(), token)
+	assert.ErrorIs(t, err, authn.ErrTokenExpired)
+}
+
+// completeLogin returns to the callback with the authorization code and returns the ID token cookie
+func completeLogin(t *testing.T, handler http.Handler, callbackURL *url.URL, stateCookie *http.Cookie) string {
+	req := httptest.NewRequest("GET", callbackURL.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusFound, rr.Code)
+	require.Equal(t, "http://localhost:3000/callback", rr.Header().Get("Location"))
+
+	idTokenCookie := findCookie(rr.Result().Cookies(), "id_token")
+	require.NotNil(t, idTokenCookie, "Expected id_token cookie to be set")
+	return idTokenCookie.Value
+}
+
+func validateIDToken(t *testing.T, identityProviders authn.IdentityProviders, idToken string) *models.User {
+	validator := authn.NewAuthenticationValidator(config.Authentication{Enable: true}, identityProviders, nil)
+
+	req := httptest.NewRequest("GET", "/api/tweets", nil)
+	req.Header.Set("Authorization", "Bearer "+idToken)
+	rr := httptest.NewRecorder()
+
+	user := validator.ValidateAuthentication(rr, req)
+	require.NotNil(t, user, rr.Body.String())
+	return user
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 )
@@ -14,6 +15,7 @@ const (
 	InMemory Mode = iota + 1
 	Persistent
 	Cloud
+	Embedded
 )
 
 var (
@@ -21,11 +23,13 @@ var (
 		1: "inmemory",
 		2: "persistent",
 		3: "cloud",
+		4: "embedded",
 	}
 	Mode_value = map[string]uint8{
 		"inmemory":   1,
 		"persistent": 2,
 		"cloud":      3,
+		"embedded":   4,
 	}
 )
 
@@ -57,34 +61,175 @@ func ParseMode(s string) (Mode, error) {
 	return Mode(value), nil
 }
 
+// modePreset holds the drivers selected by a mode
+type modePreset struct {
+	tweetsStorage string
+	feedsStorage  string
+	messaging     string
+}
+
+var modePresets = map[Mode]modePreset{
+	InMemory:   {tweetsStorage: MemoryDriver, feedsStorage: MemoryDriver, messaging: MemoryDriver},
+	Persistent: {tweetsStorage: MySQLDriver, feedsStorage: MongoDriver, messaging: JetStreamDriver},
+	Cloud:      {tweetsStorage: FirestoreDriver, feedsStorage: FirestoreDriver, messaging: PubSubDriver},
+	Embedded:   {tweetsStorage: BoltDriver, feedsStorage: BoltDriver, messaging: MemoryDriver},
+}
+
+// ApplyModePreset fills every driver which is not configured explicitly with the default of the mode
+func (configuration *Configuration) ApplyModePreset() {
+	preset := modePresets[configuration.Mode]
+
+	if configuration.TweetsStorage.Driver == "" {
+		configuration.TweetsStorage.Driver = preset.tweetsStorage
+	}
+	if configuration.FeedsStorage.Driver == "" {
+		configuration.FeedsStorage.Driver = preset.feedsStorage
+	}
+	if configuration.Messaging.Driver == "" {
+		configuration.Messaging.Driver = preset.messaging
+	}
+}
+
 type ApiServer struct {
 	ApplicationUrl string
 }
 
+// Storage and messaging drivers, each section selects its own
+const (
+	MemoryDriver    = "memory"
+	MySQLDriver     = "mysql"
+	MongoDriver     = "mongo"
+	PostgresDriver  = "postgres"
+	FirestoreDriver = "firestore"
+	BoltDriver      = "bolt"
+	NATSDriver      = "nats" // Deprecated NATS Streaming, use jetstream
+	JetStreamDriver = "jetstream"
+	KafkaDriver     = "kafka"
+	PubSubDriver    = "pubsub"
+	NullDriver      = "none"
+)
+
+// Encodings of the published events
+const (
+	JSONEncoding     = "json"
+	ProtobufEncoding = "protobuf"
+)
+
 type TweetsStorage struct {
+	Driver           string // memory, mysql, postgres, firestore or bolt
 	ConnectionString string
 	DatabaseName     string
+	AutoMigrate      bool // Apply pending schema migrations on startup, only used by mysql
 }
 
 type FeedsStorage struct {
+	Driver           string // memory, mongo, postgres, firestore or bolt
 	ConnectionString string
 	DatabaseName     string
-	CollectionName   string
+	CollectionName   string // Only used by mongo
 }
 
-type Authentication struct {
-	Enable bool
+type Messaging struct {
+	Driver        string // memory, jetstream, nats, kafka, pubsub or none
+	Encoding      string // json or protobuf, the format of the published events
+	JetStream     JetStream
+	Kafka         Kafka
+	Retry         Retry
+	Outbox        Outbox
+	Deduplication Deduplication
+}
+
+// Deduplication of redelivered tweet events by the feed handlers
+type Deduplication struct {
+	TTL Duration // How long handled events are remembered
+}
+
+// Outbox relay forwarding the tweet events recorded by the tweets storage
+type Outbox struct {
+	PollInterval Duration // How often pending events are looked up besides after every tweet change
+	BatchSize    int      // Events read per lookup
+}
+
+// Retry of failing feed updates, messages which exhaust the retries are moved to the poison topic
+type Retry struct {
+	MaxRetries      int
+	InitialInterval Duration // Delay before the first retry
+	MaxInterval     Duration // Upper bound of the delay between retries
+	Multiplier      float64  // Growth of the delay after every retry
+}
+
+// JetStream settings are shared by all replicas, so they consume feed updates together
+type JetStream struct {
+	Stream     string   // Stream holding every topic as a <Stream>.<topic> subject, created when missing
+	Consumer   string   // Prefix of the durable consumers processing feed updates
+	AckWait    Duration // How long a delivery may stay unacknowledged before it is redelivered
+	MaxDeliver int      // Deliveries of a message before it is given up, -1 for unlimited
+}
+
+// Kafka settings, tweet events are partitioned by the first tag of the tweet and feed updates by
+// the feed name, every event has its own topic
+type Kafka struct {
+	Brokers       []string
+	ConsumerGroup string // Consumer group processing feed updates, shared by all replicas
+}
+
+// Duration reads durations like "30s" from the settings
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) (err error) {
+	var duration string
+	if err := json.Unmarshal(data, &duration); err != nil {
+		return err
+	}
+	d.Duration, err = time.ParseDuration(duration)
+	return err
+}
+
+type EmbeddedStorage struct {
+	Path string // bbolt file holding tweets and feeds in embedded mode
+}
+
+const (
+	GoogleProvider = "google"
+	OIDCProvider   = "oidc"
+	GitHubProvider = "github"
+)
+
+type IdentityProvider struct {
+	Name   string // Used in the auth routes, e.g. /auth/{name}/login
+	Type   string // One of google, oidc or github
+	Issuer string // Discovery URL base of oidc providers
 	OAuth2 oauth2.Config
 }
 
+type Authentication struct {
+	Enable       bool
+	OAuth2       oauth2.Config // Google provider available as /auth/google/*
+	CookieSecret string        // Signs OAuth2 state cookies and session tokens, must be shared by all replicas
+	Providers    []IdentityProvider
+}
+
+type Authorization struct {
+	Admins []string // IDs (<provider>|<subject>) or verified emails of users granted the admin role
+}
+
 type Configuration struct {
-	Mode           Mode
-	ApiServer      ApiServer
-	TweetsStorage  TweetsStorage
-	FeedsStorage   FeedsStorage
-	NATSUrl        string
-	Authentication Authentication
-	RedirectURI    string
-	AllowOrigin    string // When using credentials (like cookies or HTTP authentication), CORS header Access-Control-Allow-Origin cannot be set to *
-	ProjectId      string
+	Mode            Mode
+	ApiServer       ApiServer
+	TweetsStorage   TweetsStorage
+	FeedsStorage    FeedsStorage
+	EmbeddedStorage EmbeddedStorage
+	Messaging       Messaging
+	NATSUrl         string
+	Authentication  Authentication
+	Authorization   Authorization
+	RedirectURI     string
+	AllowOrigin     string // When using credentials (like cookies or HTTP authentication), CORS header Access-Control-Allow-Origin cannot be set to *
+	ProjectId       string
 }
@@ -6,8 +6,8 @@ import (
 	"log"
 	"os"
 	"strconv"
-
-	"golang.org/x/oauth2/google"
+	"strings"
+	"time"
 )
 
 //go:embed appsettings.json
@@ -20,16 +20,11 @@ func ReadConfiguration() Configuration {
 	googleApplicationCredentialsEnvVar := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
 	log.Println("Read GOOGLE_APPLICATION_CREDENTIALS from environment variable: ", googleApplicationCredentialsEnvVar)
 
-	// Set default google endpoints
 	if authenticationEnableEnvVar := os.Getenv("AUTHENTICATION_ENABLE"); authenticationEnableEnvVar != "" {
 		log.Println("Overriding AUTHENTICATION_ENABLE from environment variable: ", authenticationEnableEnvVar)
 		configuration.Authentication.Enable, _ = strconv.ParseBool(authenticationEnableEnvVar)
 	}
 
-	if configuration.Authentication.Enable {
-		configuration.Authentication.OAuth2.Endpoint = google.Endpoint
-	}
-
 	if modeEnvVar := os.Getenv("MODE"); modeEnvVar != "" {
 		log.Println("Overriding MODE from environment variable: ", modeEnvVar)
 		configuration.Mode, _ = ParseMode(modeEnvVar)
@@ -50,21 +45,51 @@ func ReadConfiguration() Configuration {
 		configuration.Authentication.OAuth2.ClientSecret = clientSecretEnvVar
 	}
 
+	if cookieSecretEnvVar := os.Getenv("AUTHENTICATION_COOKIESECRET"); cookieSecretEnvVar != "" {
+		log.Println("Overriding AUTHENTICATION_COOKIESECRET from environment variable")
+		configuration.Authentication.CookieSecret = cookieSecretEnvVar
+	}
+
 	if redirectUrlEnvVar := os.Getenv("AUTHENTICATION_OAUTH2_REDIRECT_URI"); redirectUrlEnvVar != "" {
 		log.Println("Overriding AUTHENTICATION_OAUTH2_REDIRECT_URI from environment variable: ", redirectUrlEnvVar)
 		configuration.Authentication.OAuth2.RedirectURL = redirectUrlEnvVar
 	}
 
+	if authorizationAdminsEnvVar := os.Getenv("AUTHORIZATION_ADMINS"); authorizationAdminsEnvVar != "" {
+		log.Println("Overriding AUTHORIZATION_ADMINS from environment variable: ", authorizationAdminsEnvVar)
+		configuration.Authorization.Admins = strings.Split(authorizationAdminsEnvVar, ",")
+	}
+
 	if tweetsStorageConnectionStringEnvVar := os.Getenv("TWEETSSTORAGE_CONNECTIONSTRING"); tweetsStorageConnectionStringEnvVar != "" {
 		log.Println("Overriding TWEETSSTORAGE_CONNECTIONSTRING from environment variable: ", tweetsStorageConnectionStringEnvVar)
 		configuration.TweetsStorage.ConnectionString = tweetsStorageConnectionStringEnvVar
 	}
 
+	if tweetsStorageDriverEnvVar := os.Getenv("TWEETSSTORAGE_DRIVER"); tweetsStorageDriverEnvVar != "" {
+		log.Println("Overriding TWEETSSTORAGE_DRIVER from environment variable: ", tweetsStorageDriverEnvVar)
+		configuration.TweetsStorage.Driver = tweetsStorageDriverEnvVar
+	}
+
+	if tweetsStorageAutoMigrateEnvVar := os.Getenv("TWEETSSTORAGE_AUTOMIGRATE"); tweetsStorageAutoMigrateEnvVar != "" {
+		log.Println("Overriding TWEETSSTORAGE_AUTOMIGRATE from environment variable: ", tweetsStorageAutoMigrateEnvVar)
+		configuration.TweetsStorage.AutoMigrate, _ = strconv.ParseBool(tweetsStorageAutoMigrateEnvVar)
+	}
+
 	if feedsStorageConnectionStringEnvVar := os.Getenv("FEEDSSTORAGE_CONNECTIONSTRING"); feedsStorageConnectionStringEnvVar != "" {
 		log.Println("Overriding FEEDSSTORAGE_CONNECTIONSTRING from environment variable: ", feedsStorageConnectionStringEnvVar)
 		configuration.FeedsStorage.ConnectionString = feedsStorageConnectionStringEnvVar
 	}
 
+	if feedsStorageDriverEnvVar := os.Getenv("FEEDSSTORAGE_DRIVER"); feedsStorageDriverEnvVar != "" {
+		log.Println("Overriding FEEDSSTORAGE_DRIVER from environment variable: ", feedsStorageDriverEnvVar)
+		configuration.FeedsStorage.Driver = feedsStorageDriverEnvVar
+	}
+
+	if embeddedStoragePathEnvVar := os.Getenv("EMBEDDEDSTORAGE_PATH"); embeddedStoragePathEnvVar != "" {
+		log.Println("Overriding EMBEDDEDSTORAGE_PATH from environment variable: ", embeddedStoragePathEnvVar)
+		configuration.EmbeddedStorage.Path = embeddedStoragePathEnvVar
+	}
+
 	if apiServerApplicationUrlStringEnvVar := os.Getenv("APISERVER_APPLICATIONURL"); apiServerApplicationUrlStringEnvVar != "" {
 		log.Println("Overriding APISERVER_APPLICATIONURL from environment variable: ", apiServerApplicationUrlStringEnvVar)
 		configuration.ApiServer.ApplicationUrl = apiServerApplicationUrlStringEnvVar
@@ -75,6 +100,71 @@ func ReadConfiguration() Configuration {
 		configuration.NATSUrl = natsUrlStringEnvVar
 	}
 
+	if messagingDriverEnvVar := os.Getenv("MESSAGING_DRIVER"); messagingDriverEnvVar != "" {
+		log.Println("Overriding MESSAGING_DRIVER from environment variable: ", messagingDriverEnvVar)
+		configuration.Messaging.Driver = messagingDriverEnvVar
+	}
+
+	if messagingEncodingEnvVar := os.Getenv("MESSAGING_ENCODING"); messagingEncodingEnvVar != "" {
+		log.Println("Overriding MESSAGING_ENCODING from environment variable: ", messagingEncodingEnvVar)
+		configuration.Messaging.Encoding = messagingEncodingEnvVar
+	}
+
+	if jetStreamStreamEnvVar := os.Getenv("MESSAGING_JETSTREAM_STREAM"); jetStreamStreamEnvVar != "" {
+		log.Println("Overriding MESSAGING_JETSTREAM_STREAM from environment variable: ", jetStreamStreamEnvVar)
+		configuration.Messaging.JetStream.Stream = jetStreamStreamEnvVar
+	}
+
+	if jetStreamConsumerEnvVar := os.Getenv("MESSAGING_JETSTREAM_CONSUMER"); jetStreamConsumerEnvVar != "" {
+		log.Println("Overriding MESSAGING_JETSTREAM_CONSUMER from environment variable: ", jetStreamConsumerEnvVar)
+		configuration.Messaging.JetStream.Consumer = jetStreamConsumerEnvVar
+	}
+
+	if jetStreamAckWaitEnvVar := os.Getenv("MESSAGING_JETSTREAM_ACKWAIT"); jetStreamAckWaitEnvVar != "" {
+		log.Println("Overriding MESSAGING_JETSTREAM_ACKWAIT from environment variable: ", jetStreamAckWaitEnvVar)
+		configuration.Messaging.JetStream.AckWait.Duration, _ = time.ParseDuration(jetStreamAckWaitEnvVar)
+	}
+
+	if jetStreamMaxDeliverEnvVar := os.Getenv("MESSAGING_JETSTREAM_MAXDELIVER"); jetStreamMaxDeliverEnvVar != "" {
+		log.Println("Overriding MESSAGING_JETSTREAM_MAXDELIVER from environment variable: ", jetStreamMaxDeliverEnvVar)
+		configuration.Messaging.JetStream.MaxDeliver, _ = strconv.Atoi(jetStreamMaxDeliverEnvVar)
+	}
+
+	if kafkaBrokersEnvVar := os.Getenv("MESSAGING_KAFKA_BROKERS"); kafkaBrokersEnvVar != "" {
+		log.Println("Overriding MESSAGING_KAFKA_BROKERS from environment variable: ", kafkaBrokersEnvVar)
+		configuration.Messaging.Kafka.Brokers = strings.Split(kafkaBrokersEnvVar, ",")
+	}
+
+	if kafkaConsumerGroupEnvVar := os.Getenv("MESSAGING_KAFKA_CONSUMERGROUP"); kafkaConsumerGroupEnvVar != "" {
+		log.Println("Overriding MESSAGING_KAFKA_CONSUMERGROUP from environment variable: ", kafkaConsumerGroupEnvVar)
+		configuration.Messaging.Kafka.ConsumerGroup = kafkaConsumerGroupEnvVar
+	}
+
+	if retryMaxRetriesEnvVar := os.Getenv("MESSAGING_RETRY_MAXRETRIES"); retryMaxRetriesEnvVar != "" {
+		log.Println("Overriding MESSAGING_RETRY_MAXRETRIES from environment variable: ", retryMaxRetriesEnvVar)
+		configuration.Messaging.Retry.MaxRetries, _ = strconv.Atoi(retryMaxRetriesEnvVar)
+	}
+
+	if retryInitialIntervalEnvVar := os.Getenv("MESSAGING_RETRY_INITIALINTERVAL"); retryInitialIntervalEnvVar != "" {
+		log.Println("Overriding MESSAGING_RETRY_INITIALINTERVAL from environment variable: ", retryInitialIntervalEnvVar)
+		configuration.Messaging.Retry.InitialInterval.Duration, _ = time.ParseDuration(retryInitialIntervalEnvVar)
+	}
+
+	if retryMaxIntervalEnvVar := os.Getenv("MESSAGING_RETRY_MAXINTERVAL"); retryMaxIntervalEnvVar != "" {
+		log.Println("Overriding MESSAGING_RETRY_MAXINTERVAL from environment variable: ", retryMaxIntervalEnvVar)
+		configuration.Messaging.Retry.MaxInterval.Duration, _ = time.ParseDuration(retryMaxIntervalEnvVar)
+	}
+
+	if outboxPollIntervalEnvVar := os.Getenv("MESSAGING_OUTBOX_POLLINTERVAL"); outboxPollIntervalEnvVar != "" {
+		log.Println("Overriding MESSAGING_OUTBOX_POLLINTERVAL from environment variable: ", outboxPollIntervalEnvVar)
+		configuration.Messaging.Outbox.PollInterval.Duration, _ = time.ParseDuration(outboxPollIntervalEnvVar)
+	}
+
+	if deduplicationTTLEnvVar := os.Getenv("MESSAGING_DEDUPLICATION_TTL"); deduplicationTTLEnvVar != "" {
+		log.Println("Overriding MESSAGING_DEDUPLICATION_TTL from environment variable: ", deduplicationTTLEnvVar)
+		configuration.Messaging.Deduplication.TTL.Duration, _ = time.ParseDuration(deduplicationTTLEnvVar)
+	}
+
 	if redirectUriStringEnvVar := os.Getenv("REDIRECT_URI"); redirectUriStringEnvVar != "" {
 		log.Println("Overriding REDIRECT_URI from environment variable: ", redirectUriStringEnvVar)
 		configuration.RedirectURI = redirectUriStringEnvVar
@@ -85,6 +175,9 @@ func ReadConfiguration() Configuration {
 		configuration.AllowOrigin = allowOriginStringEnvVar
 	}
 
+	// The mode only decides the drivers which are not configured explicitly
+	configuration.ApplyModePreset()
+
 	configurationJson, err := json.MarshalIndent(configuration, "", "  ")
 	if err != nil {
 		log.Println("Error marshalling configuration to JSON: ", err)
@@ -5,10 +5,10 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 	config "twitter-clone/internal/config"
 
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 func TestReadConfiguration_DefaultsFromEmbeddedFile(t *testing.T) {
@@ -23,14 +23,47 @@ func TestReadConfiguration_DefaultsFromEmbeddedFile(t *testing.T) {
 			ApplicationUrl: "localhost:8016",
 		},
 		TweetsStorage: config.TweetsStorage{
+			Driver:           "memory",
 			ConnectionString: "myuser:mypassword@tcp(127.0.0.1:3306)",
 			DatabaseName:     "TweetsDb",
+			AutoMigrate:      true,
 		},
 		FeedsStorage: config.FeedsStorage{
+			Driver:           "memory",
 			ConnectionString: "mongodb://localhost:27017",
 			DatabaseName:     "FeedsDb",
 			CollectionName:   "Feeds",
 		},
+		EmbeddedStorage: config.EmbeddedStorage{
+			Path: "twitter-clone.db",
+		},
+		Messaging: config.Messaging{
+			Driver:   "memory",
+			Encoding: "json",
+			JetStream: config.JetStream{
+				Stream:     "TWITTER",
+				Consumer:   "twitter-clone",
+				AckWait:    config.Duration{Duration: 30 * time.Second},
+				MaxDeliver: 5,
+			},
+			Kafka: config.Kafka{
+				Brokers:       []string{"localhost:9092"},
+				ConsumerGroup: "twitter-clone",
+			},
+			Retry: config.Retry{
+				MaxRetries:      3,
+				InitialInterval: config.Duration{Duration: time.Second},
+				MaxInterval:     config.Duration{Duration: 30 * time.Second},
+				Multiplier:      2,
+			},
+			Outbox: config.Outbox{
+				PollInterval: config.Duration{Duration: 5 * time.Second},
+				BatchSize:    100,
+			},
+			Deduplication: config.Deduplication{
+				TTL: config.Duration{Duration: 10 * time.Minute},
+			},
+		},
 		RedirectURI: "http://localhost:3000/callback",
 		AllowOrigin: "http://localhost:3000",
 		Authentication: config.Authentication{
@@ -43,8 +76,11 @@ func TestReadConfiguration_DefaultsFromEmbeddedFile(t *testing.T) {
 					"https://www.googleapis.com/auth/userinfo.email",
 					"https://www.googleapis.com/auth/userinfo.profile",
 				},
-				Endpoint: google.Endpoint,
 			},
+			Providers: []config.IdentityProvider{},
+		},
+		Authorization: config.Authorization{
+			Admins: []string{},
 		},
 	}
 
@@ -64,8 +100,18 @@ func TestReadConfiguration_WithEnvVars(t *testing.T) {
 	os.Setenv("AUTHENTICATION_OAUTH2_CLIENTSECRET", "test-client-secret")
 	os.Setenv("TWEETSSTORAGE_CONNECTIONSTRING", "test-tweets-connection")
 	os.Setenv("FEEDSSTORAGE_CONNECTIONSTRING", "test-feeds-connection")
+	os.Setenv("EMBEDDEDSTORAGE_PATH", "/data/test.db")
+	os.Setenv("TWEETSSTORAGE_DRIVER", "postgres")
+	os.Setenv("TWEETSSTORAGE_AUTOMIGRATE", "false")
+	os.Setenv("FEEDSSTORAGE_DRIVER", "postgres")
 	os.Setenv("APISERVER_APPLICATIONURL", "http://localhost:8080")
 	os.Setenv("NATS_URL", "nats://localhost:4222")
+	os.Setenv("MESSAGING_JETSTREAM_ACKWAIT", "1m")
+	os.Setenv("MESSAGING_JETSTREAM_MAXDELIVER", "10")
+	os.Setenv("MESSAGING_KAFKA_BROKERS", "kafka-1:9092,kafka-2:9092")
+	os.Setenv("MESSAGING_RETRY_MAXRETRIES", "0")
+	os.Setenv("MESSAGING_RETRY_INITIALINTERVAL", "100ms")
+	os.Setenv("AUTHORIZATION_ADMINS", "admin-id,admin@gmail.com")
 
 	defer func() {
 		// Clean up environment variables after the test
@@ -105,6 +151,18 @@ func TestReadConfiguration_WithEnvVars(t *testing.T) {
 		t.Errorf("Expected FeedsStorage.ConnectionString to be 'test-feeds-connection', got %v", configuration.FeedsStorage.ConnectionString)
 	}
 
+	if configuration.TweetsStorage.Driver != "postgres" || configuration.FeedsStorage.Driver != "postgres" {
+		t.Errorf("Expected storage drivers to be 'postgres', got %v and %v", configuration.TweetsStorage.Driver, configuration.FeedsStorage.Driver)
+	}
+
+	if configuration.TweetsStorage.AutoMigrate {
+		t.Errorf("Expected TweetsStorage.AutoMigrate to be false, got true")
+	}
+
+	if configuration.EmbeddedStorage.Path != "/data/test.db" {
+		t.Errorf("Expected EmbeddedStorage.Path to be '/data/test.db', got %v", configuration.EmbeddedStorage.Path)
+	}
+
 	if configuration.ApiServer.ApplicationUrl != "http://localhost:8080" {
 		t.Errorf("Expected ApiServer.ApplicationUrl to be 'http://localhost:8080', got %v", configuration.ApiServer.ApplicationUrl)
 	}
@@ -112,6 +170,22 @@ func TestReadConfiguration_WithEnvVars(t *testing.T) {
 	if configuration.NATSUrl != "nats://localhost:4222" {
 		t.Errorf("Expected NATSUrl to be 'nats://localhost:4222', got %v", configuration.NATSUrl)
 	}
+
+	if configuration.Messaging.JetStream.AckWait.Duration != time.Minute || configuration.Messaging.JetStream.MaxDeliver != 10 {
+		t.Errorf("Expected JetStream AckWait 1m and MaxDeliver 10, got %v and %v", configuration.Messaging.JetStream.AckWait, configuration.Messaging.JetStream.MaxDeliver)
+	}
+
+	if !reflect.DeepEqual(configuration.Messaging.Kafka.Brokers, []string{"kafka-1:9092", "kafka-2:9092"}) {
+		t.Errorf("Expected Kafka brokers to be [kafka-1:9092 kafka-2:9092], got %v", configuration.Messaging.Kafka.Brokers)
+	}
+
+	if configuration.Messaging.Retry.MaxRetries != 0 || configuration.Messaging.Retry.InitialInterval.Duration != 100*time.Millisecond {
+		t.Errorf("Expected Retry MaxRetries 0 and InitialInterval 100ms, got %v and %v", configuration.Messaging.Retry.MaxRetries, configuration.Messaging.Retry.InitialInterval)
+	}
+
+	if !reflect.DeepEqual(configuration.Authorization.Admins, []string{"admin-id", "admin@gmail.com"}) {
+		t.Errorf("Expected Authorization.Admins to be [admin-id admin@gmail.com], got %v", configuration.Authorization.Admins)
+	}
 }
 
 func TestReadConfiguration_EnvironmentVariableOverrides(t *testing.T) {
@@ -126,3 +200,58 @@ func TestReadConfiguration_EnvironmentVariableOverrides(t *testing.T) {
 		t.Errorf("Expected Authentication.Enable to be %v, got %v", expected, config.Authentication.Enable)
 	}
 }
+
+func TestApplyModePreset(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    config.Configuration
+		expected [3]string // Tweets storage, feeds storage and messaging drivers
+	}{
+		{
+			name:     "in-memory preset",
+			input:    config.Configuration{Mode: config.InMemory},
+			expected: [3]string{config.MemoryDriver, config.MemoryDriver, config.MemoryDriver},
+		},
+		{
+			name:     "persistent preset",
+			input:    config.Configuration{Mode: config.Persistent},
+			expected: [3]string{config.MySQLDriver, config.MongoDriver, config.JetStreamDriver},
+		},
+		{
+			name:     "cloud preset",
+			input:    config.Configuration{Mode: config.Cloud},
+			expected: [3]string{config.FirestoreDriver, config.FirestoreDriver, config.PubSubDriver},
+		},
+		{
+			name:     "embedded preset",
+			input:    config.Configuration{Mode: config.Embedded},
+			expected: [3]string{config.BoltDriver, config.BoltDriver, config.MemoryDriver},
+		},
+		{
+			name: "explicit drivers win over the preset",
+			input: config.Configuration{
+				Mode:         config.Persistent,
+				FeedsStorage: config.FeedsStorage{Driver: config.PostgresDriver},
+				Messaging:    config.Messaging{Driver: config.MemoryDriver},
+			},
+			expected: [3]string{config.MySQLDriver, config.PostgresDriver, config.MemoryDriver},
+		},
+		{
+			name:     "no mode keeps the drivers unset",
+			input:    config.Configuration{},
+			expected: [3]string{"", "", ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			configuration := testCase.input
+			configuration.ApplyModePreset()
+
+			actual := [3]string{configuration.TweetsStorage.Driver, configuration.FeedsStorage.Driver, configuration.Messaging.Driver}
+			if actual != testCase.expected {
+				t.Errorf("Expected drivers %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
@@ -1,3 +1,5 @@
 package config
 
-const GOOGLE_TOKENINFO_URL = "https://www.googleapis.com/oauth2/v3/tokeninfo"
+const GOOGLE_CERTS_URL = "https://www.googleapis.com/oauth2/v3/certs"
+
+var GOOGLE_ISSUERS = []string{"accounts.google.com", "https://accounts.google.com"}
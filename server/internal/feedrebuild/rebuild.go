@@ -0,0 +1,177 @@
+// Package feedrebuild recomputes the feeds from the stored tweets
+package feedrebuild
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+	"twitter-clone/internal/hashtags"
+	"twitter-clone/internal/models"
+	feedrepo "twitter-clone/internal/repositories/feed"
+)
+
+// Actions of the changes, in the order they are applied
+const (
+	RemoveTweet = "remove"
+	UpdateTweet = "update"
+	CreateFeed  = "create"
+	AppendTweet = "append"
+)
+
+// TweetSource provides every stored tweet
+type TweetSource interface {
+	GetTweets(ctx context.Context) ([]models.Tweet, error)
+}
+
+// Change is a difference between a feed and the tweets, TweetID is empty when a feed is created
+type Change struct {
+	Action  string `json:"action"`
+	Feed    string `json:"feed"`
+	TweetID string `json:"tweet_id,omitempty"`
+}
+
+type Report struct {
+	DryRun  bool     `json:"dry_run"`
+	Tweets  int      `json:"tweets"`
+	Feeds   int      `json:"feeds"`
+	Changes []Change `json:"changes"`
+}
+
+// Rebuild compares every feed with the tweets tagged with its name and, unless dryRun is set, applies
+// the changes: stray tweets are removed, outdated copies replaced and missing feeds and tweets added.
+// Feeds without tweets are kept, as they are after their last tweet was deleted.
+func Rebuild(ctx context.Context, tweetSource TweetSource, feedRepo feedrepo.FeedRepository, dryRun bool) (*Report, error) {
+	tweets, err := tweetSource.GetTweets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	feeds, err := feedRepo.GetFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tags stored before they were normalized are normalized here, so their tweets move to the feeds
+	// of the normalized names
+	tweets = slices.Clone(tweets)
+	for i := range tweets {
+		tweets[i].Tags = hashtags.Merge(tweets[i].Tags)
+	}
+
+	// Tweets by ID within every feed they should be in
+	expected := map[string]map[string]models.Tweet{}
+	for _, tweet := range tweets {
+		for _, tag := range tweet.Tags {
+			if expected[tag] == nil {
+				expected[tag] = map[string]models.Tweet{}
+			}
+			expected[tag][tweet.ID] = tweet
+		}
+	}
+
+	tweetsByID := map[string]models.Tweet{}
+	for _, tweet := range tweets {
+		tweetsByID[tweet.ID] = tweet
+	}
+
+	var changes []Change
+	existingFeeds := map[string]bool{}
+	for _, feed := range feeds {
+		existingFeeds[feed.Name] = true
+		present := map[string]bool{}
+		for _, feedTweet := range feed.Tweets {
+			present[feedTweet.ID] = true
+			tweet, found := expected[feed.Name][feedTweet.ID]
+			switch {
+			case !found:
+				changes = append(changes, Change{Action: RemoveTweet, Feed: feed.Name, TweetID: feedTweet.ID})
+			case !sameTweet(feedTweet, tweet):
+				changes = append(changes, Change{Action: UpdateTweet, Feed: feed.Name, TweetID: feedTweet.ID})
+			}
+		}
+		for id := range expected[feed.Name] {
+			if !present[id] {
+				changes = append(changes, Change{Action: AppendTweet, Feed: feed.Name, TweetID: id})
+			}
+		}
+	}
+	for name, feedTweets := range expected {
+		if existingFeeds[name] {
+			continue
+		}
+		changes = append(changes, Change{Action: CreateFeed, Feed: name})
+		for id := range feedTweets {
+			changes = append(changes, Change{Action: AppendTweet, Feed: name, TweetID: id})
+		}
+	}
+
+	sortChanges(changes)
+
+	report := &Report{
+		DryRun:  dryRun,
+		Tweets:  len(tweets),
+		Feeds:   len(existingFeeds),
+		Changes: changes,
+	}
+	if report.Changes == nil {
+		report.Changes = []Change{}
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	for _, change := range changes {
+		if err := apply(ctx, feedRepo, change, tweetsByID); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+func apply(ctx context.Context, feedRepo feedrepo.FeedRepository, change Change, tweetsByID map[string]models.Tweet) error {
+	switch change.Action {
+	case RemoveTweet:
+		// Only the copy of this feed is removed, the tweet may still belong to other feeds
+		_, err := feedRepo.DeleteTweet(ctx, models.Tweet{ID: change.TweetID, Tags: []string{change.Feed}})
+		return err
+	case UpdateTweet:
+		if _, err := feedRepo.DeleteTweet(ctx, models.Tweet{ID: change.TweetID, Tags: []string{change.Feed}}); err != nil {
+			return err
+		}
+		_, err := feedRepo.AppendTweet(ctx, tweetsByID[change.TweetID])
+		return err
+	case CreateFeed:
+		return feedRepo.CreateFeed(ctx, change.Feed)
+	case AppendTweet:
+		// Appending is idempotent, the feeds of the other tags which already hold the tweet keep it
+		_, err := feedRepo.AppendTweet(ctx, tweetsByID[change.TweetID])
+		return err
+	}
+	return nil
+}
+
+// sameTweet compares a feed copy with the stored tweet. Creation times are compared in seconds,
+// the precision of the mysql tweets storage, while feeds may keep the time the tweet was created with.
+func sameTweet(feedTweet models.Tweet, tweet models.Tweet) bool {
+	return feedTweet.Title == tweet.Title &&
+		feedTweet.Content == tweet.Content &&
+		slices.Equal(feedTweet.Tags, tweet.Tags) &&
+		feedTweet.User.ID == tweet.User.ID &&
+		feedTweet.User.Email == tweet.User.Email &&
+		feedTweet.CreatedAt.Truncate(time.Second).Equal(tweet.CreatedAt.Truncate(time.Second))
+}
+
+// sortChanges orders the changes by action, feed and tweet, so reports are stable and
+// feeds are created before tweets are appended to them
+func sortChanges(changes []Change) {
+	order := map[string]int{RemoveTweet: 0, UpdateTweet: 1, CreateFeed: 2, AppendTweet: 3}
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Or(
+			cmp.Compare(order[a.Action], order[b.Action]),
+			cmp.Compare(a.Feed, b.Feed),
+			cmp.Compare(a.TweetID, b.TweetID),
+		)
+	})
+}
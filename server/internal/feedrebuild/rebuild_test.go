@@ -0,0 +1,107 @@
+package feedrebuild_test
+
+import (
+	"context"
+	"testing"
+	"twitter-clone/internal/feedrebuild"
+	"twitter-clone/internal/models"
+	feedrepo "twitter-clone/internal/repositories/feed"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebuild(t *testing.T) {
+	ctx := context.Background()
+	tweetRepo := &tweetrepo.InMemoryTweetRepository{}
+	feedRepo := &feedrepo.InMemoryFeedRepository{}
+
+	golang, err := tweetRepo.CreateTweet(ctx, models.CreateTweetRequest{Content: "go", Tags: []string{"golang"}}, tweetrepo.TestUser)
+	require.NoError(t, err)
+	both, err := tweetRepo.CreateTweet(ctx, models.CreateTweetRequest{Content: "both", Tags: []string{"golang", "news"}}, tweetrepo.TestUser)
+	require.NoError(t, err)
+
+	// The golang feed lost a tweet, holds an outdated copy and a deleted tweet, the news feed is missing
+	require.NoError(t, feedRepo.CreateFeed(ctx, "golang"))
+	require.NoError(t, feedRepo.CreateFeed(ctx, "empty"))
+	outdated := both.Clone()
+	outdated.Content = "outdated"
+	_, err = feedRepo.AppendTweet(ctx, outdated)
+	require.NoError(t, err)
+	_, err = feedRepo.AppendTweet(ctx, models.Tweet{ID: "deleted", Tags: []string{"golang"}})
+	require.NoError(t, err)
+
+	expectedChanges := []feedrebuild.Change{
+		{Action: feedrebuild.RemoveTweet, Feed: "golang", TweetID: "deleted"},
+		{Action: feedrebuild.UpdateTweet, Feed: "golang", TweetID: both.ID},
+		{Action: feedrebuild.CreateFeed, Feed: "news"},
+		{Action: feedrebuild.AppendTweet, Feed: "golang", TweetID: golang.ID},
+		{Action: feedrebuild.AppendTweet, Feed: "news", TweetID: both.ID},
+	}
+
+	// A dry run only reports the changes
+	report, err := feedrebuild.Rebuild(ctx, tweetRepo, feedRepo, true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 2, report.Tweets)
+	assert.Equal(t, 2, report.Feeds)
+	assert.Equal(t, expectedChanges, report.Changes)
+	_, err = feedRepo.GetFeedByName(ctx, "news")
+	assert.Error(t, err, "A dry run should not create feeds")
+
+	report, err = feedrebuild.Rebuild(ctx, tweetRepo, feedRepo, false)
+	require.NoError(t, err)
+	assert.Equal(t, expectedChanges, report.Changes)
+
+	feed, err := feedRepo.GetFeedByName(ctx, "golang")
+	require.NoError(t, err)
+	require.Len(t, feed.Tweets, 2)
+	assert.ElementsMatch(t, []string{golang.ID, both.ID}, []string{feed.Tweets[0].ID, feed.Tweets[1].ID})
+	for _, tweet := range feed.Tweets {
+		assert.NotEqual(t, "outdated", tweet.Content)
+	}
+
+	feed, err = feedRepo.GetFeedByName(ctx, "news")
+	require.NoError(t, err)
+	require.Len(t, feed.Tweets, 1)
+	assert.Equal(t, both.ID, feed.Tweets[0].ID)
+
+	_, err = feedRepo.GetFeedByName(ctx, "empty")
+	assert.NoError(t, err, "Feeds without tweets should be kept")
+
+	// Rebuilt feeds match the tweets
+	report, err = feedrebuild.Rebuild(ctx, tweetRepo, feedRepo, true)
+	require.NoError(t, err)
+	assert.Empty(t, report.Changes)
+}
+
+// tweetSource provides tweets as they were stored, such as before their tags were normalized
+type tweetSource []models.Tweet
+
+func (source tweetSource) GetTweets(ctx context.Context) ([]models.Tweet, error) {
+	return source, nil
+}
+
+func TestRebuild_NormalizesStoredTags(t *testing.T) {
+	ctx := context.Background()
+	feedRepo := &feedrepo.InMemoryFeedRepository{}
+
+	legacy := models.Tweet{ID: "legacy", Content: "legacy", Tags: []string{"Go", " #go"}}
+	require.NoError(t, feedRepo.CreateFeed(ctx, "Go"))
+	_, err := feedRepo.AppendTweet(ctx, legacy)
+	require.NoError(t, err)
+
+	report, err := feedrebuild.Rebuild(ctx, tweetSource{legacy}, feedRepo, false)
+	require.NoError(t, err)
+	assert.Equal(t, []feedrebuild.Change{
+		{Action: feedrebuild.RemoveTweet, Feed: "Go", TweetID: "legacy"},
+		{Action: feedrebuild.CreateFeed, Feed: "go"},
+		{Action: feedrebuild.AppendTweet, Feed: "go", TweetID: "legacy"},
+	}, report.Changes)
+
+	feed, err := feedRepo.GetFeedByName(ctx, "go")
+	require.NoError(t, err)
+	require.Len(t, feed.Tweets, 1)
+	assert.Equal(t, "legacy", feed.Tweets[0].ID)
+}
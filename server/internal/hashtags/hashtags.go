@@ -0,0 +1,99 @@
+// Package hashtags extracts the hashtags of tweets and normalizes tags, so feeds are named consistently
+package hashtags
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxTagLength is the number of characters tags are truncated to
+const MaxTagLength = 50
+
+var (
+	// Text in which # does not start a hashtag, replaced before scanning
+	codeBlockPattern = regexp.MustCompile("```[\\s\\S]*?```")
+	codeSpanPattern  = regexp.MustCompile("`[^`\n]*`")
+	urlPattern       = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)\S+`)
+)
+
+// Merge returns the explicit tags followed by the hashtags of the texts, normalized and without
+// duplicates, in order of appearance
+func Merge(explicitTags []string, texts ...string) []string {
+	candidates := slices.Clone(explicitTags)
+	for _, text := range texts {
+		candidates = append(candidates, Extract(text)...)
+	}
+
+	var tags []string
+	for _, candidate := range candidates {
+		tag := Normalize(candidate)
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Extract returns the hashtags of text without their #, in order of appearance. A hashtag is made
+// of letters, marks, digits and underscores and has at least one character which is not a digit.
+// # only starts a hashtag at the beginning of a word, never inside URLs or markdown code.
+func Extract(text string) []string {
+	text = codeBlockPattern.ReplaceAllString(text, " ")
+	text = codeSpanPattern.ReplaceAllString(text, " ")
+	text = urlPattern.ReplaceAllString(text, " ")
+
+	var hashtags []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if !isHashSign(runes[i]) {
+			continue
+		}
+		// Within a word or an HTML entity such as &#39;
+		if i > 0 && (isTagRune(runes[i-1]) || runes[i-1] == '&') {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isTagRune(runes[end]) {
+			end++
+		}
+
+		hashtag := runes[i+1 : end]
+		if slices.ContainsFunc(hashtag, func(r rune) bool { return !unicode.IsDigit(r) }) {
+			hashtags = append(hashtags, string(hashtag))
+		}
+		i = end - 1
+	}
+	return hashtags
+}
+
+// Normalize trims the tag and a leading #, folds its case, composes its characters and truncates it
+// to MaxTagLength characters. An empty result is no tag.
+func Normalize(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if r, size := utf8.DecodeRuneInString(tag); isHashSign(r) {
+		tag = strings.TrimSpace(tag[size:])
+	}
+
+	tag = norm.NFC.String(cases.Fold().String(tag))
+
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		tag = strings.TrimSpace(string([]rune(tag)[:MaxTagLength]))
+	}
+	return tag
+}
+
+// isHashSign also accepts the fullwidth number sign of CJK input methods
+func isHashSign(r rune) bool {
+	return r == '#' || r == '＃'
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || r == '_'
+}
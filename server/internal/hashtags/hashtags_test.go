@@ -0,0 +1,65 @@
+package hashtags_test
+
+import (
+	"strings"
+	"testing"
+	"twitter-clone/internal/hashtags"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"words", "Learning #golang and #Go_1_23 today", []string{"golang", "Go_1_23"}},
+		{"punctuation", "(#news), #golang! #go.", []string{"news", "golang", "go"}},
+		{"unicode", "#café #日本語 #Ελληνικά ＃全角", []string{"café", "日本語", "Ελληνικά", "全角"}},
+		{"combining marks", "#cafe\u0301", []string{"cafe\u0301"}},
+		{"digits only", "#123 issue #2024goals", []string{"2024goals"}},
+		{"inside words", "C# and a#b are not hashtags, nor &#39;", nil},
+		{"urls", "see https://example.com/page#section and www.example.com/#/feeds?tag=#go #real", []string{"real"}},
+		{"code spans", "use `#define` or\n```\n#include <stdio.h>\n#pragma once\n```\nthen #c", []string{"c"}},
+		{"lone signs", "# ## #", nil},
+		{"adjacent", "#go#lang", []string{"go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, hashtags.Extract(tt.text))
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{"Go", "go"},
+		{"  GoLang ", "golang"},
+		{"#News", "news"},
+		{"# news", "news"},
+		{"Straße", "strasse"},
+		{"cafe\u0301", "caf\u00e9"},
+		{"go,lang", "go,lang"},
+		{"   ", ""},
+		{"#", ""},
+		{strings.Repeat("é", hashtags.MaxTagLength+10), strings.Repeat("é", hashtags.MaxTagLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			assert.Equal(t, tt.expected, hashtags.Normalize(tt.tag))
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tags := hashtags.Merge([]string{"News", " golang", "", "news"}, "Hello #Go", "More #GOLANG and #Go and #café")
+	assert.Equal(t, []string{"news", "golang", "go", "café"}, tags, "Explicit tags should come first, without duplicates")
+
+	assert.Nil(t, hashtags.Merge(nil, "no hashtags"))
+}
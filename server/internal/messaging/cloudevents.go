@@ -0,0 +1,86 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CloudEvents 1.0 attributes of the published events
+const (
+	CloudEventsSpecVersion = "1.0"
+	EventSource            = "/twitter-clone"
+	// EventSchemaVersion is the version of the event data schemas, raised on incompatible changes.
+	// Version 1 renamed the deleted tweet of TweetDeleted from original_tweet to deleted_tweet.
+	EventSchemaVersion = 1
+)
+
+// eventTypes are the CloudEvents types of the events published to each topic
+var eventTypes = map[string]string{
+	TweetCreatedTopic: "twitter-clone.tweet.created",
+	TweetUpdatedTopic: "twitter-clone.tweet.updated",
+	TweetDeletedTopic: "twitter-clone.tweet.deleted",
+	FeedUpdatedTopic:  "twitter-clone.feed.updated",
+}
+
+var (
+	ErrUnknownEventTopic      = errors.New("no event type for topic")
+	ErrUnexpectedEventType    = errors.New("unexpected event type")
+	ErrUnsupportedEventSchema = errors.New("unsupported event schema")
+)
+
+// CloudEvent is the CloudEvents 1.0 envelope of the published events, in structured JSON mode
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	ID              string          `json:"id"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// EventType returns the CloudEvents type of the events published to topic
+func EventType(topic string) (string, error) {
+	eventType, found := eventTypes[topic]
+	if !found {
+		return "", fmt.Errorf("%w %q", ErrUnknownEventTopic, topic)
+	}
+	return eventType, nil
+}
+
+// EventDataSchema returns the URI of the current data schema of eventType, its last segment is the version
+func EventDataSchema(eventType string) string {
+	return fmt.Sprintf("urn:twitter-clone:schema:%s:v%d", eventType, EventSchemaVersion)
+}
+
+// checkEnvelope rejects envelopes of another type than the events of topic or of a newer schema version
+func checkEnvelope(topic string, eventType string, dataSchema string) error {
+	expectedType, err := EventType(topic)
+	if err != nil {
+		return err
+	}
+	if eventType != expectedType {
+		return fmt.Errorf("%w %q on topic %q", ErrUnexpectedEventType, eventType, topic)
+	}
+
+	version, err := schemaVersion(dataSchema)
+	if err != nil || version > EventSchemaVersion {
+		return fmt.Errorf("%w %q", ErrUnsupportedEventSchema, dataSchema)
+	}
+
+	return nil
+}
+
+// schemaVersion parses the version from the last segment of a data schema URI
+func schemaVersion(dataSchema string) (int, error) {
+	segment := dataSchema[strings.LastIndexAny(dataSchema, ":/")+1:]
+	if !strings.HasPrefix(segment, "v") {
+		return 0, fmt.Errorf("no version in %q", dataSchema)
+	}
+	return strconv.Atoi(segment[1:])
+}
@@ -0,0 +1,74 @@
+package messaging_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONCodec(t *testing.T) {
+	occurredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tweet := models.Tweet{ID: "tweet-1", Tags: []string{"golang"}}
+
+	payload, err := messaging.JSONCodec{}.Encode(messaging.TweetDeletedTopic, "event-1", occurredAt, messaging.TweetDeleted{DeletedTweet: tweet, OccurredAt: occurredAt})
+	require.NoError(t, err)
+
+	var envelope map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &envelope))
+	assert.Equal(t, "1.0", envelope["specversion"])
+	assert.Equal(t, "twitter-clone.tweet.deleted", envelope["type"])
+	assert.Equal(t, "/twitter-clone", envelope["source"])
+	assert.Equal(t, "event-1", envelope["id"])
+	assert.Equal(t, "2024-05-01T12:00:00Z", envelope["time"])
+	assert.Equal(t, "application/json", envelope["datacontenttype"])
+	assert.Equal(t, "urn:twitter-clone:schema:twitter-clone.tweet.deleted:v1", envelope["dataschema"])
+	assert.Contains(t, envelope["data"], "deleted_tweet")
+
+	var event messaging.TweetDeleted
+	require.NoError(t, messaging.JSONCodec{}.Decode(payload, messaging.TweetDeletedTopic, &event))
+	assert.Equal(t, tweet.ID, event.DeletedTweet.ID)
+	assert.True(t, occurredAt.Equal(event.OccurredAt))
+
+	_, err = messaging.JSONCodec{}.Encode("unknown-topic", "event-2", occurredAt, event)
+	assert.ErrorIs(t, err, messaging.ErrUnknownEventTopic)
+}
+
+func TestJSONCodec_Legacy(t *testing.T) {
+	// Events published before the envelope, the deleted tweet was named original_tweet
+	var deleted messaging.TweetDeleted
+	require.NoError(t, messaging.JSONCodec{}.Decode([]byte(`{"original_tweet":{"id":"tweet-1"},"occurred_at":"2024-05-01T12:00:00Z"}`), messaging.TweetDeletedTopic, &deleted))
+	assert.Equal(t, "tweet-1", deleted.DeletedTweet.ID)
+
+	var created messaging.TweetCreated
+	require.NoError(t, messaging.JSONCodec{}.Decode([]byte(`{"tweet":{"id":"tweet-2","tags":["golang"]}}`), messaging.TweetCreatedTopic, &created))
+	assert.Equal(t, "tweet-2", created.Tweet.ID)
+	assert.Equal(t, []string{"golang"}, created.Tweet.Tags)
+}
+
+func TestJSONCodec_Rejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected error
+	}{
+		{"other type", `{"specversion":"1.0","type":"twitter-clone.tweet.created","dataschema":"urn:twitter-clone:schema:twitter-clone.tweet.created:v1","data":{}}`, messaging.ErrUnexpectedEventType},
+		{"newer schema", `{"specversion":"1.0","type":"twitter-clone.tweet.deleted","dataschema":"urn:twitter-clone:schema:twitter-clone.tweet.deleted:v2","data":{}}`, messaging.ErrUnsupportedEventSchema},
+		{"no schema version", `{"specversion":"1.0","type":"twitter-clone.tweet.deleted","data":{}}`, messaging.ErrUnsupportedEventSchema},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event messaging.TweetDeleted
+			err := messaging.JSONCodec{}.Decode([]byte(tt.payload), messaging.TweetDeletedTopic, &event)
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+
+	var event messaging.TweetDeleted
+	assert.Error(t, messaging.JSONCodec{}.Decode([]byte("{"), messaging.TweetDeletedTopic, &event))
+}
@@ -0,0 +1,171 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"twitter-clone/internal/config"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// ContentTypeKey is the metadata holding the format of the message payload
+const ContentTypeKey = "content-type"
+
+// Content types of the CloudEvents formats
+const (
+	JSONContentType     = "application/cloudevents+json"
+	ProtobufContentType = "application/cloudevents+protobuf"
+)
+
+var ErrUnknownContentType = errors.New("unknown content type")
+
+// EventCodec encodes the published events in a CloudEvents format and decodes them
+type EventCodec interface {
+	ContentType() string
+	// Encode wraps the event published to topic in an envelope, id should be the message id
+	Encode(topic string, id string, occurredAt time.Time, event interface{}) ([]byte, error)
+	// Decode reads the event published to topic, rejecting envelopes of another type or of a newer schema version
+	Decode(payload []byte, topic string, event interface{}) error
+}
+
+// NewEventCodec returns the codec of the configured encoding, JSON when it is not set
+func NewEventCodec(encoding string) (EventCodec, error) {
+	switch encoding {
+	case "", config.JSONEncoding:
+		return JSONCodec{}, nil
+	case config.ProtobufEncoding:
+		return ProtobufCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown messaging encoding %q", encoding)
+	}
+}
+
+// NewEventMessage encodes the event published to topic in a new message carrying its content type
+func NewEventMessage(codec EventCodec, topic string, occurredAt time.Time, event interface{}) (*message.Message, error) {
+	id := watermill.NewUUID()
+	payload, err := codec.Encode(topic, id, occurredAt, event)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := message.NewMessage(id, payload)
+	msg.Metadata.Set(ContentTypeKey, codec.ContentType())
+	return msg, nil
+}
+
+// StoredEventMessage makes the message of an event from the outbox or the event store. Events are
+// stored as JSON and encoded again for other codecs, keeping their id.
+func StoredEventMessage(codec EventCodec, id string, topic string, payload []byte) (*message.Message, error) {
+	if codec.ContentType() != JSONContentType {
+		event, err := newEvent(topic)
+		if err != nil {
+			return nil, err
+		}
+		if err := (JSONCodec{}).Decode(payload, topic, event); err != nil {
+			return nil, err
+		}
+		if payload, err = codec.Encode(topic, id, occurredAt(event), event); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := message.NewMessage(id, payload)
+	msg.Metadata.Set(ContentTypeKey, codec.ContentType())
+	return msg, nil
+}
+
+// DecodeMessage reads the event of a message published to topic with the codec of its content type.
+// Messages without a content type were published before it was set, they are JSON.
+func DecodeMessage(msg *message.Message, topic string, event interface{}) error {
+	switch contentType := msg.Metadata.Get(ContentTypeKey); contentType {
+	case "", JSONContentType:
+		return JSONCodec{}.Decode(msg.Payload, topic, event)
+	case ProtobufContentType:
+		return ProtobufCodec{}.Decode(msg.Payload, topic, event)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownContentType, contentType)
+	}
+}
+
+// newEvent returns a pointer to an empty event of topic
+func newEvent(topic string) (interface{}, error) {
+	switch topic {
+	case TweetCreatedTopic:
+		return &TweetCreated{}, nil
+	case TweetUpdatedTopic:
+		return &TweetUpdated{}, nil
+	case TweetDeletedTopic:
+		return &TweetDeleted{}, nil
+	case FeedUpdatedTopic:
+		return &FeedUpdated{}, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownEventTopic, topic)
+	}
+}
+
+// occurredAt returns when the event happened
+func occurredAt(event interface{}) time.Time {
+	switch event := event.(type) {
+	case *TweetCreated:
+		return event.OccurredAt
+	case *TweetUpdated:
+		return event.OccurredAt
+	case *TweetDeleted:
+		return event.OccurredAt
+	case *FeedUpdated:
+		return event.OccurredAt
+	default:
+		return time.Time{}
+	}
+}
+
+// JSONCodec encodes the events in the CloudEvents JSON format, in which the event is the data
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return JSONContentType
+}
+
+func (JSONCodec) Encode(topic string, id string, occurredAt time.Time, event interface{}) ([]byte, error) {
+	eventType, err := EventType(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		Type:            eventType,
+		Source:          EventSource,
+		ID:              id,
+		Time:            occurredAt.UTC(),
+		DataContentType: "application/json",
+		DataSchema:      EventDataSchema(eventType),
+		Data:            data,
+	})
+}
+
+// Decode also accepts the bare events published before the envelope, recognized by their missing specversion
+func (JSONCodec) Decode(payload []byte, topic string, event interface{}) error {
+	var envelope CloudEvent
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return err
+	}
+
+	if envelope.SpecVersion == "" {
+		return json.Unmarshal(payload, event)
+	}
+
+	if err := checkEnvelope(topic, envelope.Type, envelope.DataSchema); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(envelope.Data, event)
+}
@@ -0,0 +1,97 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"time"
+	"twitter-clone/internal/models"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+)
+
+// CollectDeadLetters is the handler storing the messages of the poison topic
+const CollectDeadLetters = "collect-dead-letters"
+
+// DeadLetterStore keeps the dead letters in the tweets storage, so every replica sees the same
+// letters and they survive restarts. Adding a letter whose ID is stored replaces it, a missing
+// letter is reported as repoerrors.ErrNotFound.
+type DeadLetterStore interface {
+	AddDeadLetter(ctx context.Context, letter models.DeadLetter) error
+	// GetDeadLetters returns all stored letters, oldest first
+	GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id string) error
+}
+
+// NewDeadLetter reads a message of the poison topic
+func NewDeadLetter(msg *message.Message) models.DeadLetter {
+	metadata := message.Metadata{}
+	for key, value := range msg.Metadata {
+		metadata.Set(key, value)
+	}
+
+	return models.DeadLetter{
+		ID:       msg.UUID,
+		Topic:    metadata.Get(middleware.PoisonedTopicKey),
+		Handler:  metadata.Get(middleware.PoisonedHandlerKey),
+		Reason:   metadata.Get(middleware.ReasonForPoisonedKey),
+		Payload:  string(msg.Payload),
+		Metadata: metadata,
+		FailedAt: time.Now().UTC(),
+	}
+}
+
+// DeadLetterHandler stores the messages of the poison topic. A message which cannot be stored
+// is not acknowledged, so the broker delivers it again instead of losing it.
+func DeadLetterHandler(msg *message.Message, store DeadLetterStore, logger watermill.LoggerAdapter) error {
+	letter := NewDeadLetter(msg)
+	if err := store.AddDeadLetter(msg.Context(), letter); err != nil {
+		return err
+	}
+
+	logger.Error("Feed update moved to the poison topic", errors.New(letter.Reason), watermill.LogFields{"id": letter.ID, "topic": letter.Topic})
+	return nil
+}
+
+// DeadLetterQueue lists the stored dead letters and publishes them to their original topic again
+type DeadLetterQueue struct {
+	store     DeadLetterStore
+	publisher message.Publisher
+}
+
+// NewDeadLetterQueue creates a queue replaying the dead letters of the store with the publisher
+func NewDeadLetterQueue(store DeadLetterStore, publisher message.Publisher) *DeadLetterQueue {
+	return &DeadLetterQueue{store: store, publisher: publisher}
+}
+
+// List returns the dead letters, oldest first
+func (q *DeadLetterQueue) List(ctx context.Context) ([]models.DeadLetter, error) {
+	return q.store.GetDeadLetters(ctx)
+}
+
+// Replay publishes the dead letter to its original topic again and deletes it from the store.
+// The message gets a new id, brokers deduplicating by id would drop it otherwise.
+// A letter which could not be deleted is published again by the next replay.
+func (q *DeadLetterQueue) Replay(ctx context.Context, id string) error {
+	letter, err := q.store.GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), []byte(letter.Payload))
+	for key, value := range letter.Metadata {
+		switch key {
+		case middleware.ReasonForPoisonedKey, middleware.PoisonedTopicKey, middleware.PoisonedHandlerKey, middleware.PoisonedSubscriberKey:
+		default:
+			msg.Metadata.Set(key, value)
+		}
+	}
+
+	if err := q.publisher.Publish(letter.Topic, msg); err != nil {
+		return err
+	}
+
+	return q.store.DeleteDeadLetter(ctx, id)
+}
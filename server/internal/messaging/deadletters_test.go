@@ -0,0 +1,127 @@
+package messaging_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+	repositories "twitter-clone/internal/repositories/feed"
+	"twitter-clone/internal/repositories/repoerrors"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingFeedRepository fails appending tweets while failing is set
+type failingFeedRepository struct {
+	repositories.InMemoryFeedRepository
+	failing  atomic.Bool
+	attempts atomic.Int32
+}
+
+func (repo *failingFeedRepository) AppendTweet(ctx context.Context, tweet models.Tweet) (bool, error) {
+	repo.attempts.Add(1)
+	if repo.failing.Load() {
+		return false, errors.New("feed storage is down")
+	}
+	return repo.InMemoryFeedRepository.AppendTweet(ctx, tweet)
+}
+
+func TestDeadLetterQueue_RetriesPoisonsAndReplays(t *testing.T) {
+	feedRepo := &failingFeedRepository{}
+	feedRepo.failing.Store(true)
+	logger := watermill.NewStdLogger(false, false)
+
+	configuration := config.Configuration{}
+	configuration.Messaging.Retry = config.Retry{
+		MaxRetries:      2,
+		InitialInterval: config.Duration{Duration: time.Millisecond},
+		Multiplier:      2,
+	}
+
+	store := &tweetrepo.InMemoryDeadLetterStore{}
+	handler := messaging.InMemoryMessageHandler{}
+	pub, sub, err := handler.SetupMessageRouter(configuration, feedRepo, store, logger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	deadLetters := messaging.NewDeadLetterQueue(store, pub)
+	list := func() []models.DeadLetter {
+		letters, err := deadLetters.List(ctx)
+		require.NoError(t, err)
+		return letters
+	}
+
+	feedUpdates, err := sub.Subscribe(ctx, messaging.FeedUpdatedTopic)
+	require.NoError(t, err)
+
+	tweet := models.Tweet{ID: "tweet1", Tags: []string{"golang"}}
+	publishEvent(t, pub, messaging.TweetCreatedTopic, messaging.TweetCreated{Tweet: tweet, OccurredAt: time.Now().UTC()})
+
+	require.Eventually(t, func() bool {
+		return len(list()) == 1
+	}, 5*time.Second, 10*time.Millisecond, "Expected the failing update to be dead-lettered")
+	assert.EqualValues(t, 3, feedRepo.attempts.Load(), "Expected the first attempt and 2 retries")
+
+	deadLetter := list()[0]
+	assert.Equal(t, messaging.TweetCreatedTopic, deadLetter.Topic)
+	assert.Equal(t, messaging.UpdateFeedsOnNewTweetCreated, deadLetter.Handler)
+	assert.Contains(t, deadLetter.Reason, "feed storage is down")
+
+	// Replaying after the storage recovered updates the feed
+	feedRepo.failing.Store(false)
+	require.NoError(t, deadLetters.Replay(ctx, deadLetter.ID))
+	assert.Empty(t, list(), "Replayed letter should be deleted from the store")
+
+	feedUpdated := receiveFeedUpdated(ctx, t, feedUpdates)
+	assert.Equal(t, "golang", feedUpdated.Name)
+
+	feed, err := feedRepo.GetFeedByName(context.Background(), "golang")
+	require.NoError(t, err)
+	assert.Len(t, feed.Tweets, 1)
+
+	assert.ErrorIs(t, deadLetters.Replay(ctx, deadLetter.ID), repoerrors.ErrNotFound)
+}
+
+// unavailableDeadLetterStore fails to add letters while failing is set
+type unavailableDeadLetterStore struct {
+	tweetrepo.InMemoryDeadLetterStore
+	failing atomic.Bool
+}
+
+func (store *unavailableDeadLetterStore) AddDeadLetter(ctx context.Context, letter models.DeadLetter) error {
+	if store.failing.Load() {
+		return repoerrors.Unavailable(errors.New("tweets storage is down"))
+	}
+	return store.InMemoryDeadLetterStore.AddDeadLetter(ctx, letter)
+}
+
+func TestDeadLetterHandler_KeepsLettersWhichCannotBeStored(t *testing.T) {
+	feedRepo := &failingFeedRepository{}
+	feedRepo.failing.Store(true)
+	store := &unavailableDeadLetterStore{}
+	store.failing.Store(true)
+
+	handler := messaging.InMemoryMessageHandler{}
+	pub, _, err := handler.SetupMessageRouter(config.Configuration{}, feedRepo, store, watermill.NopLogger{})
+	require.NoError(t, err)
+
+	publishEvent(t, pub, messaging.TweetCreatedTopic, messaging.TweetCreated{Tweet: models.Tweet{ID: "tweet1", Tags: []string{"golang"}}})
+
+	// The letter is redelivered until the store recovers
+	time.Sleep(50 * time.Millisecond)
+	store.failing.Store(false)
+
+	require.Eventually(t, func() bool {
+		letters, err := store.GetDeadLetters(context.Background())
+		return err == nil && len(letters) == 1
+	}, 5*time.Second, 10*time.Millisecond, "Expected the letter to be stored once the store recovered")
+}
@@ -0,0 +1,71 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultDeduplicationTTL is used when no TTL is configured
+const defaultDeduplicationTTL = 10 * time.Minute
+
+// DeduplicationStore remembers the handled events, so their redeliveries can be skipped
+type DeduplicationStore interface {
+	Seen(ctx context.Context, key string) (bool, error)
+	Remember(ctx context.Context, key string) error
+}
+
+// DeduplicationKey identifies the event of a topic about a tweet. Redeliveries are recognized by the
+// tweet ID rather than the message UUID, which changes when an event is published again.
+func DeduplicationKey(topic string, tweetID string) string {
+	return topic + ":" + tweetID
+}
+
+// InMemoryDeduplicationStore forgets the keys after the TTL. Each replica has its own store,
+// so a redelivery to another replica is not recognized. It finds the feeds unchanged, which
+// keeps the handlers from emitting feed updated events again.
+type InMemoryDeduplicationStore struct {
+	ttl       time.Duration
+	mutex     sync.Mutex
+	expiry    map[string]time.Time
+	nextSweep time.Time
+}
+
+func NewInMemoryDeduplicationStore(ttl time.Duration) *InMemoryDeduplicationStore {
+	if ttl <= 0 {
+		ttl = defaultDeduplicationTTL
+	}
+
+	return &InMemoryDeduplicationStore{
+		ttl:    ttl,
+		expiry: map[string]time.Time{},
+	}
+}
+
+func (store *InMemoryDeduplicationStore) Seen(ctx context.Context, key string) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	expiresAt, found := store.expiry[key]
+	return found && time.Now().Before(expiresAt), nil
+}
+
+func (store *InMemoryDeduplicationStore) Remember(ctx context.Context, key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	store.expiry[key] = now.Add(store.ttl)
+
+	// Expired keys are dropped at most once per TTL to keep remembering cheap
+	if now.After(store.nextSweep) {
+		for existingKey, expiresAt := range store.expiry {
+			if !now.Before(expiresAt) {
+				delete(store.expiry, existingKey)
+			}
+		}
+		store.nextSweep = now.Add(store.ttl)
+	}
+
+	return nil
+}
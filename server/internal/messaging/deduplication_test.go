@@ -0,0 +1,96 @@
+package messaging_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryDeduplicationStore_ForgetsAfterTTL(t *testing.T) {
+	store := messaging.NewInMemoryDeduplicationStore(50 * time.Millisecond)
+	ctx := context.Background()
+
+	seen, err := store.Seen(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, seen, "Unknown keys should not be seen")
+
+	require.NoError(t, store.Remember(ctx, "key"))
+	seen, err = store.Seen(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, seen, "Remembered keys should be seen")
+
+	time.Sleep(100 * time.Millisecond)
+	seen, err = store.Seen(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, seen, "Keys should be forgotten after the TTL")
+}
+
+func TestTweetCreatedHandler_SkipsRedeliveries(t *testing.T) {
+	feedRepo := &failingFeedRepository{}
+	store := messaging.NewInMemoryDeduplicationStore(time.Minute)
+	logger := watermill.NopLogger{}
+
+	payload, err := json.Marshal(messaging.TweetCreated{Tweet: models.Tweet{ID: "tweet-1", Tags: []string{"golang"}}})
+	require.NoError(t, err)
+
+	// A failed delivery is not remembered, so its redelivery is handled
+	feedRepo.failing.Store(true)
+	_, err = messaging.TweetCreatedHandler(message.NewMessage(watermill.NewUUID(), payload), feedRepo, store, messaging.JSONCodec{}, logger)
+	require.Error(t, err)
+
+	feedRepo.failing.Store(false)
+	messages, err := messaging.TweetCreatedHandler(message.NewMessage(watermill.NewUUID(), payload), feedRepo, store, messaging.JSONCodec{}, logger)
+	require.NoError(t, err)
+	assert.Len(t, messages, 1, "Expected a feed updated event")
+
+	// Redeliveries are skipped, even when they were published again under a new UUID
+	messages, err = messaging.TweetCreatedHandler(message.NewMessage(watermill.NewUUID(), payload), feedRepo, store, messaging.JSONCodec{}, logger)
+	require.NoError(t, err)
+	assert.Empty(t, messages, "Expected no feed updated event for a redelivery")
+	assert.Equal(t, int32(2), feedRepo.attempts.Load(), "Redeliveries should not reach the feeds")
+
+	// Another replica does not share the store, the unchanged feeds keep it from announcing them again
+	otherReplicaStore := messaging.NewInMemoryDeduplicationStore(time.Minute)
+	messages, err = messaging.TweetCreatedHandler(message.NewMessage(watermill.NewUUID(), payload), feedRepo, otherReplicaStore, messaging.JSONCodec{}, logger)
+	require.NoError(t, err)
+	assert.Empty(t, messages, "Expected no feed updated event for a redelivery to another replica")
+
+	feed, err := feedRepo.GetFeedByName(context.Background(), "golang")
+	require.NoError(t, err)
+	assert.Len(t, feed.Tweets, 1)
+}
+
+func TestTweetDeletedHandler_SkipsRedeliveries(t *testing.T) {
+	feedRepo := &failingFeedRepository{}
+	store := messaging.NewInMemoryDeduplicationStore(time.Minute)
+	logger := watermill.NopLogger{}
+
+	tweet := models.Tweet{ID: "tweet-1", Tags: []string{"golang"}}
+	require.NoError(t, feedRepo.CreateFeed(context.Background(), "golang"))
+	_, err := feedRepo.AppendTweet(context.Background(), tweet)
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(messaging.TweetDeleted{DeletedTweet: tweet})
+	require.NoError(t, err)
+
+	messages, err := messaging.TweetDeletedHandler(message.NewMessage(watermill.NewUUID(), payload), feedRepo, store, messaging.JSONCodec{}, logger)
+	require.NoError(t, err)
+	assert.Len(t, messages, 1, "Expected a feed updated event")
+
+	messages, err = messaging.TweetDeletedHandler(message.NewMessage(watermill.NewUUID(), payload), feedRepo, store, messaging.JSONCodec{}, logger)
+	require.NoError(t, err)
+	assert.Empty(t, messages, "Expected no feed updated event for a redelivery")
+
+	otherReplicaStore := messaging.NewInMemoryDeduplicationStore(time.Minute)
+	messages, err = messaging.TweetDeletedHandler(message.NewMessage(watermill.NewUUID(), payload), feedRepo, otherReplicaStore, messaging.JSONCodec{}, logger)
+	require.NoError(t, err)
+	assert.Empty(t, messages, "Expected no feed updated event for a redelivery to another replica")
+}
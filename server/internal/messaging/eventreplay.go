@@ -0,0 +1,87 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"twitter-clone/internal/models"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// Metadata of replayed events
+const (
+	EventIDKey       = "event_id"
+	EventSequenceKey = "event_sequence"
+	EventTopicKey    = "event_topic" // Topic the event was published to originally
+)
+
+// replayBatchSize is the number of events read from the store at once
+const replayBatchSize = 100
+
+var ErrInvalidReplayRange = errors.New("replay range must start at sequence 1 or later and not end before it starts")
+
+// EventHistory reads the stored tweet events
+type EventHistory interface {
+	GetEvents(ctx context.Context, since int64, limit int) ([]models.StoredEvent, error)
+}
+
+// EventReplayer publishes stored events again, so new projections can be bootstrapped from the history
+type EventReplayer struct {
+	history   EventHistory
+	publisher message.Publisher
+	codec     EventCodec
+}
+
+func NewEventReplayer(history EventHistory, publisher message.Publisher, codec EventCodec) *EventReplayer {
+	return &EventReplayer{history: history, publisher: publisher, codec: codec}
+}
+
+// Replay publishes the events from sequence from to sequence to, both included, in order, and returns
+// how many were published. A to of 0 replays up to the latest event. The events go to the given topic,
+// or to their original topics when it is empty. Replayed messages get a new id, brokers deduplicating
+// by id would drop them otherwise, the event id is kept in the metadata.
+func (replayer *EventReplayer) Replay(ctx context.Context, from int64, to int64, topic string) (int, error) {
+	if from < 1 || (to != 0 && to < from) {
+		return 0, ErrInvalidReplayRange
+	}
+
+	replayed := 0
+	since := from - 1
+	for {
+		events, err := replayer.history.GetEvents(ctx, since, replayBatchSize)
+		if err != nil {
+			return replayed, err
+		}
+
+		for _, event := range events {
+			if to != 0 && event.Sequence > to {
+				return replayed, nil
+			}
+
+			msg, err := StoredEventMessage(replayer.codec, watermill.NewUUID(), event.Topic, event.Payload)
+			if err != nil {
+				return replayed, err
+			}
+			msg.Metadata.Set(EventIDKey, event.ID)
+			msg.Metadata.Set(EventSequenceKey, strconv.FormatInt(event.Sequence, 10))
+			msg.Metadata.Set(EventTopicKey, event.Topic)
+
+			target := topic
+			if target == "" {
+				target = event.Topic
+			}
+			if err := replayer.publisher.Publish(target, msg); err != nil {
+				return replayed, err
+			}
+
+			replayed++
+			since = event.Sequence
+		}
+
+		if len(events) < replayBatchSize {
+			return replayed, nil
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package messaging_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+	tweetrepo "twitter-clone/internal/repositories/tweet"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedEvents appends count events alternating between the created and deleted topics
+func storedEvents(t *testing.T, count int) *tweetrepo.InMemoryEventStore {
+	store := &tweetrepo.InMemoryEventStore{}
+	events := make([]models.OutboxEvent, count)
+	for i := range events {
+		topic := messaging.TweetCreatedTopic
+		if i%2 == 1 {
+			topic = messaging.TweetDeletedTopic
+		}
+		events[i] = models.OutboxEvent{ID: fmt.Sprintf("event-%d", i+1), Topic: topic, Payload: []byte(fmt.Sprint(i + 1)), CreatedAt: time.Now()}
+	}
+	require.NoError(t, store.AppendEvents(context.Background(), events))
+	return store
+}
+
+// recordingPublisher keeps the published messages in order
+type recordingPublisher struct {
+	topics   []string
+	messages []*message.Message
+}
+
+func (publisher *recordingPublisher) Publish(topic string, messages ...*message.Message) error {
+	for _, msg := range messages {
+		publisher.topics = append(publisher.topics, topic)
+		publisher.messages = append(publisher.messages, msg)
+	}
+	return nil
+}
+
+func (publisher *recordingPublisher) Close() error {
+	return nil
+}
+
+func TestEventReplayer_ReplaysToOriginalTopics(t *testing.T) {
+	publisher := &recordingPublisher{}
+	replayer := messaging.NewEventReplayer(storedEvents(t, 6), publisher, messaging.JSONCodec{})
+
+	replayed, err := replayer.Replay(context.Background(), 2, 5, "")
+	require.NoError(t, err)
+	assert.Equal(t, 4, replayed)
+
+	assert.Equal(t, []string{messaging.TweetDeletedTopic, messaging.TweetCreatedTopic, messaging.TweetDeletedTopic, messaging.TweetCreatedTopic}, publisher.topics)
+	for i, msg := range publisher.messages {
+		assert.Equal(t, fmt.Sprint(i+2), string(msg.Payload), "Events should be replayed in order")
+	}
+
+	msg := publisher.messages[0]
+	assert.Equal(t, "event-2", msg.Metadata.Get(messaging.EventIDKey))
+	assert.Equal(t, "2", msg.Metadata.Get(messaging.EventSequenceKey))
+	assert.Equal(t, messaging.TweetDeletedTopic, msg.Metadata.Get(messaging.EventTopicKey))
+	assert.NotEqual(t, "event-2", msg.UUID, "Replayed messages should get a new id")
+}
+
+func TestEventReplayer_ReplaysEverythingToTopic(t *testing.T) {
+	publisher := &recordingPublisher{}
+
+	// More events than a batch, up to the latest
+	replayer := messaging.NewEventReplayer(storedEvents(t, 250), publisher, messaging.JSONCodec{})
+	replayed, err := replayer.Replay(context.Background(), 1, 0, "projection.bootstrap")
+	require.NoError(t, err)
+	assert.Equal(t, 250, replayed)
+
+	require.Len(t, publisher.messages, 250)
+	for i, msg := range publisher.messages {
+		assert.Equal(t, "projection.bootstrap", publisher.topics[i])
+		assert.Equal(t, fmt.Sprint(i+1), string(msg.Payload), "Events should be replayed in order")
+	}
+
+	// Ranges past the latest event replay nothing
+	replayed, err = replayer.Replay(context.Background(), 300, 0, "projection.bootstrap")
+	require.NoError(t, err)
+	assert.Zero(t, replayed)
+}
+
+func TestEventReplayer_RejectsInvalidRanges(t *testing.T) {
+	replayer := messaging.NewEventReplayer(storedEvents(t, 3), &recordingPublisher{}, messaging.JSONCodec{})
+
+	_, err := replayer.Replay(context.Background(), 0, 2, "")
+	assert.ErrorIs(t, err, messaging.ErrInvalidReplayRange)
+
+	_, err = replayer.Replay(context.Background(), 3, 2, "")
+	assert.ErrorIs(t, err, messaging.ErrInvalidReplayRange)
+}
@@ -1,6 +1,7 @@
 package messaging
 
 import (
+	"encoding/json"
 	"time"
 	"twitter-clone/internal/models"
 )
@@ -8,10 +9,12 @@ import (
 const (
 	UpdateFeedsOnNewTweetCreated = "update-feeds-on-tweet-created"
 	UpdateFeedsOnTweetDeleted    = "update-feeds-on-tweet-deleted"
+	UpdateFeedsOnTweetUpdated    = "update-feeds-on-tweet-updated"
 	TweetCreatedTopic            = "tweet-created"
 	TweetDeletedTopic            = "tweet-deleted"
 	TweetUpdatedTopic            = "tweet-updated"
 	FeedUpdatedTopic             = "feed-updated"
+	PoisonTopic                  = "feed-updates-poison"
 )
 
 type TweetCreated struct {
@@ -21,11 +24,29 @@ type TweetCreated struct {
 }
 
 type TweetDeleted struct {
-	DeletedTweet models.Tweet `json:"original_tweet"`
+	DeletedTweet models.Tweet `json:"deleted_tweet"`
 
 	OccurredAt time.Time `json:"occurred_at"`
 }
 
+// UnmarshalJSON also accepts the original_tweet field of the events published before schema version 1
+func (event *TweetDeleted) UnmarshalJSON(data []byte) error {
+	type tweetDeleted TweetDeleted
+	decoded := struct {
+		tweetDeleted
+		LegacyDeletedTweet *models.Tweet `json:"original_tweet"`
+	}{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*event = TweetDeleted(decoded.tweetDeleted)
+	if decoded.LegacyDeletedTweet != nil && event.DeletedTweet.ID == "" {
+		event.DeletedTweet = *decoded.LegacyDeletedTweet
+	}
+	return nil
+}
+
 type TweetUpdated struct {
 	OriginalTweet models.Tweet `json:"original_tweet"`
 	NewTweet      models.Tweet `json:"new_tweet"`
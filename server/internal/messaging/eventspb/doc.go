@@ -0,0 +1,4 @@
+// Package eventspb holds the protobuf messages of the published events
+package eventspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative cloudevent.proto events.proto
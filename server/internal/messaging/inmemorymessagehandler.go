@@ -0,0 +1,48 @@
+package messaging
+
+import (
+	"context"
+	"twitter-clone/internal/config"
+	repositories "twitter-clone/internal/repositories/feed"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+)
+
+// InMemoryMessageHandler runs the feed handlers on an in-process Go channel pub/sub,
+// so the full event flow works without an external broker.
+type InMemoryMessageHandler struct {
+}
+
+func (n *InMemoryMessageHandler) SetupMessageRouter(
+	configuration config.Configuration,
+	feedRepo repositories.FeedRepository,
+	deadLetters DeadLetterStore,
+	logger watermill.LoggerAdapter,
+) (message.Publisher, message.Subscriber, error) {
+	router, err := message.NewRouter(message.RouterConfig{}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	router.AddMiddleware(middleware.Recoverer)
+
+	// GoChannel has no global state, the same instance is used for publishing and subscribing
+	pubSub := gochannel.NewGoChannel(gochannel.Config{}, logger)
+
+	if err := addFeedHandlers(router, pubSub, pubSub, feedRepo, deadLetters, configuration.Messaging, logger); err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		err = router.Run(context.Background())
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	<-router.Running()
+
+	return pubSub, pubSub, nil
+}
@@ -0,0 +1,132 @@
+package messaging_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+	repositories "twitter-clone/internal/repositories/feed"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryMessageHandler_TweetCreatedUpdatesFeeds(t *testing.T) {
+	feedRepo := &repositories.InMemoryFeedRepository{}
+	logger := watermill.NewStdLogger(false, false)
+
+	handler := messaging.InMemoryMessageHandler{}
+	pub, sub, err := handler.SetupMessageRouter(config.Configuration{}, feedRepo, nil, logger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	feedUpdates, err := sub.Subscribe(ctx, messaging.FeedUpdatedTopic)
+	require.NoError(t, err)
+
+	tweet := models.Tweet{
+		ID:   "tweet1",
+		Tags: []string{"golang"},
+	}
+	publishEvent(t, pub, messaging.TweetCreatedTopic, messaging.TweetCreated{Tweet: tweet, OccurredAt: time.Now().UTC()})
+
+	feedUpdated := receiveFeedUpdated(ctx, t, feedUpdates)
+	assert.Equal(t, "golang", feedUpdated.Name)
+
+	feed, err := feedRepo.GetFeedByName(context.Background(), "golang")
+	require.NoError(t, err)
+	require.NotNil(t, feed, "Expected feed to be created by the handler")
+	assert.Len(t, feed.Tweets, 1)
+
+	publishEvent(t, pub, messaging.TweetDeletedTopic, messaging.TweetDeleted{DeletedTweet: tweet, OccurredAt: time.Now().UTC()})
+
+	feedUpdated = receiveFeedUpdated(ctx, t, feedUpdates)
+	assert.Equal(t, "golang", feedUpdated.Name)
+
+	feed, err = feedRepo.GetFeedByName(context.Background(), "golang")
+	require.NoError(t, err)
+	assert.Empty(t, feed.Tweets, "Expected tweet to be removed from the feed")
+}
+
+func TestInMemoryMessageHandler_TweetUpdatedReindexesFeeds(t *testing.T) {
+	feedRepo := &repositories.InMemoryFeedRepository{}
+	logger := watermill.NewStdLogger(false, false)
+
+	originalTweet := models.Tweet{
+		ID:      "tweet1",
+		Content: "original",
+		Tags:    []string{"golang", "news"},
+	}
+	require.NoError(t, feedRepo.CreateFeed(context.Background(), "golang"))
+	require.NoError(t, feedRepo.CreateFeed(context.Background(), "news"))
+	_, err := feedRepo.AppendTweet(context.Background(), originalTweet)
+	require.NoError(t, err)
+
+	handler := messaging.InMemoryMessageHandler{}
+	pub, sub, err := handler.SetupMessageRouter(config.Configuration{}, feedRepo, nil, logger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	feedUpdates, err := sub.Subscribe(ctx, messaging.FeedUpdatedTopic)
+	require.NoError(t, err)
+
+	newTweet := models.Tweet{
+		ID:      originalTweet.ID,
+		Content: "updated",
+		Tags:    []string{"golang", "rust"},
+	}
+	publishEvent(t, pub, messaging.TweetUpdatedTopic, messaging.TweetUpdated{
+		OriginalTweet: originalTweet,
+		NewTweet:      newTweet,
+		OccurredAt:    time.Now().UTC(),
+	})
+
+	var updatedFeeds []string
+	for range 3 {
+		updatedFeeds = append(updatedFeeds, receiveFeedUpdated(ctx, t, feedUpdates).Name)
+	}
+	assert.ElementsMatch(t, []string{"golang", "news", "rust"}, updatedFeeds)
+
+	golangFeed, err := feedRepo.GetFeedByName(context.Background(), "golang")
+	require.NoError(t, err)
+	require.Len(t, golangFeed.Tweets, 1)
+	assert.Equal(t, "updated", golangFeed.Tweets[0].Content, "Expected kept feed to hold the updated tweet")
+
+	newsFeed, err := feedRepo.GetFeedByName(context.Background(), "news")
+	require.NoError(t, err)
+	assert.Empty(t, newsFeed.Tweets, "Expected tweet to be removed from the dropped tag")
+
+	rustFeed, err := feedRepo.GetFeedByName(context.Background(), "rust")
+	require.NoError(t, err)
+	require.NotNil(t, rustFeed, "Expected feed to be created for the new tag")
+	assert.Len(t, rustFeed.Tweets, 1)
+}
+
+func publishEvent(t *testing.T, pub message.Publisher, topic string, event interface{}) {
+	msg, err := messaging.NewEventMessage(messaging.JSONCodec{}, topic, time.Now(), event)
+	require.NoError(t, err)
+
+	err = pub.Publish(topic, msg)
+	require.NoError(t, err)
+}
+
+func receiveFeedUpdated(ctx context.Context, t *testing.T, messages <-chan *message.Message) messaging.FeedUpdated {
+	select {
+	case msg := <-messages:
+		msg.Ack()
+
+		event := messaging.FeedUpdated{}
+		require.NoError(t, messaging.DecodeMessage(msg, messaging.FeedUpdatedTopic, &event))
+		return event
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for FeedUpdated event")
+		return messaging.FeedUpdated{}
+	}
+}
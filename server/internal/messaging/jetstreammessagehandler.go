@@ -0,0 +1,215 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"twitter-clone/internal/config"
+	repositories "twitter-clone/internal/repositories/feed"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-nats/v2/pkg/nats"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	nc "github.com/nats-io/nats.go"
+)
+
+// JetStreamMessageHandler publishes every topic to a single JetStream stream.
+// Feed updates are processed by durable queue consumers shared by all replicas, while
+// server-sent events use ephemeral consumers so every replica sees every event.
+type JetStreamMessageHandler struct {
+}
+
+func (n *JetStreamMessageHandler) SetupMessageRouter(
+	configuration config.Configuration,
+	feedRepo repositories.FeedRepository,
+	deadLetters DeadLetterStore,
+	logger watermill.LoggerAdapter,
+) (message.Publisher, message.Subscriber, error) {
+	settings := configuration.Messaging.JetStream
+	if settings.Stream == "" || settings.Consumer == "" {
+		return nil, nil, errors.New("jetstream messaging requires Messaging.JetStream.Stream and Messaging.JetStream.Consumer")
+	}
+
+	router, err := message.NewRouter(message.RouterConfig{}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	router.AddMiddleware(middleware.Recoverer)
+
+	pubConn, err := nc.Connect(configuration.NATSUrl, nc.Name(settings.Consumer+"-publisher"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot connect to NATS: %w", err)
+	}
+
+	if err := ensureStream(pubConn, settings.Stream); err != nil {
+		pubConn.Close()
+		return nil, nil, err
+	}
+
+	subjects := streamSubjectCalculator(settings.Stream)
+	jetStream := nats.JetStreamConfig{TrackMsgId: true} // Deduplicates publish retries by message UUID
+
+	natsPub, err := nats.NewPublisherWithNatsConn(pubConn, nats.PublisherPublishConfig{
+		Marshaler:         &nats.NATSMarshaler{},
+		SubjectCalculator: subjects,
+		JetStream:         jetStream,
+	}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub := streamPublisher{Publisher: natsPub, stream: settings.Stream}
+
+	routerSub, err := nats.NewSubscriber(JetStreamRouterSubscriberConfig(configuration.NATSUrl, settings), logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub, err := nats.NewSubscriber(JetStreamSSESubscriberConfig(configuration.NATSUrl, settings), logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := addFeedHandlers(router, routerSub, pub, feedRepo, deadLetters, configuration.Messaging, logger); err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		err = router.Run(context.Background())
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	<-router.Running()
+
+	return pub, sub, nil
+}
+
+// ensureStream creates the stream capturing all topics when it does not exist yet
+func ensureStream(conn *nc.Conn, stream string) error {
+	js, err := conn.JetStream()
+	if err != nil {
+		return fmt.Errorf("cannot initialize JetStream: %w", err)
+	}
+
+	_, err = js.StreamInfo(stream)
+	if errors.Is(err, nc.ErrStreamNotFound) {
+		streamConfig := JetStreamStreamConfig(stream)
+		_, err = js.AddStream(&streamConfig)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot provision stream %s: %w", stream, err)
+	}
+
+	return nil
+}
+
+// JetStreamStreamConfig captures the <stream>.<topic> subjects of all topics
+func JetStreamStreamConfig(stream string) nc.StreamConfig {
+	return nc.StreamConfig{
+		Name:     stream,
+		Subjects: []string{stream + ".>"},
+	}
+}
+
+// JetStreamRouterConsumer processes the whole stream, messages which are not acknowledged within
+// AckWait are redelivered up to MaxDeliver times
+func JetStreamRouterConsumer(settings config.JetStream) nc.ConsumerConfig {
+	return nc.ConsumerConfig{
+		DeliverPolicy: nc.DeliverAllPolicy,
+		AckPolicy:     nc.AckExplicitPolicy,
+		AckWait:       settings.AckWait.Duration,
+		MaxDeliver:    settings.MaxDeliver,
+	}
+}
+
+// JetStreamSSEConsumer only receives the messages published after subscribing
+func JetStreamSSEConsumer() nc.ConsumerConfig {
+	return nc.ConsumerConfig{
+		DeliverPolicy: nc.DeliverNewPolicy,
+		AckPolicy:     nc.AckExplicitPolicy,
+	}
+}
+
+// JetStreamRouterSubscriberConfig subscribes the feed handlers with one durable consumer per topic.
+// Consumers are named <Consumer>_<topic> after their queue group, which the replicas share.
+func JetStreamRouterSubscriberConfig(url string, settings config.JetStream) nats.SubscriberConfig {
+	subjects := streamSubjectCalculator(settings.Stream)
+	return nats.SubscriberConfig{
+		URL:              url,
+		NatsOptions:      []nc.Option{nc.Name(settings.Consumer + "-router")},
+		QueueGroupPrefix: settings.Consumer,
+		AckWaitTimeout:   settings.AckWait.Duration,
+		Unmarshaler:      &nats.NATSMarshaler{},
+		SubjectCalculator: func(queueGroupPrefix string, topic string) *nats.SubjectDetail {
+			detail := subjects(queueGroupPrefix, topic)
+			detail.QueueGroup = durableName(queueGroupPrefix, topic)
+			return detail
+		},
+		JetStream: nats.JetStreamConfig{
+			TrackMsgId:       true,
+			SubscribeOptions: subscribeOptions(JetStreamRouterConsumer(settings)),
+		},
+	}
+}
+
+// JetStreamSSESubscriberConfig subscribes the server-sent events with an ephemeral consumer
+// without queue group, so every replica receives every event
+func JetStreamSSESubscriberConfig(url string, settings config.JetStream) nats.SubscriberConfig {
+	return nats.SubscriberConfig{
+		URL:               url,
+		NatsOptions:       []nc.Option{nc.Name(settings.Consumer + "-sse")},
+		AckWaitTimeout:    settings.AckWait.Duration,
+		Unmarshaler:       &nats.NATSMarshaler{},
+		SubjectCalculator: streamSubjectCalculator(settings.Stream),
+		JetStream: nats.JetStreamConfig{
+			SubscribeOptions: subscribeOptions(JetStreamSSEConsumer()),
+		},
+	}
+}
+
+// subscribeOptions applies the deliver and ack settings of the consumer when subscribing
+func subscribeOptions(consumer nc.ConsumerConfig) []nc.SubOpt {
+	var options []nc.SubOpt
+	switch consumer.DeliverPolicy {
+	case nc.DeliverAllPolicy:
+		options = append(options, nc.DeliverAll())
+	case nc.DeliverNewPolicy:
+		options = append(options, nc.DeliverNew())
+	}
+	if consumer.AckPolicy == nc.AckExplicitPolicy {
+		options = append(options, nc.AckExplicit())
+	}
+	if consumer.AckWait > 0 {
+		options = append(options, nc.AckWait(consumer.AckWait))
+	}
+	if consumer.MaxDeliver != 0 {
+		options = append(options, nc.MaxDeliver(consumer.MaxDeliver))
+	}
+	return options
+}
+
+// streamSubjectCalculator publishes and subscribes topics as <stream>.<topic> subjects
+func streamSubjectCalculator(stream string) nats.SubjectCalculator {
+	return func(queueGroupPrefix string, topic string) *nats.SubjectDetail {
+		return &nats.SubjectDetail{Primary: stream + "." + topic}
+	}
+}
+
+// streamPublisher publishes topics as <stream>.<topic> subjects, the subject calculator
+// of the watermill publisher is only used to provision streams
+type streamPublisher struct {
+	message.Publisher
+	stream string
+}
+
+func (p streamPublisher) Publish(topic string, messages ...*message.Message) error {
+	return p.Publisher.Publish(p.stream+"."+topic, messages...)
+}
+
+// durableName derives a consumer name from the topic, consumer names cannot contain dots
+func durableName(prefix string, topic string) string {
+	return strings.ReplaceAll(prefix+"_"+topic, ".", "_")
+}
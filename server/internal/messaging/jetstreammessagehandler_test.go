@@ -0,0 +1,94 @@
+package messaging_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/messaging"
+	repositories "twitter-clone/internal/repositories/feed"
+
+	"github.com/ThreeDotsLabs/watermill"
+	nc "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJetStreamMessageHandler_RequiresStreamAndConsumer(t *testing.T) {
+	handler := messaging.JetStreamMessageHandler{}
+
+	configuration := config.Configuration{NATSUrl: "nats://127.0.0.1:1"}
+	configuration.Messaging.JetStream.Consumer = "twitter-clone"
+
+	_, _, err := handler.SetupMessageRouter(configuration, &repositories.InMemoryFeedRepository{}, nil, watermill.NopLogger{})
+	assert.ErrorContains(t, err, "Messaging.JetStream.Stream", "Missing stream should be rejected before connecting")
+}
+
+var jetStreamSettings = config.JetStream{
+	Stream:     "tweets",
+	Consumer:   "twitter-clone",
+	AckWait:    config.Duration{Duration: 30 * time.Second},
+	MaxDeliver: 5,
+}
+
+func TestJetStreamStreamConfig_CapturesAllTopics(t *testing.T) {
+	stream := messaging.JetStreamStreamConfig(jetStreamSettings.Stream)
+	assert.Equal(t, "tweets", stream.Name)
+	require.Equal(t, []string{"tweets.>"}, stream.Subjects)
+
+	// Both subscribers listen on subjects captured by the stream
+	routerConfig := messaging.JetStreamRouterSubscriberConfig("nats://127.0.0.1:4222", jetStreamSettings)
+	sseConfig := messaging.JetStreamSSESubscriberConfig("nats://127.0.0.1:4222", jetStreamSettings)
+	for _, topic := range []string{messaging.TweetCreatedTopic, messaging.FeedUpdatedTopic, messaging.PoisonTopic} {
+		for _, subject := range []string{
+			routerConfig.SubjectCalculator(routerConfig.QueueGroupPrefix, topic).Primary,
+			sseConfig.SubjectCalculator(sseConfig.QueueGroupPrefix, topic).Primary,
+		} {
+			assert.Equal(t, "tweets."+topic, subject)
+			assert.True(t, strings.HasPrefix(subject, strings.TrimSuffix(stream.Subjects[0], ">")), "Subject %s should be captured by the stream", subject)
+		}
+	}
+}
+
+func TestJetStreamRouterSubscriberConfig_NamesDurableConsumers(t *testing.T) {
+	subscriberConfig := messaging.JetStreamRouterSubscriberConfig("nats://127.0.0.1:4222", jetStreamSettings)
+	subscriptionConfig := subscriberConfig.GetSubscriberSubscriptionConfig()
+	require.NoError(t, subscriptionConfig.Validate())
+
+	assert.Equal(t, "twitter-clone", subscriberConfig.QueueGroupPrefix)
+	assert.True(t, subscriberConfig.JetStream.TrackMsgId)
+	assert.Empty(t, subscriberConfig.JetStream.CalculateDurableName(messaging.TweetCreatedTopic), "The queue group should name the durable consumer")
+
+	// Each topic has its own durable consumer, named <Consumer>_<topic> without dots
+	detail := subscriberConfig.SubjectCalculator(subscriberConfig.QueueGroupPrefix, messaging.TweetCreatedTopic)
+	assert.Equal(t, "twitter-clone_"+messaging.TweetCreatedTopic, detail.QueueGroup)
+	detail = subscriberConfig.SubjectCalculator(subscriberConfig.QueueGroupPrefix, "feed.updates")
+	assert.Equal(t, "twitter-clone_feed_updates", detail.QueueGroup)
+
+	// The whole stream is processed, failed deliveries are redelivered after AckWait at most MaxDeliver times
+	assert.Equal(t, nc.ConsumerConfig{
+		DeliverPolicy: nc.DeliverAllPolicy,
+		AckPolicy:     nc.AckExplicitPolicy,
+		AckWait:       30 * time.Second,
+		MaxDeliver:    5,
+	}, messaging.JetStreamRouterConsumer(jetStreamSettings))
+	assert.Len(t, subscriberConfig.JetStream.SubscribeOptions, 4, "Expected the deliver, ack, ack wait and max deliver options")
+}
+
+func TestJetStreamSSESubscriberConfig_SubscribesEphemeralConsumers(t *testing.T) {
+	subscriberConfig := messaging.JetStreamSSESubscriberConfig("nats://127.0.0.1:4222", jetStreamSettings)
+	subscriptionConfig := subscriberConfig.GetSubscriberSubscriptionConfig()
+	require.NoError(t, subscriptionConfig.Validate())
+
+	// Without queue group nor durable name every replica gets its own ephemeral consumer
+	assert.Empty(t, subscriberConfig.QueueGroupPrefix)
+	assert.Empty(t, subscriberConfig.SubjectCalculator(subscriberConfig.QueueGroupPrefix, messaging.FeedUpdatedTopic).QueueGroup)
+	assert.Empty(t, subscriberConfig.JetStream.CalculateDurableName(messaging.FeedUpdatedTopic))
+
+	// Only the events published after subscribing are sent to the clients
+	assert.Equal(t, nc.ConsumerConfig{
+		DeliverPolicy: nc.DeliverNewPolicy,
+		AckPolicy:     nc.AckExplicitPolicy,
+	}, messaging.JetStreamSSEConsumer())
+	assert.Len(t, subscriberConfig.JetStream.SubscribeOptions, 2, "Expected the deliver and ack options")
+}
@@ -0,0 +1,142 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	repositories "twitter-clone/internal/repositories/feed"
+
+	"github.com/IBM/sarama"
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+)
+
+// KafkaMessageHandler keys the tweet events by the first tag of the tweet and the feed updates by
+// the feed name, see TagPartitionKey. Kafka orders messages within a partition of a topic only.
+type KafkaMessageHandler struct {
+}
+
+func (n *KafkaMessageHandler) SetupMessageRouter(
+	configuration config.Configuration,
+	feedRepo repositories.FeedRepository,
+	deadLetters DeadLetterStore,
+	logger watermill.LoggerAdapter,
+) (message.Publisher, message.Subscriber, error) {
+	settings := configuration.Messaging.Kafka
+	if len(settings.Brokers) == 0 || settings.ConsumerGroup == "" {
+		return nil, nil, errors.New("kafka messaging requires Messaging.Kafka.Brokers and Messaging.Kafka.ConsumerGroup")
+	}
+
+	router, err := message.NewRouter(message.RouterConfig{}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	router.AddMiddleware(middleware.Recoverer)
+
+	marshaler := kafka.NewWithPartitioningMarshaler(TagPartitionKey)
+
+	// Kafka 4 dropped the protocol versions older than 2.1, which are the defaults of watermill
+	publisherSaramaConfig := kafka.DefaultSaramaSyncPublisherConfig()
+	publisherSaramaConfig.Version = sarama.V2_1_0_0
+
+	pub, err := kafka.NewPublisher(kafka.PublisherConfig{
+		Brokers:               settings.Brokers,
+		Marshaler:             marshaler,
+		OverwriteSaramaConfig: publisherSaramaConfig,
+	}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The consumer group starts from the oldest offset, so events published before the first start are not lost
+	routerSaramaConfig := kafka.DefaultSaramaSubscriberConfig()
+	routerSaramaConfig.Version = sarama.V2_1_0_0
+	routerSaramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	routerSub, err := kafka.NewSubscriber(kafka.SubscriberConfig{
+		Brokers:               settings.Brokers,
+		Unmarshaler:           marshaler,
+		ConsumerGroup:         settings.ConsumerGroup,
+		OverwriteSaramaConfig: routerSaramaConfig,
+	}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Server-sent events are consumed without a group, so every replica receives every event
+	sseSaramaConfig := kafka.DefaultSaramaSubscriberConfig()
+	sseSaramaConfig.Version = sarama.V2_1_0_0
+
+	sub, err := kafka.NewSubscriber(kafka.SubscriberConfig{
+		Brokers:               settings.Brokers,
+		Unmarshaler:           marshaler,
+		OverwriteSaramaConfig: sseSaramaConfig,
+	}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := addFeedHandlers(router, routerSub, pub, feedRepo, deadLetters, configuration.Messaging, logger); err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		err = router.Run(context.Background())
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	<-router.Running()
+
+	return pub, sub, nil
+}
+
+// TagPartitionKey keys tweet events by the first tag of the tweet and feed updates by the feed name,
+// so the events of one topic for tweets sharing their first tag are handled in order. The feeds of
+// the other tags of a tweet may be updated concurrently with events keyed by those tags. Untagged
+// tweets are keyed by their id, updated tweets by the first original tag so edits which change the
+// tags stay in the partition of the created event.
+func TagPartitionKey(topic string, msg *message.Message) (string, error) {
+	switch topic {
+	case TweetCreatedTopic:
+		event := TweetCreated{}
+		if err := DecodeMessage(msg, topic, &event); err != nil {
+			return "", err
+		}
+		return tweetPartitionKey(event.Tweet), nil
+	case TweetUpdatedTopic:
+		event := TweetUpdated{}
+		if err := DecodeMessage(msg, topic, &event); err != nil {
+			return "", err
+		}
+		if len(event.OriginalTweet.Tags) == 0 {
+			return tweetPartitionKey(event.NewTweet), nil
+		}
+		return tweetPartitionKey(event.OriginalTweet), nil
+	case TweetDeletedTopic:
+		event := TweetDeleted{}
+		if err := DecodeMessage(msg, topic, &event); err != nil {
+			return "", err
+		}
+		return tweetPartitionKey(event.DeletedTweet), nil
+	case FeedUpdatedTopic:
+		event := FeedUpdated{}
+		if err := DecodeMessage(msg, topic, &event); err != nil {
+			return "", err
+		}
+		return event.Name, nil
+	default:
+		return msg.UUID, nil
+	}
+}
+
+func tweetPartitionKey(tweet models.Tweet) string {
+	if len(tweet.Tags) > 0 {
+		return tweet.Tags[0]
+	}
+	return tweet.ID
+}
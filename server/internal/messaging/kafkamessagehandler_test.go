@@ -0,0 +1,48 @@
+package messaging_test
+
+import (
+	"testing"
+	"time"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagPartitionKey(t *testing.T) {
+	tagged := models.Tweet{ID: "tagged", Tags: []string{"golang", "news"}}
+	untagged := models.Tweet{ID: "untagged"}
+
+	tests := []struct {
+		name     string
+		topic    string
+		event    interface{}
+		expected string
+	}{
+		{"created tweet by first tag", messaging.TweetCreatedTopic, messaging.TweetCreated{Tweet: tagged}, "golang"},
+		{"untagged tweet by id", messaging.TweetCreatedTopic, messaging.TweetCreated{Tweet: untagged}, "untagged"},
+		{"updated tweet by original tag", messaging.TweetUpdatedTopic, messaging.TweetUpdated{OriginalTweet: tagged, NewTweet: untagged}, "golang"},
+		{"updated tweet by new tag", messaging.TweetUpdatedTopic, messaging.TweetUpdated{OriginalTweet: untagged, NewTweet: tagged}, "golang"},
+		{"deleted tweet by first tag", messaging.TweetDeletedTopic, messaging.TweetDeleted{DeletedTweet: tagged}, "golang"},
+		{"feed update by name", messaging.FeedUpdatedTopic, messaging.FeedUpdated{Name: "news"}, "news"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := messaging.NewEventMessage(messaging.JSONCodec{}, tt.topic, time.Now(), tt.event)
+			require.NoError(t, err)
+
+			key, err := messaging.TagPartitionKey(tt.topic, msg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, key)
+		})
+	}
+}
+
+func TestTagPartitionKey_InvalidPayload(t *testing.T) {
+	_, err := messaging.TagPartitionKey(messaging.TweetCreatedTopic, message.NewMessage(watermill.NewUUID(), []byte("{")))
+	assert.Error(t, err, "Invalid payload should not be published")
+}
@@ -6,12 +6,107 @@ import (
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
 )
 
 type MessageHandler interface {
 	SetupMessageRouter(
 		configuration config.Configuration,
 		feedRepo repositories.FeedRepository,
+		deadLetters DeadLetterStore,
 		logger watermill.LoggerAdapter,
 	) (message.Publisher, message.Subscriber, error)
 }
+
+// addFeedHandlers registers the handlers which keep feeds in sync with tweet lifecycle events.
+// Failing updates are retried with exponential backoff and then moved to the poison topic,
+// whose messages are kept in the dead letter store. Without a store they stay on the broker.
+func addFeedHandlers(
+	router *message.Router,
+	sub message.Subscriber,
+	pub message.Publisher,
+	feedRepo repositories.FeedRepository,
+	deadLetters DeadLetterStore,
+	settings config.Messaging,
+	logger watermill.LoggerAdapter,
+) error {
+	codec, err := NewEventCodec(settings.Encoding)
+	if err != nil {
+		return err
+	}
+
+	// Redelivered creations and deletions are skipped without touching the feeds. Redeliveries to other
+	// replicas leave the feeds unchanged, so they emit no duplicate feed updated events either
+	deduplication := NewInMemoryDeduplicationStore(settings.Deduplication.TTL.Duration)
+
+	poisonQueue, err := middleware.PoisonQueue(pub, PoisonTopic)
+	if err != nil {
+		return err
+	}
+
+	// Panics are recovered inside the retries, so they are retried and poisoned like errors
+	middlewares := []message.HandlerMiddleware{poisonQueue}
+	// The retry middleware always retries at least once, it is left out to disable retries
+	if settings.Retry.MaxRetries > 0 {
+		retryMiddleware := middleware.Retry{
+			MaxRetries:      settings.Retry.MaxRetries,
+			InitialInterval: settings.Retry.InitialInterval.Duration,
+			MaxInterval:     settings.Retry.MaxInterval.Duration,
+			Multiplier:      settings.Retry.Multiplier,
+			Logger:          logger,
+		}
+		middlewares = append(middlewares, retryMiddleware.Middleware)
+	}
+	middlewares = append(middlewares, middleware.Recoverer)
+
+	handlers := []*message.Handler{
+		router.AddHandler(
+			UpdateFeedsOnNewTweetCreated,
+			TweetCreatedTopic,
+			sub,
+			FeedUpdatedTopic,
+			pub,
+			func(msg *message.Message) (messages []*message.Message, err error) {
+				return TweetCreatedHandler(msg, feedRepo, deduplication, codec, logger)
+			},
+		),
+		router.AddHandler(
+			UpdateFeedsOnTweetUpdated,
+			TweetUpdatedTopic,
+			sub,
+			FeedUpdatedTopic,
+			pub,
+			func(msg *message.Message) (messages []*message.Message, err error) {
+				return TweetUpdatedHandler(msg, feedRepo, codec, logger)
+			},
+		),
+		router.AddHandler(
+			UpdateFeedsOnTweetDeleted,
+			TweetDeletedTopic,
+			sub,
+			FeedUpdatedTopic,
+			pub,
+			func(msg *message.Message) (messages []*message.Message, err error) {
+				return TweetDeletedHandler(msg, feedRepo, deduplication, codec, logger)
+			},
+		),
+	}
+
+	for _, handler := range handlers {
+		handler.AddMiddleware(middlewares...)
+	}
+
+	// Dead letters are collected by the subscriber shared by all replicas, so each is stored once
+	if deadLetters != nil {
+		router.AddNoPublisherHandler(
+			CollectDeadLetters,
+			PoisonTopic,
+			sub,
+			func(msg *message.Message) error {
+				return DeadLetterHandler(msg, deadLetters, logger)
+			},
+		)
+	}
+
+	return nil
+}
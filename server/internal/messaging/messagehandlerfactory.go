@@ -1,19 +1,25 @@
 package messaging
 
 import (
-	"errors"
+	"fmt"
 	"twitter-clone/internal/config"
 )
 
 func CreateMessageHandler(configuration config.Configuration) (MessageHandler, error) {
-	switch configuration.Mode {
-	case config.InMemory:
-		return &NullMessageHandler{}, nil
-	case config.Persistent:
+	switch configuration.Messaging.Driver {
+	case config.MemoryDriver:
+		return &InMemoryMessageHandler{}, nil
+	case config.JetStreamDriver:
+		return &JetStreamMessageHandler{}, nil
+	case config.NATSDriver:
 		return &NATSMessageHandler{}, nil
-	case config.Cloud:
+	case config.KafkaDriver:
+		return &KafkaMessageHandler{}, nil
+	case config.PubSubDriver:
 		return &PubSubMessageHandler{}, nil
+	case config.NullDriver:
+		return &NullMessageHandler{}, nil
 	default:
-		return nil, errors.New("unknown mode")
+		return nil, fmt.Errorf("unknown messaging driver %q", configuration.Messaging.Driver)
 	}
 }
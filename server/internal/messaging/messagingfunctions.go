@@ -1,7 +1,7 @@
 package messaging
 
 import (
-	"encoding/json"
+	"slices"
 	"time"
 	repositories "twitter-clone/internal/repositories/feed"
 
@@ -12,6 +12,8 @@ import (
 func TweetCreatedHandler(
 	msg *message.Message,
 	feedRepo repositories.FeedRepository,
+	deduplication DeduplicationStore,
+	codec EventCodec,
 	logger watermill.LoggerAdapter,
 ) (messages []*message.Message, err error) {
 
@@ -24,34 +26,54 @@ func TweetCreatedHandler(
 	}()
 
 	event := TweetCreated{}
-	err = json.Unmarshal(msg.Payload, &event)
+	err = DecodeMessage(msg, TweetCreatedTopic, &event)
 	if err != nil {
 		return nil, err
 	}
 
+	key := DeduplicationKey(TweetCreatedTopic, event.Tweet.ID)
+	if seen, err := deduplication.Seen(msg.Context(), key); err != nil || seen {
+		return nil, skipRedelivery(msg, err, logger)
+	}
+
 	logger.Info("Adding tweet", watermill.LogFields{"post": event.Tweet})
 
+	appended := false
 	if len(event.Tweet.Tags) > 0 {
 		for _, tag := range event.Tweet.Tags {
 			logger.Info("Adding tag", watermill.LogFields{"tag": tag})
-			err = feedRepo.CreateFeed(tag)
+			err = feedRepo.CreateFeed(msg.Context(), tag)
 			if err != nil {
 				return nil, err
 			}
 		}
 
-		err = feedRepo.AppendTweet(event.Tweet)
+		appended, err = feedRepo.AppendTweet(msg.Context(), event.Tweet)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return CreateFeedUpdatedEvents(event.Tweet.Tags)
+	// The feeds of a redelivered event, e.g. to another replica, were already updated and announced
+	if !appended {
+		rememberHandled(msg, deduplication, key, logger)
+		return nil, nil
+	}
+
+	messages, err = CreateFeedUpdatedEvents(event.Tweet.Tags, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	rememberHandled(msg, deduplication, key, logger)
+	return messages, nil
 }
 
 func TweetDeletedHandler(
 	msg *message.Message,
 	feedRepo repositories.FeedRepository,
+	deduplication DeduplicationStore,
+	codec EventCodec,
 	logger watermill.LoggerAdapter,
 ) (messages []*message.Message, err error) {
 
@@ -64,20 +86,118 @@ func TweetDeletedHandler(
 	}()
 
 	event := TweetDeleted{}
-	err = json.Unmarshal(msg.Payload, &event)
+	err = DecodeMessage(msg, TweetDeletedTopic, &event)
 	if err != nil {
 		return nil, err
 	}
 
+	key := DeduplicationKey(TweetDeletedTopic, event.DeletedTweet.ID)
+	if seen, err := deduplication.Seen(msg.Context(), key); err != nil || seen {
+		return nil, skipRedelivery(msg, err, logger)
+	}
+
 	logger.Info("Deleting tweet", watermill.LogFields{"post": event.DeletedTweet})
 
-	feedRepo.DeleteTweet(event.DeletedTweet)
-	// TODO: handle error
+	deleted, err := feedRepo.DeleteTweet(msg.Context(), event.DeletedTweet)
+	if err != nil {
+		return nil, err
+	}
+
+	// The feeds of a redelivered event, e.g. to another replica, were already updated and announced
+	if !deleted {
+		rememberHandled(msg, deduplication, key, logger)
+		return nil, nil
+	}
+
+	messages, err = CreateFeedUpdatedEvents(event.DeletedTweet.Tags, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	rememberHandled(msg, deduplication, key, logger)
+	return messages, nil
+}
+
+// skipRedelivery logs an event which was already handled, a failing lookup is returned to retry the message
+func skipRedelivery(msg *message.Message, err error, logger watermill.LoggerAdapter) error {
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Skipping redelivered event", watermill.LogFields{"uuid": msg.UUID})
+	return nil
+}
+
+// rememberHandled records the handled event. The feeds are already updated, so a failure is only
+// logged: a redelivery finds them updated and emits no feed updated events.
+func rememberHandled(msg *message.Message, deduplication DeduplicationStore, key string, logger watermill.LoggerAdapter) {
+	if err := deduplication.Remember(msg.Context(), key); err != nil {
+		logger.Error("Error while remembering handled event", err, watermill.LogFields{"uuid": msg.UUID})
+	}
+}
+
+func TweetUpdatedHandler(
+	msg *message.Message,
+	feedRepo repositories.FeedRepository,
+	codec EventCodec,
+	logger watermill.LoggerAdapter,
+) (messages []*message.Message, err error) {
+
+	defer func() {
+		if err == nil {
+			logger.Info("Successfully updated feeds on tweet updated", nil)
+		} else {
+			logger.Error("Error while updating feeds on tweet updated", err, nil)
+		}
+	}()
+
+	event := TweetUpdated{}
+	err = DecodeMessage(msg, TweetUpdatedTopic, &event)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Updating tweet", watermill.LogFields{"post": event.NewTweet})
+
+	// Feeds keep their own copy of the tweet, so the original copy is removed from
+	// every feed it was indexed in (including dropped tags) and the new one is re-added.
+	if len(event.OriginalTweet.Tags) > 0 {
+		_, err = feedRepo.DeleteTweet(msg.Context(), event.OriginalTweet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(event.NewTweet.Tags) > 0 {
+		for _, tag := range event.NewTweet.Tags {
+			logger.Info("Adding tag", watermill.LogFields{"tag": tag})
+			err = feedRepo.CreateFeed(msg.Context(), tag)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	return CreateFeedUpdatedEvents(event.DeletedTweet.Tags)
+		_, err = feedRepo.AppendTweet(msg.Context(), event.NewTweet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return CreateFeedUpdatedEvents(MergeTags(event.OriginalTweet.Tags, event.NewTweet.Tags), codec)
+}
+
+// MergeTags returns the union of both tag lists preserving their order
+func MergeTags(tags []string, otherTags []string) []string {
+	var merged []string
+	for _, tag := range append(append([]string{}, tags...), otherTags...) {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
 }
 
-func CreateFeedUpdatedEvents(tags []string) ([]*message.Message, error) {
+func CreateFeedUpdatedEvents(tags []string, codec EventCodec) ([]*message.Message, error) {
 	var messages []*message.Message
 
 	for _, tag := range tags {
@@ -86,13 +206,11 @@ func CreateFeedUpdatedEvents(tags []string) ([]*message.Message, error) {
 			OccurredAt: time.Now().UTC(),
 		}
 
-		payload, err := json.Marshal(event)
+		msg, err := NewEventMessage(codec, FeedUpdatedTopic, event.OccurredAt, event)
 		if err != nil {
 			return nil, err
 		}
 
-		msg := message.NewMessage(watermill.NewUUID(), payload)
-
 		messages = append(messages, msg)
 	}
 
@@ -18,6 +18,7 @@ type NATSMessageHandler struct {
 func (n *NATSMessageHandler) SetupMessageRouter(
 	configuration config.Configuration,
 	feedRepo repositories.FeedRepository,
+	deadLetters DeadLetterStore,
 	logger watermill.LoggerAdapter,
 ) (message.Publisher, message.Subscriber, error) {
 	router, err := message.NewRouter(message.RouterConfig{}, logger)
@@ -47,27 +48,9 @@ func (n *NATSMessageHandler) SetupMessageRouter(
 		return nil, nil, err
 	}
 
-	router.AddHandler(
-		UpdateFeedsOnNewTweetCreated,
-		TweetCreatedTopic,
-		sub,
-		FeedUpdatedTopic,
-		pub,
-		func(msg *message.Message) (messages []*message.Message, err error) {
-			return TweetCreatedHandler(msg, feedRepo, logger)
-		},
-	)
-
-	router.AddHandler(
-		UpdateFeedsOnTweetDeleted,
-		TweetDeletedTopic,
-		sub,
-		FeedUpdatedTopic,
-		pub,
-		func(msg *message.Message) (messages []*message.Message, err error) {
-			return TweetDeletedHandler(msg, feedRepo, logger)
-		},
-	)
+	if err := addFeedHandlers(router, sub, pub, feedRepo, deadLetters, configuration.Messaging, logger); err != nil {
+		return nil, nil, err
+	}
 
 	go func() {
 		err = router.Run(context.Background())
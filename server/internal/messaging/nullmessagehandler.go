@@ -15,6 +15,7 @@ type NullMessageHandler struct {
 func (n *NullMessageHandler) SetupMessageRouter(
 	configuration config.Configuration,
 	feedRepo repositories.FeedRepository,
+	deadLetters DeadLetterStore,
 	logger watermill.LoggerAdapter,
 ) (message.Publisher, message.Subscriber, error) {
 	// Create no-op publisher and subscriber
@@ -0,0 +1,187 @@
+package messaging
+
+import (
+	"fmt"
+	"time"
+	"twitter-clone/internal/messaging/eventspb"
+	"twitter-clone/internal/models"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Attributes of the protobuf envelope which have no field of their own
+const (
+	timeAttribute            = "time"
+	dataContentTypeAttribute = "datacontenttype"
+	dataSchemaAttribute      = "dataschema"
+)
+
+// ProtobufCodec encodes the events in the CloudEvents protobuf format, the event messages of
+// eventspb are packed in the proto_data field
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) ContentType() string {
+	return ProtobufContentType
+}
+
+func (ProtobufCodec) Encode(topic string, id string, occurredAt time.Time, event interface{}) ([]byte, error) {
+	eventType, err := EventType(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	eventMessage, err := toProto(event)
+	if err != nil {
+		return nil, err
+	}
+	data, err := anypb.New(eventMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(&eventspb.CloudEvent{
+		Id:          id,
+		Source:      EventSource,
+		SpecVersion: CloudEventsSpecVersion,
+		Type:        eventType,
+		Attributes: map[string]*eventspb.CloudEventAttributeValue{
+			timeAttribute:            {Attr: &eventspb.CloudEventAttributeValue_CeTimestamp{CeTimestamp: timestamppb.New(occurredAt)}},
+			dataContentTypeAttribute: {Attr: &eventspb.CloudEventAttributeValue_CeString{CeString: "application/protobuf"}},
+			dataSchemaAttribute:      {Attr: &eventspb.CloudEventAttributeValue_CeUri{CeUri: EventDataSchema(eventType)}},
+		},
+		Data: &eventspb.CloudEvent_ProtoData{ProtoData: data},
+	})
+}
+
+func (ProtobufCodec) Decode(payload []byte, topic string, event interface{}) error {
+	var envelope eventspb.CloudEvent
+	if err := proto.Unmarshal(payload, &envelope); err != nil {
+		return err
+	}
+
+	dataSchema := envelope.GetAttributes()[dataSchemaAttribute].GetCeUri()
+	if err := checkEnvelope(topic, envelope.GetType(), dataSchema); err != nil {
+		return err
+	}
+
+	if envelope.GetProtoData() == nil {
+		return fmt.Errorf("no event data in %q envelope %q", envelope.GetType(), envelope.GetId())
+	}
+	data, err := envelope.GetProtoData().UnmarshalNew()
+	if err != nil {
+		return err
+	}
+
+	return fromProto(data, event)
+}
+
+// toProto converts an event to its protobuf message
+func toProto(event interface{}) (proto.Message, error) {
+	switch event := event.(type) {
+	case TweetCreated:
+		return &eventspb.TweetCreated{Tweet: tweetToProto(event.Tweet), OccurredAt: timestampToProto(event.OccurredAt)}, nil
+	case TweetUpdated:
+		return &eventspb.TweetUpdated{
+			OriginalTweet: tweetToProto(event.OriginalTweet),
+			NewTweet:      tweetToProto(event.NewTweet),
+			OccurredAt:    timestampToProto(event.OccurredAt),
+		}, nil
+	case TweetDeleted:
+		return &eventspb.TweetDeleted{DeletedTweet: tweetToProto(event.DeletedTweet), OccurredAt: timestampToProto(event.OccurredAt)}, nil
+	case FeedUpdated:
+		return &eventspb.FeedUpdated{Name: event.Name, OccurredAt: timestampToProto(event.OccurredAt)}, nil
+	case *TweetCreated:
+		return toProto(*event)
+	case *TweetUpdated:
+		return toProto(*event)
+	case *TweetDeleted:
+		return toProto(*event)
+	case *FeedUpdated:
+		return toProto(*event)
+	default:
+		return nil, fmt.Errorf("no protobuf message for %T", event)
+	}
+}
+
+// fromProto fills the event pointed to by event from its protobuf message
+func fromProto(data proto.Message, event interface{}) error {
+	switch data := data.(type) {
+	case *eventspb.TweetCreated:
+		if event, ok := event.(*TweetCreated); ok {
+			*event = TweetCreated{Tweet: tweetFromProto(data.GetTweet()), OccurredAt: timestampFromProto(data.GetOccurredAt())}
+			return nil
+		}
+	case *eventspb.TweetUpdated:
+		if event, ok := event.(*TweetUpdated); ok {
+			*event = TweetUpdated{
+				OriginalTweet: tweetFromProto(data.GetOriginalTweet()),
+				NewTweet:      tweetFromProto(data.GetNewTweet()),
+				OccurredAt:    timestampFromProto(data.GetOccurredAt()),
+			}
+			return nil
+		}
+	case *eventspb.TweetDeleted:
+		if event, ok := event.(*TweetDeleted); ok {
+			*event = TweetDeleted{DeletedTweet: tweetFromProto(data.GetDeletedTweet()), OccurredAt: timestampFromProto(data.GetOccurredAt())}
+			return nil
+		}
+	case *eventspb.FeedUpdated:
+		if event, ok := event.(*FeedUpdated); ok {
+			*event = FeedUpdated{Name: data.GetName(), OccurredAt: timestampFromProto(data.GetOccurredAt())}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: cannot read %s into %T", ErrUnexpectedEventType, proto.MessageName(data), event)
+}
+
+func tweetToProto(tweet models.Tweet) *eventspb.Tweet {
+	return &eventspb.Tweet{
+		Id:        tweet.ID,
+		Title:     tweet.Title,
+		Content:   tweet.Content,
+		Tags:      tweet.Tags,
+		CreatedAt: timestampToProto(tweet.CreatedAt.Time),
+		User: &eventspb.User{
+			Id:        tweet.User.ID,
+			FirstName: tweet.User.FirstName,
+			LastName:  tweet.User.LastName,
+			Email:     tweet.User.Email,
+			Picture:   tweet.User.Picture,
+		},
+	}
+}
+
+func tweetFromProto(tweet *eventspb.Tweet) models.Tweet {
+	return models.Tweet{
+		ID:        tweet.GetId(),
+		Title:     tweet.GetTitle(),
+		Content:   tweet.GetContent(),
+		Tags:      tweet.GetTags(),
+		CreatedAt: models.MySQLTimestamp{Time: timestampFromProto(tweet.GetCreatedAt())},
+		User: models.User{
+			ID:        tweet.GetUser().GetId(),
+			FirstName: tweet.GetUser().GetFirstName(),
+			LastName:  tweet.GetUser().GetLastName(),
+			Email:     tweet.GetUser().GetEmail(),
+			Picture:   tweet.GetUser().GetPicture(),
+		},
+	}
+}
+
+// timestampToProto leaves unset times out of the message
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timestampFromProto(timestamp *timestamppb.Timestamp) time.Time {
+	if timestamp == nil {
+		return time.Time{}
+	}
+	return timestamp.AsTime()
+}
@@ -0,0 +1,197 @@
+package messaging_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/messaging/eventspb"
+	"twitter-clone/internal/models"
+	repositories "twitter-clone/internal/repositories/feed"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestProtobufCodec(t *testing.T) {
+	occurredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tweet := models.Tweet{
+		ID:        "tweet-1",
+		Title:     "title",
+		Content:   "content",
+		Tags:      []string{"golang", "news"},
+		CreatedAt: models.MySQLTimestamp{Time: occurredAt.Add(-time.Hour)},
+		User:      models.User{ID: "user-1", FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Picture: "https://example.com/ada.png"},
+	}
+
+	tests := []struct {
+		topic   string
+		event   interface{}
+		decoded interface{}
+	}{
+		{messaging.TweetCreatedTopic, messaging.TweetCreated{Tweet: tweet, OccurredAt: occurredAt}, &messaging.TweetCreated{}},
+		{messaging.TweetUpdatedTopic, messaging.TweetUpdated{OriginalTweet: tweet, NewTweet: models.Tweet{ID: "tweet-1"}, OccurredAt: occurredAt}, &messaging.TweetUpdated{}},
+		{messaging.TweetDeletedTopic, messaging.TweetDeleted{DeletedTweet: tweet, OccurredAt: occurredAt}, &messaging.TweetDeleted{}},
+		{messaging.FeedUpdatedTopic, messaging.FeedUpdated{Name: "golang", OccurredAt: occurredAt}, &messaging.FeedUpdated{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			codec := messaging.ProtobufCodec{}
+			payload, err := codec.Encode(tt.topic, "event-1", occurredAt, tt.event)
+			require.NoError(t, err)
+
+			var envelope eventspb.CloudEvent
+			require.NoError(t, proto.Unmarshal(payload, &envelope))
+			eventType, err := messaging.EventType(tt.topic)
+			require.NoError(t, err)
+			assert.Equal(t, "event-1", envelope.GetId())
+			assert.Equal(t, "1.0", envelope.GetSpecVersion())
+			assert.Equal(t, "/twitter-clone", envelope.GetSource())
+			assert.Equal(t, eventType, envelope.GetType())
+			assert.Equal(t, messaging.EventDataSchema(eventType), envelope.GetAttributes()["dataschema"].GetCeUri())
+			assert.True(t, occurredAt.Equal(envelope.GetAttributes()["time"].GetCeTimestamp().AsTime()))
+
+			require.NoError(t, codec.Decode(payload, tt.topic, tt.decoded))
+			assertSameEvent(t, tt.event, tt.decoded)
+		})
+	}
+}
+
+func TestProtobufCodec_Rejects(t *testing.T) {
+	codec := messaging.ProtobufCodec{}
+	payload, err := codec.Encode(messaging.TweetCreatedTopic, "event-1", time.Now(), messaging.TweetCreated{Tweet: models.Tweet{ID: "tweet-1"}})
+	require.NoError(t, err)
+
+	var deleted messaging.TweetDeleted
+	assert.ErrorIs(t, codec.Decode(payload, messaging.TweetDeletedTopic, &deleted), messaging.ErrUnexpectedEventType)
+
+	var envelope eventspb.CloudEvent
+	require.NoError(t, proto.Unmarshal(payload, &envelope))
+	envelope.Attributes["dataschema"] = &eventspb.CloudEventAttributeValue{Attr: &eventspb.CloudEventAttributeValue_CeUri{CeUri: "urn:twitter-clone:schema:twitter-clone.tweet.created:v2"}}
+	newer, err := proto.Marshal(&envelope)
+	require.NoError(t, err)
+
+	var created messaging.TweetCreated
+	assert.ErrorIs(t, codec.Decode(newer, messaging.TweetCreatedTopic, &created), messaging.ErrUnsupportedEventSchema)
+	assert.Error(t, codec.Decode([]byte("not protobuf"), messaging.TweetCreatedTopic, &created))
+}
+
+func TestDecodeMessage_MixedContentTypes(t *testing.T) {
+	event := messaging.TweetCreated{Tweet: models.Tweet{ID: "tweet-1", Tags: []string{"golang"}}}
+
+	jsonMessage, err := messaging.NewEventMessage(messaging.JSONCodec{}, messaging.TweetCreatedTopic, time.Now(), event)
+	require.NoError(t, err)
+	assert.Equal(t, messaging.JSONContentType, jsonMessage.Metadata.Get(messaging.ContentTypeKey))
+
+	protobufMessage, err := messaging.NewEventMessage(messaging.ProtobufCodec{}, messaging.TweetCreatedTopic, time.Now(), event)
+	require.NoError(t, err)
+	assert.Equal(t, messaging.ProtobufContentType, protobufMessage.Metadata.Get(messaging.ContentTypeKey))
+
+	// Published before the content type was set
+	legacyMessage := message.NewMessage(watermill.NewUUID(), []byte(`{"tweet":{"id":"tweet-1","tags":["golang"]}}`))
+
+	for name, msg := range map[string]*message.Message{"json": jsonMessage, "protobuf": protobufMessage, "legacy": legacyMessage} {
+		var decoded messaging.TweetCreated
+		require.NoError(t, messaging.DecodeMessage(msg, messaging.TweetCreatedTopic, &decoded), name)
+		assert.Equal(t, "tweet-1", decoded.Tweet.ID, name)
+		assert.Equal(t, []string{"golang"}, decoded.Tweet.Tags, name)
+	}
+
+	unknown := message.NewMessage(watermill.NewUUID(), []byte("<tweet/>"))
+	unknown.Metadata.Set(messaging.ContentTypeKey, "application/xml")
+	var decoded messaging.TweetCreated
+	assert.ErrorIs(t, messaging.DecodeMessage(unknown, messaging.TweetCreatedTopic, &decoded), messaging.ErrUnknownContentType)
+}
+
+func TestStoredEventMessage(t *testing.T) {
+	occurredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	event := messaging.TweetDeleted{DeletedTweet: models.Tweet{ID: "tweet-1", Tags: []string{"golang"}}, OccurredAt: occurredAt}
+	stored, err := messaging.JSONCodec{}.Encode(messaging.TweetDeletedTopic, "event-1", occurredAt, event)
+	require.NoError(t, err)
+
+	// JSON events are published as stored
+	msg, err := messaging.StoredEventMessage(messaging.JSONCodec{}, "event-1", messaging.TweetDeletedTopic, stored)
+	require.NoError(t, err)
+	assert.Equal(t, "event-1", msg.UUID)
+	assert.Equal(t, stored, []byte(msg.Payload))
+	assert.Equal(t, messaging.JSONContentType, msg.Metadata.Get(messaging.ContentTypeKey))
+
+	// Other codecs encode them again, also the bare events stored before the envelope
+	legacy := []byte(`{"original_tweet":{"id":"tweet-1","tags":["golang"]},"occurred_at":"2024-05-01T12:00:00Z"}`)
+	for _, payload := range [][]byte{stored, legacy} {
+		msg, err = messaging.StoredEventMessage(messaging.ProtobufCodec{}, "event-1", messaging.TweetDeletedTopic, payload)
+		require.NoError(t, err)
+		assert.Equal(t, "event-1", msg.UUID)
+		assert.Equal(t, messaging.ProtobufContentType, msg.Metadata.Get(messaging.ContentTypeKey))
+
+		var envelope eventspb.CloudEvent
+		require.NoError(t, proto.Unmarshal(msg.Payload, &envelope))
+		assert.Equal(t, "event-1", envelope.GetId())
+
+		var decoded messaging.TweetDeleted
+		require.NoError(t, messaging.DecodeMessage(msg, messaging.TweetDeletedTopic, &decoded))
+		assertSameEvent(t, &event, &decoded)
+	}
+}
+
+func TestNewEventCodec(t *testing.T) {
+	for encoding, expected := range map[string]string{"": messaging.JSONContentType, config.JSONEncoding: messaging.JSONContentType, config.ProtobufEncoding: messaging.ProtobufContentType} {
+		codec, err := messaging.NewEventCodec(encoding)
+		require.NoError(t, err)
+		assert.Equal(t, expected, codec.ContentType())
+	}
+
+	_, err := messaging.NewEventCodec("xml")
+	assert.Error(t, err)
+}
+
+func TestInMemoryMessageHandler_ProtobufEncoding(t *testing.T) {
+	feedRepo := &repositories.InMemoryFeedRepository{}
+	configuration := config.Configuration{}
+	configuration.Messaging.Encoding = config.ProtobufEncoding
+
+	handler := messaging.InMemoryMessageHandler{}
+	pub, sub, err := handler.SetupMessageRouter(configuration, feedRepo, nil, watermill.NewStdLogger(false, false))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	feedUpdates, err := sub.Subscribe(ctx, messaging.FeedUpdatedTopic)
+	require.NoError(t, err)
+
+	msg, err := messaging.NewEventMessage(messaging.ProtobufCodec{}, messaging.TweetCreatedTopic, time.Now(), messaging.TweetCreated{Tweet: models.Tweet{ID: "tweet1", Tags: []string{"golang"}}})
+	require.NoError(t, err)
+	require.NoError(t, pub.Publish(messaging.TweetCreatedTopic, msg))
+
+	select {
+	case msg := <-feedUpdates:
+		msg.Ack()
+		assert.Equal(t, messaging.ProtobufContentType, msg.Metadata.Get(messaging.ContentTypeKey), "Feed updates should use the configured encoding")
+
+		var feedUpdated messaging.FeedUpdated
+		require.NoError(t, messaging.DecodeMessage(msg, messaging.FeedUpdatedTopic, &feedUpdated))
+		assert.Equal(t, "golang", feedUpdated.Name)
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for FeedUpdated event")
+	}
+
+	feed, err := feedRepo.GetFeedByName(context.Background(), "golang")
+	require.NoError(t, err)
+	assert.Len(t, feed.Tweets, 1)
+}
+
+// assertSameEvent compares events by their JSON, so values and pointers compare alike
+func assertSameEvent(t *testing.T, expected interface{}, actual interface{}) {
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(actual)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
@@ -17,6 +17,7 @@ type PubSubMessageHandler struct {
 func (n *PubSubMessageHandler) SetupMessageRouter(
 	configuration config.Configuration,
 	feedRepo repositories.FeedRepository,
+	deadLetters DeadLetterStore,
 	logger watermill.LoggerAdapter,
 ) (message.Publisher, message.Subscriber, error) {
 	router, err := message.NewRouter(message.RouterConfig{}, logger)
@@ -55,28 +56,9 @@ func (n *PubSubMessageHandler) SetupMessageRouter(
 		return nil, nil, err
 	}
 
-	// Add handler to process incoming messages
-	router.AddHandler(
-		UpdateFeedsOnNewTweetCreated,
-		TweetCreatedTopic,
-		routerSub,
-		FeedUpdatedTopic,
-		pub,
-		func(msg *message.Message) (messages []*message.Message, err error) {
-			return TweetCreatedHandler(msg, feedRepo, logger)
-		},
-	)
-
-	router.AddHandler(
-		UpdateFeedsOnTweetDeleted,
-		TweetDeletedTopic,
-		routerSub,
-		FeedUpdatedTopic,
-		pub,
-		func(msg *message.Message) (messages []*message.Message, err error) {
-			return TweetDeletedHandler(msg, feedRepo, logger)
-		},
-	)
+	if err := addFeedHandlers(router, routerSub, pub, feedRepo, deadLetters, configuration.Messaging, logger); err != nil {
+		return nil, nil, err
+	}
 
 	go func() {
 		err = router.Run(context.Background())
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	ScopeTweetsWrite  = "tweets:write"
+	ScopeTweetsDelete = "tweets:delete"
+)
+
+var APITokenScopes = []string{ScopeTweetsWrite, ScopeTweetsDelete}
+
+// APIToken is a personal access token used by bots and scripts in place of an ID token.
+// Only the SHA-256 hash of the token is stored.
+type APIToken struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"` // Empty grants everything the user may do
+	User      User       `json:"-"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (token APIToken) IsExpired(now time.Time) bool {
+	return token.ExpiresAt != nil && !now.Before(*token.ExpiresAt)
+}
+
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // Zero creates a token which does not expire
+}
+
+// CreatedAPIToken is returned only once, the plain token cannot be read back afterwards
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DeadLetter is a feed update which still failed after all retries, kept until it is replayed
+type DeadLetter struct {
+	ID       string            `json:"id"`
+	Topic    string            `json:"topic"`
+	Handler  string            `json:"handler"`
+	Reason   string            `json:"reason"`
+	Payload  string            `json:"payload"`
+	Metadata map[string]string `json:"metadata"`
+	FailedAt time.Time         `json:"failed_at"`
+}
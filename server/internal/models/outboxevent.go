@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// OutboxEvent is a tweet event stored with the change which caused it, until it is published
+type OutboxEvent struct {
+	ID        string // Published as the message id, so brokers can deduplicate resent events
+	Topic     string
+	Payload   []byte
+	CreatedAt time.Time
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageRequest selects a page of tweets ordered from newest to oldest.
+// A nil Cursor selects the first page.
+type PageRequest struct {
+	Limit  int
+	Cursor *Cursor
+}
+
+// Cursor points at the last tweet of the previous page
+type Cursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+type TweetsPage struct {
+	Tweets     []Tweet `json:"tweets"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+type FeedPage struct {
+	Name       string  `json:"name"`
+	Tweets     []Tweet `json:"tweets"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+func CursorOf(tweet Tweet) Cursor {
+	return Cursor{
+		CreatedAt: tweet.CreatedAt.Time,
+		ID:        tweet.ID,
+	}
+}
+
+// EncodeCursor returns an opaque representation of the cursor to be handed out to clients
+func EncodeCursor(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// DecodeCursor parses a cursor previously produced by EncodeCursor
+func DecodeCursor(encoded string) (*Cursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// Follows reports whether the tweet comes after the cursor in newest-first order
+func (cursor Cursor) Follows(tweet Tweet) bool {
+	if tweet.CreatedAt.Time.Equal(cursor.CreatedAt) {
+		return tweet.ID < cursor.ID
+	}
+	return tweet.CreatedAt.Time.Before(cursor.CreatedAt)
+}
+
+// SortTweetsNewestFirst orders tweets by creation time and ID, both descending
+func SortTweetsNewestFirst(tweets []Tweet) {
+	slices.SortStableFunc(tweets, func(a, b Tweet) int {
+		if c := b.CreatedAt.Time.Compare(a.CreatedAt.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+}
+
+// PaginateTweets returns the requested page of already sorted tweets and the cursor of the next page
+func PaginateTweets(tweets []Tweet, page PageRequest) ([]Tweet, string) {
+	start := 0
+	if page.Cursor != nil {
+		start = slices.IndexFunc(tweets, page.Cursor.Follows)
+		if start == -1 {
+			return []Tweet{}, ""
+		}
+	}
+
+	end := min(start+page.Limit+1, len(tweets))
+	return TrimPage(slices.Clone(tweets[start:end]), page.Limit)
+}
+
+// TrimPage cuts a result set fetched with limit+1 rows down to the limit.
+// The extra row only signals that another page exists.
+func TrimPage(tweets []Tweet, limit int) ([]Tweet, string) {
+	if tweets == nil {
+		tweets = []Tweet{}
+	}
+
+	if len(tweets) <= limit {
+		return tweets, ""
+	}
+
+	tweets = tweets[:limit]
+	return tweets, EncodeCursor(CursorOf(tweets[limit-1]))
+}
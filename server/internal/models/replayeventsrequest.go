@@ -0,0 +1,14 @@
+package models
+
+// ReplayEventsRequest selects the stored events to publish again, a To of 0 replays up to the
+// latest event and an empty Topic publishes every event to its original topic
+type ReplayEventsRequest struct {
+	From  int64  `json:"from"`
+	To    int64  `json:"to"`
+	Topic string `json:"topic"`
+}
+
+// ReplayedEvents reports how many events were published again
+type ReplayedEvents struct {
+	Replayed int `json:"replayed"`
+}
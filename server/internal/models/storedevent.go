@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// StoredEvent is a published tweet event kept in the event store
+type StoredEvent struct {
+	Sequence  int64     `json:"sequence"` // Position in the event store, increasing with every append
+	ID        string    `json:"id"`
+	Topic     string    `json:"topic"`
+	Payload   []byte    `json:"payload"`
+	CreatedAt time.Time `json:"created_at"` // When the tweet change happened
+}
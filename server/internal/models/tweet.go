@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"slices"
 	"time"
 )
 
@@ -13,6 +14,9 @@ type MySQLTimestamp struct {
 // Scan implements the sql.Scanner interface
 func (t *MySQLTimestamp) Scan(value interface{}) error {
 	switch v := value.(type) {
+	case time.Time:
+		// Drivers such as postgres already return a time
+		t.Time = v
 	case []uint8:
 		// Parse the []uint8 value into a time.Time
 		parseTime, err := time.Parse("2006-01-02 15:04:05", string(v))
@@ -34,3 +38,9 @@ type Tweet struct {
 	CreatedAt MySQLTimestamp `json:"created_at" bson:"created_at"`
 	User      User           `json:"user" bson:"user"`
 }
+
+// Clone returns a copy of the tweet which does not share its tags with the original
+func (tweet Tweet) Clone() Tweet {
+	tweet.Tags = slices.Clone(tweet.Tags)
+	return tweet
+}
@@ -0,0 +1,7 @@
+package models
+
+type UpdateTweetRequest struct {
+	Title   string   `json:"title" bson:"title"`
+	Content string   `json:"content" bson:"content"`
+	Tags    []string `json:"tags" bson:"tags"`
+}
@@ -2,8 +2,13 @@ package models
 
 type User struct {
 	IsAnonymous bool   `json:"-"`
+	ID          string `json:"id"` // Stable subject identifier issued by the identity provider
 	FirstName   string `json:"firstName"`
 	LastName    string `json:"lastName"`
 	Email       string `json:"email"`
 	Picture     string `json:"picture"`
+
+	// Set when the request authenticated with a personal API token, never stored with tweets
+	APITokenID string   `json:"-" bson:"-" firestore:"-"`
+	Scopes     []string `json:"-" bson:"-" firestore:"-"`
 }
@@ -0,0 +1,148 @@
+// Package outbox forwards the tweet events recorded by the tweets storage to the message broker
+package outbox
+
+import (
+	"context"
+	"time"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+)
+
+// Store holds the events written together with the tweet changes, oldest first
+type Store interface {
+	// ForwardOutboxEvents passes up to limit of the oldest events to forward and deletes the ids it
+	// returns, also when it fails. No events are passed while another relay forwards older ones.
+	ForwardOutboxEvents(ctx context.Context, limit int, forward func(events []models.OutboxEvent) ([]string, error)) error
+}
+
+// History records the forwarded events before they are published. The mysql and postgres stores
+// lock the events meanwhile, so the relays of all replicas append one after the other and the
+// sequences of the history follow the order in which the appends commit.
+type History interface {
+	AppendEvents(ctx context.Context, events []models.OutboxEvent) error
+}
+
+// RelayHandler names the relay as the handler of the outbox events it moves to the poison topic
+const RelayHandler = "outbox-relay"
+
+// Relay publishes the pending events and deletes them afterwards. An event is published again
+// when it could not be deleted, or with the storages which do not lock the forwarded events when
+// another replica forwards it at the same time, so delivery is at least once. Events keep their id
+// as message id, which lets brokers drop such duplicates.
+type Relay struct {
+	store     Store
+	history   History
+	publisher message.Publisher
+	codec     messaging.EventCodec
+	settings  config.Outbox
+	logger    watermill.LoggerAdapter
+	notify    chan struct{}
+}
+
+func NewRelay(store Store, history History, publisher message.Publisher, codec messaging.EventCodec, settings config.Outbox, logger watermill.LoggerAdapter) *Relay {
+	if settings.PollInterval.Duration <= 0 {
+		settings.PollInterval.Duration = 5 * time.Second
+	}
+	if settings.BatchSize <= 0 {
+		settings.BatchSize = 100
+	}
+
+	return &Relay{
+		store:     store,
+		history:   history,
+		publisher: publisher,
+		codec:     codec,
+		settings:  settings,
+		logger:    logger,
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes the relay up after events were recorded, without waiting for the next poll
+func (relay *Relay) Notify() {
+	select {
+	case relay.notify <- struct{}{}:
+	default:
+		// A wake up is already pending
+	}
+}
+
+// Run forwards the events until the context is done
+func (relay *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.settings.PollInterval.Duration)
+	defer ticker.Stop()
+
+	for {
+		if err := relay.Forward(ctx); err != nil {
+			relay.logger.Error("Error while forwarding outbox events, retrying on the next poll", err, nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-relay.notify:
+		}
+	}
+}
+
+// Forward publishes the pending events in order until none are left. Publishing stops
+// at the first failure, so later events are never published before earlier ones. Events which
+// cannot be encoded would block the outbox forever, they are moved to the poison topic instead.
+func (relay *Relay) Forward(ctx context.Context) error {
+	for {
+		passed := 0
+		err := relay.store.ForwardOutboxEvents(ctx, relay.settings.BatchSize, func(events []models.OutboxEvent) ([]string, error) {
+			passed = len(events)
+			return relay.publish(ctx, events)
+		})
+		if err != nil {
+			return err
+		}
+
+		if passed < relay.settings.BatchSize {
+			return nil
+		}
+	}
+}
+
+// publish returns the ids of the events published before the first failure
+func (relay *Relay) publish(ctx context.Context, events []models.OutboxEvent) ([]string, error) {
+	// Recorded first, so the history holds every published event
+	if err := relay.history.AppendEvents(ctx, events); err != nil {
+		return nil, err
+	}
+
+	published := make([]string, 0, len(events))
+	for _, event := range events {
+		topic := event.Topic
+		msg, err := messaging.StoredEventMessage(relay.codec, event.ID, event.Topic, event.Payload)
+		if err != nil {
+			relay.logger.Error("Outbox event cannot be encoded, moving it to the poison topic", err, watermill.LogFields{"id": event.ID, "topic": event.Topic})
+			topic, msg = messaging.PoisonTopic, poisonMessage(event, err)
+		}
+
+		if err := relay.publisher.Publish(topic, msg); err != nil {
+			return published, err
+		}
+		published = append(published, event.ID)
+	}
+
+	return published, nil
+}
+
+// poisonMessage carries the stored JSON event with the metadata of the poison queue middleware, so
+// it is kept as a dead letter and can be replayed to its topic
+func poisonMessage(event models.OutboxEvent, err error) *message.Message {
+	msg := message.NewMessage(event.ID, event.Payload)
+	msg.Metadata.Set(messaging.ContentTypeKey, messaging.JSONContentType)
+	msg.Metadata.Set(middleware.PoisonedTopicKey, event.Topic)
+	msg.Metadata.Set(middleware.PoisonedHandlerKey, RelayHandler)
+	msg.Metadata.Set(middleware.ReasonForPoisonedKey, err.Error())
+	return msg
+}
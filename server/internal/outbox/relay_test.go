@@ -0,0 +1,182 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/outbox"
+	repositories "twitter-clone/internal/repositories/tweet"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyPublisher fails the given number of publishes before recording the messages
+type flakyPublisher struct {
+	failures  int
+	published []*message.Message
+	topics    []string
+}
+
+func (p *flakyPublisher) Publish(topic string, messages ...*message.Message) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	for _, msg := range messages {
+		p.topics = append(p.topics, topic)
+		p.published = append(p.published, msg)
+	}
+	return nil
+}
+
+func (p *flakyPublisher) Close() error {
+	return nil
+}
+
+func TestRelay_Forward(t *testing.T) {
+	repo := &repositories.InMemoryTweetRepository{}
+	ctx := context.Background()
+
+	for range 3 {
+		_, err := repo.CreateTweet(ctx, repositories.TestCreateTweetRequest, repositories.TestUser)
+		require.NoError(t, err)
+	}
+	events, err := repo.GetOutboxEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	publisher := &flakyPublisher{failures: 1}
+	history := &repositories.InMemoryEventStore{}
+	relay := outbox.NewRelay(repo, history, publisher, messaging.JSONCodec{}, config.Outbox{BatchSize: 2}, watermill.NopLogger{})
+
+	// The events stay in the outbox while the broker is unavailable
+	assert.Error(t, relay.Forward(ctx))
+	assert.Empty(t, publisher.published)
+	pending, err := repo.GetOutboxEvents(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 3)
+
+	// All batches are forwarded in order once the broker is back
+	require.NoError(t, relay.Forward(ctx))
+	require.Len(t, publisher.published, 3)
+	for i, event := range events {
+		assert.Equal(t, event.ID, publisher.published[i].UUID, "Messages should keep the event id")
+		assert.Equal(t, event.Payload, []byte(publisher.published[i].Payload))
+		assert.Equal(t, messaging.TweetCreatedTopic, publisher.topics[i])
+	}
+
+	pending, err = repo.GetOutboxEvents(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "Forwarded events should be deleted")
+
+	// Events sent again after the failure are recorded once
+	recorded, err := history.GetEvents(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, recorded, 3)
+	for i, event := range events {
+		assert.Equal(t, int64(i+1), recorded[i].Sequence)
+		assert.Equal(t, event.ID, recorded[i].ID)
+	}
+}
+
+func TestRelay_ProtobufEncoding(t *testing.T) {
+	repo := &repositories.InMemoryTweetRepository{}
+	ctx := context.Background()
+
+	tweet, err := repo.CreateTweet(ctx, repositories.TestCreateTweetRequest, repositories.TestUser)
+	require.NoError(t, err)
+
+	publisher := &flakyPublisher{}
+	history := &repositories.InMemoryEventStore{}
+	relay := outbox.NewRelay(repo, history, publisher, messaging.ProtobufCodec{}, config.Outbox{}, watermill.NopLogger{})
+	require.NoError(t, relay.Forward(ctx))
+
+	require.Len(t, publisher.published, 1)
+	msg := publisher.published[0]
+	assert.Equal(t, messaging.ProtobufContentType, msg.Metadata.Get(messaging.ContentTypeKey))
+
+	var created messaging.TweetCreated
+	require.NoError(t, messaging.DecodeMessage(msg, messaging.TweetCreatedTopic, &created))
+	assert.Equal(t, tweet.ID, created.Tweet.ID)
+
+	// The history keeps the JSON event
+	recorded, err := history.GetEvents(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, recorded, 1)
+	assert.Equal(t, msg.UUID, recorded[0].ID)
+	assert.Contains(t, string(recorded[0].Payload), tweet.ID)
+}
+
+func TestRelay_Notify(t *testing.T) {
+	repo := &repositories.InMemoryTweetRepository{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pubSub := gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{})
+	messages, err := pubSub.Subscribe(ctx, messaging.TweetCreatedTopic)
+	require.NoError(t, err)
+
+	// The poll interval is too long for the test, only the notification forwards the event
+	relay := outbox.NewRelay(repo, &repositories.InMemoryEventStore{}, pubSub, messaging.JSONCodec{}, config.Outbox{PollInterval: config.Duration{Duration: time.Hour}}, watermill.NopLogger{})
+	go relay.Run(ctx)
+
+	tweet, err := repo.CreateTweet(ctx, repositories.TestCreateTweetRequest, repositories.TestUser)
+	require.NoError(t, err)
+	relay.Notify()
+
+	select {
+	case msg := <-messages:
+		msg.Ack()
+		assert.Contains(t, string(msg.Payload), tweet.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the event to be forwarded after the notification")
+	}
+}
+
+// outboxStore passes its events to forward without locking them
+type outboxStore struct {
+	events []models.OutboxEvent
+}
+
+func (store *outboxStore) ForwardOutboxEvents(ctx context.Context, limit int, forward func(events []models.OutboxEvent) ([]string, error)) error {
+	forwarded, err := forward(store.events[:min(limit, len(store.events))])
+	store.events = slices.DeleteFunc(store.events, func(event models.OutboxEvent) bool {
+		return slices.Contains(forwarded, event.ID)
+	})
+	return err
+}
+
+func TestRelay_PoisonsEventsWhichCannotBeEncoded(t *testing.T) {
+	ctx := context.Background()
+
+	first, err := repositories.TweetCreatedOutboxEvent(models.Tweet{ID: "first"})
+	require.NoError(t, err)
+	last, err := repositories.TweetCreatedOutboxEvent(models.Tweet{ID: "last"})
+	require.NoError(t, err)
+	broken := models.OutboxEvent{ID: "broken", Topic: messaging.TweetCreatedTopic, Payload: []byte("{")}
+	store := &outboxStore{events: []models.OutboxEvent{first, broken, last}}
+
+	publisher := &flakyPublisher{}
+	relay := outbox.NewRelay(store, &repositories.InMemoryEventStore{}, publisher, messaging.ProtobufCodec{}, config.Outbox{}, watermill.NopLogger{})
+	require.NoError(t, relay.Forward(ctx))
+
+	// The broken event does not hold the later ones back
+	assert.Empty(t, store.events)
+	assert.Equal(t, []string{messaging.TweetCreatedTopic, messaging.PoisonTopic, messaging.TweetCreatedTopic}, publisher.topics)
+
+	poisoned := messaging.NewDeadLetter(publisher.published[1])
+	assert.Equal(t, "broken", poisoned.ID)
+	assert.Equal(t, messaging.TweetCreatedTopic, poisoned.Topic)
+	assert.Equal(t, outbox.RelayHandler, poisoned.Handler)
+	assert.NotEmpty(t, poisoned.Reason)
+	assert.Equal(t, "{", poisoned.Payload)
+}
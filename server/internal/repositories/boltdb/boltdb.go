@@ -0,0 +1,34 @@
+// Package boltdb shares the bbolt database file of the embedded mode between the repositories
+package boltdb
+
+import (
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	mutex     sync.Mutex
+	databases = map[string]*bbolt.DB{}
+)
+
+// Open returns the database stored at path. A bbolt file can only be opened once per process,
+// so every repository of the embedded mode receives the same handle.
+func Open(path string) (*bbolt.DB, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if db, found := databases[path]; found {
+		return db, nil
+	}
+
+	// Fail instead of blocking forever when another process holds the file lock
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	databases[path] = db
+	return db, nil
+}
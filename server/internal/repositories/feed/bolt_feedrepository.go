@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"go.etcd.io/bbolt"
+)
+
+var feedsBucket = []byte("feeds")
+
+// BoltFeedRepository keeps every feed in a nested bucket of a local bbolt file,
+// holding the tweets of the feed as JSON keyed by tweet ID
+type BoltFeedRepository struct {
+	db *bbolt.DB
+}
+
+func NewBoltFeedRepository(db *bbolt.DB) (*BoltFeedRepository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(feedsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltFeedRepository{db: db}, nil
+}
+
+// boltError passes the repository errors returned from transactions through,
+// any other failure is reported as unavailable storage
+func boltError(err error) error {
+	if err == nil || errors.Is(err, repoerrors.ErrNotFound) {
+		return err
+	}
+	return repoerrors.Unavailable(err)
+}
+
+func (repo *BoltFeedRepository) CreateFeed(ctx context.Context, name string) error {
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.Bucket(feedsBucket).CreateBucketIfNotExists([]byte(name))
+		return err
+	})
+	return boltError(err)
+}
+
+// GetFeeds returns the feeds ordered by name
+func (repo *BoltFeedRepository) GetFeeds(ctx context.Context) ([]models.Feed, error) {
+	feeds := []models.Feed{}
+	err := repo.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(feedsBucket).ForEachBucket(func(name []byte) error {
+			feed, err := readFeed(tx, string(name))
+			if err != nil {
+				return err
+			}
+			feeds = append(feeds, feed)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return feeds, nil
+}
+
+func (repo *BoltFeedRepository) GetFeedByName(ctx context.Context, name string) (*models.Feed, error) {
+	var feed models.Feed
+	err := repo.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		feed, err = readFeed(tx, name)
+		return err
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return &feed, nil
+}
+
+func (repo *BoltFeedRepository) GetFeedPage(ctx context.Context, name string, page models.PageRequest) (*models.FeedPage, error) {
+	feed, err := repo.GetFeedByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFeedPage(*feed, page), nil
+}
+
+// AppendTweet adds the tweet to the existing feeds of its tags, a tweet already in a feed is kept as is
+func (repo *BoltFeedRepository) AppendTweet(ctx context.Context, tweet models.Tweet) (bool, error) {
+	encoded, err := json.Marshal(tweet)
+	if err != nil {
+		return false, err
+	}
+
+	appended := false
+	err = repo.db.Update(func(tx *bbolt.Tx) error {
+		for _, tag := range tweet.Tags {
+			feed := tx.Bucket(feedsBucket).Bucket([]byte(tag))
+			if feed == nil || feed.Get([]byte(tweet.ID)) != nil {
+				continue
+			}
+			if err := feed.Put([]byte(tweet.ID), encoded); err != nil {
+				return err
+			}
+			appended = true
+		}
+		return nil
+	})
+	return appended, boltError(err)
+}
+
+func (repo *BoltFeedRepository) DeleteFeed(ctx context.Context, name string) error {
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		err := tx.Bucket(feedsBucket).DeleteBucket([]byte(name))
+		if errors.Is(err, bbolt.ErrBucketNotFound) {
+			return repoerrors.ErrNotFound
+		}
+		return err
+	})
+	return boltError(err)
+}
+
+func (repo *BoltFeedRepository) DeleteTweet(ctx context.Context, deletedTweet models.Tweet) (bool, error) {
+	deleted := false
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		for _, tag := range deletedTweet.Tags {
+			feed := tx.Bucket(feedsBucket).Bucket([]byte(tag))
+			if feed == nil || feed.Get([]byte(deletedTweet.ID)) == nil {
+				continue
+			}
+			if err := feed.Delete([]byte(deletedTweet.ID)); err != nil {
+				return err
+			}
+			deleted = true
+		}
+		return nil
+	})
+	return deleted, boltError(err)
+}
+
+// readFeed decodes the tweets of the feed ordered by creation time, a missing feed yields repoerrors.ErrNotFound
+func readFeed(tx *bbolt.Tx, name string) (models.Feed, error) {
+	bucket := tx.Bucket(feedsBucket).Bucket([]byte(name))
+	if bucket == nil {
+		return models.Feed{}, repoerrors.ErrNotFound
+	}
+
+	feed := models.Feed{Name: name, Tweets: []models.Tweet{}}
+	err := bucket.ForEach(func(key, value []byte) error {
+		var tweet models.Tweet
+		if err := json.Unmarshal(value, &tweet); err != nil {
+			return err
+		}
+		feed.Tweets = append(feed.Tweets, tweet)
+		return nil
+	})
+	if err != nil {
+		return models.Feed{}, err
+	}
+
+	models.SortTweetsNewestFirst(feed.Tweets)
+	slices.Reverse(feed.Tweets)
+	return feed, nil
+}
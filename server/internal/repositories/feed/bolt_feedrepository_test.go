@@ -0,0 +1,96 @@
+package repositories_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+	"twitter-clone/internal/models"
+	repositories "twitter-clone/internal/repositories/feed"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func newBoltFeedRepository(t *testing.T) *repositories.BoltFeedRepository {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "feeds.db"), 0600, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := repositories.NewBoltFeedRepository(db)
+	require.NoError(t, err)
+	return repo
+}
+
+func TestBoltFeedRepository_CreateFeed(t *testing.T) {
+	repo := newBoltFeedRepository(t)
+	ctx := context.Background()
+
+	// Creating the same feed twice is not an error
+	assert.NoError(t, repo.CreateFeed(ctx, "testFeed"))
+	assert.NoError(t, repo.CreateFeed(ctx, "testFeed"))
+
+	feeds, err := repo.GetFeeds(ctx)
+	require.NoError(t, err)
+	require.Len(t, feeds, 1)
+	assert.Equal(t, "testFeed", feeds[0].Name)
+	assert.Empty(t, feeds[0].Tweets)
+
+	// Test DeleteFeed
+	assert.NoError(t, repo.DeleteFeed(ctx, "testFeed"))
+	assert.ErrorIs(t, repo.DeleteFeed(ctx, "testFeed"), repoerrors.ErrNotFound)
+	_, err = repo.GetFeedByName(ctx, "testFeed")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound)
+}
+
+func TestBoltFeedRepository_AppendAndDeleteTweet(t *testing.T) {
+	repo := newBoltFeedRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateFeed(ctx, "golang"))
+
+	createdAt := time.Now()
+	for i, id := range []string{"1", "2", "3"} {
+		tweet := models.Tweet{
+			ID:        id,
+			Content:   "content " + id,
+			Tags:      []string{"golang", "rust"},
+			CreatedAt: models.MySQLTimestamp{Time: createdAt.Add(time.Duration(i) * time.Minute)},
+		}
+		appended, err := repo.AppendTweet(ctx, tweet)
+		require.NoError(t, err)
+		assert.True(t, appended)
+		// Appending twice keeps a single copy
+		appended, err = repo.AppendTweet(ctx, tweet)
+		require.NoError(t, err)
+		assert.False(t, appended, "Appending a tweet twice should not change the feed")
+	}
+
+	// Tweets are only added to existing feeds
+	_, err := repo.GetFeedByName(ctx, "rust")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound)
+
+	feed, err := repo.GetFeedByName(ctx, "golang")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, tweetIDs(feed.Tweets))
+	assert.Equal(t, "content 1", feed.Tweets[0].Content)
+
+	feedPage, err := repo.GetFeedPage(ctx, "golang", models.PageRequest{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "2"}, tweetIDs(feedPage.Tweets))
+	assert.NotEmpty(t, feedPage.NextCursor)
+
+	// Test DeleteTweet
+	deleted, err := repo.DeleteTweet(ctx, models.Tweet{ID: "2", Tags: []string{"golang", "rust"}})
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	feed, err = repo.GetFeedByName(ctx, "golang")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "3"}, tweetIDs(feed.Tweets))
+}
+
+func TestBoltFeedRepository_AppendTweetIsIdempotent(t *testing.T) {
+	testAppendTweetIsIdempotent(t, newBoltFeedRepository(t))
+}
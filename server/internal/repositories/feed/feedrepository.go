@@ -1,12 +1,20 @@
 package repositories
 
-import "twitter-clone/internal/models"
+import (
+	"context"
+	"twitter-clone/internal/models"
+)
 
+// FeedRepository returns repoerrors.ErrNotFound for missing feeds and wraps
+// storage failures with repoerrors.ErrUnavailable. Creating an existing feed
+// and removing a tweet which is not indexed are not errors. AppendTweet and
+// DeleteTweet report whether they changed any feed, a redelivered event changes none.
 type FeedRepository interface {
-	CreateFeed(name string) error
-	GetFeeds() ([]models.Feed, error)
-	GetFeedByName(name string) (*models.Feed, error)
-	AppendTweet(tweet models.Tweet) error
-	DeleteFeed(name string) bool
-	DeleteTweet(deletedTweet models.Tweet) bool
+	CreateFeed(ctx context.Context, name string) error
+	GetFeeds(ctx context.Context) ([]models.Feed, error)
+	GetFeedByName(ctx context.Context, name string) (*models.Feed, error)
+	GetFeedPage(ctx context.Context, name string, page models.PageRequest) (*models.FeedPage, error)
+	AppendTweet(ctx context.Context, tweet models.Tweet) (bool, error)
+	DeleteFeed(ctx context.Context, name string) error
+	DeleteTweet(ctx context.Context, deletedTweet models.Tweet) (bool, error)
 }
@@ -0,0 +1,93 @@
+package repositories_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"twitter-clone/internal/models"
+	repositories "twitter-clone/internal/repositories/feed"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAppendTweetIsIdempotent checks that redelivered tweets are kept once in every feed of any repository,
+// and that only the first delivery is reported as a change
+func testAppendTweetIsIdempotent(t *testing.T, repo repositories.FeedRepository) {
+	ctx := context.Background()
+
+	feedNames := []string{"IdempotentNews", "IdempotentGolang"}
+	for _, name := range feedNames {
+		require.NoError(t, repo.CreateFeed(ctx, name))
+	}
+	t.Cleanup(func() {
+		for _, name := range feedNames {
+			repo.DeleteFeed(ctx, name)
+		}
+	})
+
+	tweet := models.Tweet{
+		ID:        "idempotent-1",
+		Content:   "content",
+		Tags:      feedNames,
+		CreatedAt: models.MySQLTimestamp{Time: time.Now()},
+		User:      models.User{ID: "alice", Email: "alice@gmail.com"},
+	}
+	for i := range 3 {
+		appended, err := repo.AppendTweet(ctx, tweet)
+		require.NoError(t, err)
+		assert.Equal(t, i == 0, appended, "Only the first append should change the feeds")
+	}
+
+	// Concurrent redeliveries, as handled by several replicas
+	concurrentTweet := tweet
+	concurrentTweet.ID = "idempotent-2"
+	concurrentTweet.CreatedAt = models.MySQLTimestamp{Time: tweet.CreatedAt.Add(time.Minute)}
+	var wg sync.WaitGroup
+	var appends atomic.Int32
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			appended, err := repo.AppendTweet(ctx, concurrentTweet)
+			assert.NoError(t, err)
+			if appended {
+				appends.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Positive(t, appends.Load(), "A concurrent append should change the feeds")
+
+	// The first copy of a tweet is kept
+	modifiedTweet := tweet
+	modifiedTweet.Content = "modified"
+	appended, err := repo.AppendTweet(ctx, modifiedTweet)
+	require.NoError(t, err)
+	assert.False(t, appended, "A tweet already in the feeds should not change them")
+
+	for _, name := range feedNames {
+		feed, err := repo.GetFeedByName(ctx, name)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"idempotent-1", "idempotent-2"}, tweetIDs(feed.Tweets), "Feed %s should hold each tweet once", name)
+		for _, feedTweet := range feed.Tweets {
+			assert.Equal(t, "content", feedTweet.Content)
+		}
+	}
+
+	// A deleted tweet is reported once and can be appended again
+	deleted, err := repo.DeleteTweet(ctx, tweet)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.DeleteTweet(ctx, tweet)
+	require.NoError(t, err)
+	assert.False(t, deleted, "Deleting a removed tweet should not change the feeds")
+	appended, err = repo.AppendTweet(ctx, tweet)
+	require.NoError(t, err)
+	assert.True(t, appended)
+	feed, err := repo.GetFeedByName(ctx, feedNames[0])
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"idempotent-1", "idempotent-2"}, tweetIDs(feed.Tweets))
+}
@@ -2,9 +2,11 @@ package repositories
 
 import (
 	"context"
+	"slices"
 	"time"
 	"twitter-clone/internal/config"
 	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
@@ -26,9 +28,21 @@ func NewFirestoreFeedRepository(configuration config.Configuration) (*FirestoreF
 	return &FirestoreFeedRepository{client: client}, nil
 }
 
-func (r *FirestoreFeedRepository) CreateFeed(name string) error {
-	ctx := context.Background()
+// firestoreError translates the gRPC status of a failed Firestore call into the repository errors
+func firestoreError(err error) error {
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.NotFound:
+		return repoerrors.ErrNotFound
+	case codes.AlreadyExists:
+		return repoerrors.ErrConflict
+	default:
+		return repoerrors.Unavailable(err)
+	}
+}
 
+func (r *FirestoreFeedRepository) CreateFeed(ctx context.Context, name string) error {
 	// Reference to the feed document for the given name
 	feedDocRef := r.client.Collection("feeds").Doc(name)
 
@@ -36,7 +50,7 @@ func (r *FirestoreFeedRepository) CreateFeed(name string) error {
 	docSnapshot, err := feedDocRef.Get(ctx)
 	if err != nil && status.Code(err) != codes.NotFound {
 		// If the error is not 'NotFound', something else went wrong
-		return err
+		return firestoreError(err)
 	}
 
 	// If the document already exists, we don't create it again
@@ -49,69 +63,76 @@ func (r *FirestoreFeedRepository) CreateFeed(name string) error {
 		"name":       name,
 		"created_at": time.Now(),
 	})
-	return err
+	return firestoreError(err)
 }
 
-func (r *FirestoreFeedRepository) GetFeeds() ([]models.Feed, error) {
+func (r *FirestoreFeedRepository) GetFeeds(ctx context.Context) ([]models.Feed, error) {
 	var feeds []models.Feed
-	iter := r.client.Collection("feeds").Documents(context.Background())
+	iter := r.client.Collection("feeds").Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, firestoreError(err)
 		}
 		var feed models.Feed
 		if err := doc.DataTo(&feed); err != nil {
-			return nil, err
+			return nil, repoerrors.Unavailable(err)
 		}
 		feeds = append(feeds, feed)
 	}
 	return feeds, nil
 }
 
-func (r *FirestoreFeedRepository) GetFeedByName(name string) (*models.Feed, error) {
-	doc, err := r.client.Collection("feeds").Doc(name).Get(context.Background())
+func (r *FirestoreFeedRepository) GetFeedByName(ctx context.Context, name string) (*models.Feed, error) {
+	doc, err := r.client.Collection("feeds").Doc(name).Get(ctx)
 	if err != nil {
-		return nil, err
+		return nil, firestoreError(err)
 	}
 	var feed models.Feed
 	if err := doc.DataTo(&feed); err != nil {
-		return nil, err
+		return nil, repoerrors.Unavailable(err)
 	}
 	return &feed, nil
 }
 
-func (r *FirestoreFeedRepository) AppendTweet(tweet models.Tweet) error {
-	ctx := context.Background()
+// GetFeedPage paginates in memory, Firestore cannot query inside the embedded tweets array
+func (r *FirestoreFeedRepository) GetFeedPage(ctx context.Context, name string, page models.PageRequest) (*models.FeedPage, error) {
+	feed, err := r.GetFeedByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFeedPage(*feed, page), nil
+}
 
+// AppendTweet adds the tweet to the existing feeds of its tags, a tweet already in a feed is kept as is
+func (r *FirestoreFeedRepository) AppendTweet(ctx context.Context, tweet models.Tweet) (bool, error) {
 	// If there are no tags, there's no feed to append the tweet to.
 	if len(tweet.Tags) == 0 {
-		return nil
+		return false, nil
 	}
 
+	appended := false
 	// Loop over each tag and update the corresponding feed document.
 	for _, tag := range tweet.Tags {
 		feedDocRef := r.client.Collection("feeds").Doc(tag)
 
 		// Run a Firestore transaction to safely check and append the tweet.
+		// The transaction may be retried, so it only reports the append of its last attempt.
+		feedAppended := false
 		err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			feedAppended = false
 			// Check if the feed document exists.
 			doc, err := tx.Get(feedDocRef)
 			if err != nil {
 				if status.Code(err) == codes.NotFound {
-					// Document does not exist, so we initialize it with an empty tweets array.
-					err := tx.Set(feedDocRef, map[string]interface{}{
-						"tweets": []models.Tweet{},
-					})
-					if err != nil {
-						return err
-					}
-				} else {
-					return err // Return any other error.
+					// Feeds are created before tweets are appended, a missing feed is skipped.
+					return nil
 				}
+				return err // Return any other error.
 			}
 
 			var feedData struct {
@@ -123,45 +144,50 @@ func (r *FirestoreFeedRepository) AppendTweet(tweet models.Tweet) error {
 				return err
 			}
 
-			updatedTweets := append(feedData.Tweets, tweet)
+			// A redelivered event must not add the tweet twice.
+			if slices.ContainsFunc(feedData.Tweets, func(t models.Tweet) bool { return t.ID == tweet.ID }) {
+				return nil
+			}
 
-			// Append the tweet to the tweets array using ArrayUnion to avoid duplicates.
-			err = tx.Update(feedDocRef, []firestore.Update{
+			feedAppended = true
+			return tx.Update(feedDocRef, []firestore.Update{
 				{
 					Path:  "tweets",
-					Value: updatedTweets,
+					Value: append(feedData.Tweets, tweet),
 				},
 			})
-			return err
 		})
 
 		if err != nil {
-			return err // Return if there's an error in any transaction.
+			return appended, firestoreError(err) // Return if there's an error in any transaction.
 		}
+		appended = appended || feedAppended
 	}
 
-	return nil
+	return appended, nil
 }
 
-func (r *FirestoreFeedRepository) DeleteFeed(name string) bool {
-	_, err := r.client.Collection("feeds").Doc(name).Delete(context.Background())
-	return err == nil
+func (r *FirestoreFeedRepository) DeleteFeed(ctx context.Context, name string) error {
+	_, err := r.client.Collection("feeds").Doc(name).Delete(ctx, firestore.Exists)
+	return firestoreError(err)
 }
 
-func (r *FirestoreFeedRepository) DeleteTweet(deletedTweet models.Tweet) bool {
-	ctx := context.Background()
-
+func (r *FirestoreFeedRepository) DeleteTweet(ctx context.Context, deletedTweet models.Tweet) (bool, error) {
 	// If there are no tags, there's no feed to delete the tweet from.
 	if len(deletedTweet.Tags) == 0 {
-		return false
+		return false, nil
 	}
 
+	deleted := false
 	// Loop over each tag and update the corresponding feed document.
 	for _, tag := range deletedTweet.Tags {
 		feedDocRef := r.client.Collection("feeds").Doc(tag)
 
 		// Run a Firestore transaction to safely check and delete the tweet.
+		// The transaction may be retried, so it only reports the deletion of its last attempt.
+		feedDeleted := false
 		err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			feedDeleted = false
 			// Check if the feed document exists.
 			doc, err := tx.Get(feedDocRef)
 			if err != nil {
@@ -189,6 +215,10 @@ func (r *FirestoreFeedRepository) DeleteTweet(deletedTweet models.Tweet) bool {
 					updatedTweets = append(updatedTweets, t)
 				}
 			}
+			if len(updatedTweets) == len(feedData.Tweets) {
+				return nil
+			}
+			feedDeleted = true
 
 			// Update the tweets array with the filtered tweets.
 			err = tx.Update(feedDocRef, []firestore.Update{
@@ -201,9 +231,10 @@ func (r *FirestoreFeedRepository) DeleteTweet(deletedTweet models.Tweet) bool {
 		})
 
 		if err != nil {
-			return false // Return if there's an error in any transaction.
+			return deleted, firestoreError(err) // Return if there's an error in any transaction.
 		}
+		deleted = deleted || feedDeleted
 	}
 
-	return true
+	return deleted, nil
 }
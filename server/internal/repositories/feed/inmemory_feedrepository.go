@@ -1,88 +1,131 @@
 package repositories
 
 import (
+	"context"
 	"slices"
+	"strings"
+	"sync"
 	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
 )
 
+// InMemoryFeedRepository is safe for concurrent use. Feeds are indexed by their tag and
+// hold their tweets by ID. Callers always receive copies so a feed returned by
+// GetFeedByName stays valid after the feed is modified or deleted.
 type InMemoryFeedRepository struct {
-	feeds []models.Feed
+	mutex sync.RWMutex
+	feeds map[string]map[string]models.Tweet
 }
 
-func (repo *InMemoryFeedRepository) CreateFeed(name string) error {
-	idx := slices.IndexFunc(repo.feeds, func(f models.Feed) bool { return f.Name == name })
-	if idx != -1 {
+func (repo *InMemoryFeedRepository) CreateFeed(ctx context.Context, name string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.feeds[name]; found {
 		return nil
 	}
 
-	feed := models.Feed{
-		Name:   name,
-		Tweets: []models.Tweet{},
+	if repo.feeds == nil {
+		repo.feeds = map[string]map[string]models.Tweet{}
 	}
-
-	repo.feeds = append(repo.feeds, feed)
+	repo.feeds[name] = map[string]models.Tweet{}
 	return nil
 }
 
-func (repo *InMemoryFeedRepository) GetFeeds() ([]models.Feed, error) {
-	return repo.feeds, nil
+// GetFeeds returns the feeds ordered by name
+func (repo *InMemoryFeedRepository) GetFeeds(ctx context.Context) ([]models.Feed, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	feeds := make([]models.Feed, 0, len(repo.feeds))
+	for name, tweets := range repo.feeds {
+		feeds = append(feeds, newFeed(name, tweets))
+	}
+	slices.SortFunc(feeds, func(a, b models.Feed) int { return strings.Compare(a.Name, b.Name) })
+
+	return feeds, nil
 }
 
-func (repo *InMemoryFeedRepository) GetFeedByName(name string) (*models.Feed, error) {
-	idx := slices.IndexFunc(repo.feeds, func(f models.Feed) bool { return f.Name == name })
-	if idx == -1 {
-		return nil, nil
+func (repo *InMemoryFeedRepository) GetFeedByName(ctx context.Context, name string) (*models.Feed, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	tweets, found := repo.feeds[name]
+	if !found {
+		return nil, repoerrors.ErrNotFound
 	}
 
-	return &repo.feeds[idx], nil
+	feed := newFeed(name, tweets)
+	return &feed, nil
 }
 
-func (repo *InMemoryFeedRepository) AppendTweet(tweet models.Tweet) error {
-	if len(tweet.Tags) == 0 {
-		return nil
+func (repo *InMemoryFeedRepository) GetFeedPage(ctx context.Context, name string, page models.PageRequest) (*models.FeedPage, error) {
+	feed, err := repo.GetFeedByName(ctx, name)
+	if err != nil {
+		return nil, err
 	}
 
-	for idx, _ := range repo.feeds {
-		// Check if the feed should get updated based on new tweet tags
-		if ContainsTag(tweet.Tags, repo.feeds[idx].Name) {
-			// Check if the tweet is not already present in the feed
-			if !ContainsTweet(repo.feeds[idx].Tweets, tweet.ID) {
-				// Add the tweet to the feed
-				repo.feeds[idx].Tweets = append(repo.feeds[idx].Tweets, tweet)
-			}
+	return NewFeedPage(*feed, page), nil
+}
+
+// AppendTweet adds the tweet to the existing feeds of its tags, a tweet already in a feed is kept as is
+func (repo *InMemoryFeedRepository) AppendTweet(ctx context.Context, tweet models.Tweet) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	appended := false
+	for _, tag := range tweet.Tags {
+		tweets, found := repo.feeds[tag]
+		if !found {
+			continue
+		}
+		if _, found := tweets[tweet.ID]; !found {
+			tweets[tweet.ID] = tweet.Clone()
+			appended = true
 		}
 	}
 
-	return nil
+	return appended, nil
 }
 
-func (repo *InMemoryFeedRepository) DeleteFeed(name string) bool {
-	removed := false
-	for i, feed := range repo.feeds {
-		if feed.Name == name {
-			repo.feeds = append(repo.feeds[:i], repo.feeds[i+1:]...)
-			removed = true
-			break
-		}
+func (repo *InMemoryFeedRepository) DeleteFeed(ctx context.Context, name string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.feeds[name]; !found {
+		return repoerrors.ErrNotFound
 	}
 
-	return removed
+	delete(repo.feeds, name)
+	return nil
 }
 
-func (repo *InMemoryFeedRepository) DeleteTweet(deletedTweet models.Tweet) bool {
-	removed := false
+func (repo *InMemoryFeedRepository) DeleteTweet(ctx context.Context, deletedTweet models.Tweet) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	deleted := false
 	for _, tag := range deletedTweet.Tags {
-		feed, err := repo.GetFeedByName(tag)
-		if err == nil {
-			for j, tweet := range feed.Tweets {
-				if tweet.ID == deletedTweet.ID {
-					feed.Tweets = append(feed.Tweets[:j], feed.Tweets[j+1:]...)
-					removed = true
-					break
-				}
-			}
+		if _, found := repo.feeds[tag][deletedTweet.ID]; found {
+			delete(repo.feeds[tag], deletedTweet.ID)
+			deleted = true
 		}
 	}
 
-	return removed
+	return deleted, nil
+}
+
+// newFeed copies the indexed tweets into a feed ordered by creation time, the caller must hold the lock
+func newFeed(name string, tweets map[string]models.Tweet) models.Feed {
+	feed := models.Feed{
+		Name:   name,
+		Tweets: make([]models.Tweet, 0, len(tweets)),
+	}
+	for _, tweet := range tweets {
+		feed.Tweets = append(feed.Tweets, tweet.Clone())
+	}
+	models.SortTweetsNewestFirst(feed.Tweets)
+	slices.Reverse(feed.Tweets)
+
+	return feed
 }
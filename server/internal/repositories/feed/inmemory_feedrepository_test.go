@@ -1,54 +1,63 @@
 package repositories_test
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 	"twitter-clone/internal/models"
 	repositories "twitter-clone/internal/repositories/feed"
+	"twitter-clone/internal/repositories/repoerrors"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInMemoryFeedRepository_CreateFeed(t *testing.T) {
 	repo := repositories.InMemoryFeedRepository{}
+	ctx := context.Background()
 	name := "testFeed"
 
 	// Create a new feed
-	err := repo.CreateFeed(name)
+	err := repo.CreateFeed(ctx, name)
 	assert.NoError(t, err, "Error creating feed")
 
 	// Try to create the same feed again, it should not return an error
-	err = repo.CreateFeed(name)
+	err = repo.CreateFeed(ctx, name)
 	assert.NoError(t, err, "Error creating feed")
 
 	// Verify that the feed exists in the repository
-	feed, err := repo.GetFeedByName(name)
+	feed, err := repo.GetFeedByName(ctx, name)
 	assert.NoError(t, err, "Error getting feed by name")
 	assert.NotNil(t, feed, "Expected feed to exist, but it doesn't.")
 }
 
 func TestInMemoryFeedRepository_GetFeeds(t *testing.T) {
 	repo := repositories.InMemoryFeedRepository{}
+	ctx := context.Background()
 
 	// Get feeds from an empty repository
-	feeds, err := repo.GetFeeds()
+	feeds, err := repo.GetFeeds(ctx)
 	assert.NoError(t, err, "Error getting feeds")
 	assert.Empty(t, feeds, "Expected no feeds, got %d feeds", len(feeds))
 
 	// Create a new feed
-	err = repo.CreateFeed("testFeed")
+	err = repo.CreateFeed(ctx, "testFeed")
 	assert.NoError(t, err, "Error creating feed")
 
 	// Get feeds after creating one
-	feeds, err = repo.GetFeeds()
+	feeds, err = repo.GetFeeds(ctx)
 	assert.NoError(t, err, "Error getting feeds")
 	assert.Len(t, feeds, 1, "Expected 1 feed, got %d feeds", len(feeds))
 }
 
 func TestInMemoryFeedRepository_DeleteTweet(t *testing.T) {
 	repo := repositories.InMemoryFeedRepository{}
+	ctx := context.Background()
 
 	// Create a new feed
-	err := repo.CreateFeed("testFeed")
+	err := repo.CreateFeed(ctx, "testFeed")
 	assert.NoError(t, err, "Error creating feed")
 
 	// Create a new tweet
@@ -56,16 +65,140 @@ func TestInMemoryFeedRepository_DeleteTweet(t *testing.T) {
 		ID:   "1",
 		Tags: []string{"testFeed"},
 	}
-	err = repo.AppendTweet(tweet)
+	_, err = repo.AppendTweet(ctx, tweet)
 	assert.NoError(t, err, "Error appending tweet")
 
 	// Delete the tweet
-	deleted := repo.DeleteTweet(tweet)
-	assert.True(t, deleted, "Expected tweet to be deleted, but it wasn't")
+	deleted, err := repo.DeleteTweet(ctx, tweet)
+	assert.NoError(t, err, "Error deleting tweet")
+	assert.True(t, deleted, "Expected the tweet to be reported as deleted")
 
 	// Verify that the tweet was deleted
-	feed, err := repo.GetFeedByName("testFeed")
+	feed, err := repo.GetFeedByName(ctx, "testFeed")
 	assert.NoError(t, err, "Error getting feed by name")
 	assert.NotNil(t, feed, "Expected feed to exist, but it doesn't.")
 	assert.Empty(t, feed.Tweets, "Expected no tweets, got %d tweets", len(feed.Tweets))
 }
+
+func TestInMemoryFeedRepository_GetFeedPage(t *testing.T) {
+	repo := repositories.InMemoryFeedRepository{}
+	ctx := context.Background()
+
+	// Missing feed yields no page
+	_, err := repo.GetFeedPage(ctx, "testFeed", models.PageRequest{Limit: 2})
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "Expected no page for a missing feed")
+
+	err = repo.CreateFeed(ctx, "testFeed")
+	assert.NoError(t, err, "Error creating feed")
+
+	createdAt := time.Now()
+	for i, id := range []string{"1", "2", "3"} {
+		_, err = repo.AppendTweet(ctx, models.Tweet{
+			ID:        id,
+			Tags:      []string{"testFeed"},
+			CreatedAt: models.MySQLTimestamp{Time: createdAt.Add(time.Duration(i) * time.Minute)},
+		})
+		assert.NoError(t, err, "Error appending tweet")
+	}
+
+	// First page holds the two newest tweets
+	feedPage, err := repo.GetFeedPage(ctx, "testFeed", models.PageRequest{Limit: 2})
+	assert.NoError(t, err, "Error getting feed page")
+	assert.Equal(t, "testFeed", feedPage.Name)
+	assert.Equal(t, []string{"3", "2"}, tweetIDs(feedPage.Tweets))
+	assert.NotEmpty(t, feedPage.NextCursor, "Expected a cursor for the next page")
+
+	// Second page holds the remaining tweet
+	cursor, err := models.DecodeCursor(feedPage.NextCursor)
+	assert.NoError(t, err, "Error decoding cursor")
+
+	feedPage, err = repo.GetFeedPage(ctx, "testFeed", models.PageRequest{Limit: 2, Cursor: cursor})
+	assert.NoError(t, err, "Error getting feed page")
+	assert.Equal(t, []string{"1"}, tweetIDs(feedPage.Tweets))
+	assert.Empty(t, feedPage.NextCursor, "Expected no cursor after the last page")
+}
+
+func tweetIDs(tweets []models.Tweet) []string {
+	ids := []string{}
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.ID)
+	}
+	return ids
+}
+
+func TestInMemoryFeedRepository_ReturnsCopies(t *testing.T) {
+	repo := repositories.InMemoryFeedRepository{}
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateFeed(ctx, "testFeed"))
+	_, err := repo.AppendTweet(ctx, models.Tweet{ID: "1", Tags: []string{"testFeed"}})
+	require.NoError(t, err)
+
+	feed, err := repo.GetFeedByName(ctx, "testFeed")
+	require.NoError(t, err)
+
+	// A retrieved feed stays intact when the stored feed changes or disappears
+	_, err = repo.AppendTweet(ctx, models.Tweet{ID: "2", Tags: []string{"testFeed"}})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteFeed(ctx, "testFeed"))
+	assert.Equal(t, "testFeed", feed.Name)
+	assert.Equal(t, []string{"1"}, tweetIDs(feed.Tweets))
+
+	_, err = repo.GetFeedByName(ctx, "testFeed")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound)
+}
+
+// Run with -race to detect unsynchronized access
+func TestInMemoryFeedRepository_ConcurrentAccess(t *testing.T) {
+	repo := repositories.InMemoryFeedRepository{}
+	ctx := context.Background()
+
+	const workers = 8
+	const tweetsPerWorker = 50
+
+	require.NoError(t, repo.CreateFeed(ctx, "golang"))
+
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workerFeed := fmt.Sprintf("worker%d", worker)
+			assert.NoError(t, repo.CreateFeed(ctx, workerFeed))
+			assert.NoError(t, repo.CreateFeed(ctx, "golang"))
+
+			for i := range tweetsPerWorker {
+				tweet := models.Tweet{
+					ID:        fmt.Sprintf("%s-%d", workerFeed, i),
+					Tags:      []string{"golang", workerFeed},
+					CreatedAt: models.MySQLTimestamp{Time: time.Now()},
+				}
+				_, err := repo.AppendTweet(ctx, tweet)
+				assert.NoError(t, err)
+
+				_, err = repo.GetFeeds(ctx)
+				assert.NoError(t, err)
+				_, err = repo.GetFeedPage(ctx, "golang", models.PageRequest{Limit: 10})
+				assert.NoError(t, err)
+
+				// Delete every other tweet
+				if i%2 == 0 {
+					_, err = repo.DeleteTweet(ctx, tweet)
+					assert.NoError(t, err)
+				}
+			}
+
+			assert.NoError(t, repo.DeleteFeed(ctx, workerFeed))
+		}()
+	}
+	wg.Wait()
+
+	feeds, err := repo.GetFeeds(ctx)
+	require.NoError(t, err)
+	require.Len(t, feeds, 1, "Expected only the shared feed to remain")
+	assert.Len(t, feeds[0].Tweets, workers*tweetsPerWorker/2, "Expected every other tweet to remain")
+}
+
+func TestInMemoryFeedRepository_AppendTweetIsIdempotent(t *testing.T) {
+	testAppendTweetIsIdempotent(t, &repositories.InMemoryFeedRepository{})
+}
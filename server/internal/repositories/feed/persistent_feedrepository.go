@@ -7,6 +7,7 @@ import (
 	"log"
 	"twitter-clone/internal/config"
 	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -62,72 +63,108 @@ func (repo *PersistentFeedRepository) init(configuration config.Configuration) e
 	return nil
 }
 
-func (repo *PersistentFeedRepository) CreateFeed(name string) error {
-	_, err := repo.GetFeedByName(name)
-	if err != nil {
-		return err
-	}
-
+func (repo *PersistentFeedRepository) CreateFeed(ctx context.Context, name string) error {
 	feed := models.Feed{
 		Name:   name,
 		Tweets: []models.Tweet{},
 	}
 
-	insertOneResult, err := repo.feedsCollection.InsertOne(context.Background(), feed)
+	insertOneResult, err := repo.feedsCollection.InsertOne(ctx, feed)
 	if err != nil {
-		if !isDuplicateError(err) {
-			return err
+		// The feed already exists
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
 		}
-		return nil
+		return repoerrors.Unavailable(err)
 	}
 
 	if insertOneResult.InsertedID == nil {
-		return errors.New("failed to insert object")
+		return repoerrors.Unavailable(errors.New("failed to insert object"))
 	}
 
 	return nil
 }
 
-func isDuplicateError(err error) bool {
-	mErr, ok := err.(mongo.WriteException)
-	if !ok {
-		return false
-	}
-
-	return mErr.WriteErrors[0].Code == 11000
-}
-
-func (repo *PersistentFeedRepository) GetFeeds() ([]models.Feed, error) {
+func (repo *PersistentFeedRepository) GetFeeds(ctx context.Context) ([]models.Feed, error) {
 	var feeds []models.Feed
 
-	cursor, err := repo.feedsCollection.Find(context.Background(), bson.D{})
+	cursor, err := repo.feedsCollection.Find(ctx, bson.D{})
 	if err != nil {
-		return feeds, err
+		return nil, repoerrors.Unavailable(err)
 	}
 
-	if err = cursor.All(context.Background(), &feeds); err != nil {
-		panic(err)
+	if err = cursor.All(ctx, &feeds); err != nil {
+		return nil, repoerrors.Unavailable(err)
 	}
 
 	return feeds, nil
 }
 
-func (repo *PersistentFeedRepository) GetFeedByName(name string) (*models.Feed, error) {
+func (repo *PersistentFeedRepository) GetFeedByName(ctx context.Context, name string) (*models.Feed, error) {
 	filter := bson.D{{Key: "_id", Value: name}}
 
 	var result models.Feed
-	var foundResult = repo.feedsCollection.FindOne(context.Background(), filter)
-	if foundResult.Err() == nil {
-		foundResult.Decode(&result)
-		return &result, nil
+	err := repo.feedsCollection.FindOne(ctx, filter).Decode(&result)
+	if err != nil {
+		return nil, mongoError(err)
 	}
 
-	return nil, nil
+	return &result, nil
 }
 
-func (repo *PersistentFeedRepository) AppendTweet(tweet models.Tweet) error {
+// mongoError reports missing documents as repoerrors.ErrNotFound, anything else as unavailable storage
+func mongoError(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return repoerrors.ErrNotFound
+	}
+	return repoerrors.Unavailable(err)
+}
+
+func (repo *PersistentFeedRepository) GetFeedPage(ctx context.Context, name string, page models.PageRequest) (*models.FeedPage, error) {
+	filter := bson.D{{Key: "_id", Value: name}}
+	err := repo.feedsCollection.FindOne(ctx, filter, options.FindOne().SetProjection(bson.D{{Key: "_id", Value: 1}})).Err()
+	if err != nil {
+		return nil, mongoError(err)
+	}
+
+	// MySQLTimestamp is not inlined by the bson codec, hence the nested time field
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$unwind", Value: "$tweets"}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$tweets"}}}},
+	}
+
+	if page.Cursor != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{
+			"$or": bson.A{
+				bson.M{"created_at.time": bson.M{"$lt": page.Cursor.CreatedAt}},
+				bson.M{"created_at.time": page.Cursor.CreatedAt, "id": bson.M{"$lt": page.Cursor.ID}},
+			},
+		}}})
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "created_at.time", Value: -1}, {Key: "id", Value: -1}}}},
+		bson.D{{Key: "$limit", Value: page.Limit + 1}},
+	)
+
+	cursor, err := repo.feedsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, repoerrors.Unavailable(err)
+	}
+
+	var tweets []models.Tweet
+	if err = cursor.All(ctx, &tweets); err != nil {
+		return nil, repoerrors.Unavailable(err)
+	}
+
+	pageTweets, nextCursor := models.TrimPage(tweets, page.Limit)
+	return &models.FeedPage{Name: name, Tweets: pageTweets, NextCursor: nextCursor}, nil
+}
+
+func (repo *PersistentFeedRepository) AppendTweet(ctx context.Context, tweet models.Tweet) (bool, error) {
 	if len(tweet.Tags) == 0 {
-		return nil
+		return false, nil
 	}
 
 	filter := bson.M{
@@ -148,23 +185,31 @@ func (repo *PersistentFeedRepository) AppendTweet(tweet models.Tweet) error {
 		},
 	}
 
-	_, err := repo.feedsCollection.UpdateMany(context.Background(), filter, update)
-	return err
+	// Feeds already holding the tweet are filtered out, so only appends modify documents
+	result, err := repo.feedsCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return false, repoerrors.Unavailable(err)
+	}
+
+	return result.ModifiedCount > 0, nil
 }
 
-func (repo *PersistentFeedRepository) DeleteFeed(name string) bool {
+func (repo *PersistentFeedRepository) DeleteFeed(ctx context.Context, name string) error {
 	filter := bson.D{{Key: "_id", Value: name}}
 
-	deleteResult, err := repo.feedsCollection.DeleteOne(context.Background(), filter)
+	deleteResult, err := repo.feedsCollection.DeleteOne(ctx, filter)
 	if err != nil {
-		log.Printf("Error deleting feed: %v", err)
-		return false
+		return repoerrors.Unavailable(err)
+	}
+
+	if deleteResult.DeletedCount == 0 {
+		return repoerrors.ErrNotFound
 	}
 
-	return deleteResult.DeletedCount > 0
+	return nil
 }
 
-func (repo *PersistentFeedRepository) DeleteTweet(deletedTweet models.Tweet) bool {
+func (repo *PersistentFeedRepository) DeleteTweet(ctx context.Context, deletedTweet models.Tweet) (bool, error) {
 	log.Printf("Deleting tweet: %v", deletedTweet)
 
 	filter := bson.M{
@@ -181,11 +226,11 @@ func (repo *PersistentFeedRepository) DeleteTweet(deletedTweet models.Tweet) boo
 		},
 	}
 
-	_, err := repo.feedsCollection.UpdateMany(context.Background(), filter, update)
+	// Documents without the tweet are not modified by the pull
+	result, err := repo.feedsCollection.UpdateMany(ctx, filter, update)
 	if err != nil {
-		log.Printf("Error deleting tweet: %v", err)
-		return false
+		return false, repoerrors.Unavailable(err)
 	}
 
-	return true
+	return result.ModifiedCount > 0, nil
 }
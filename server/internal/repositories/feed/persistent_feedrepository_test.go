@@ -1,6 +1,7 @@
 package repositories_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -16,8 +17,8 @@ func setupFeedRepo() feedrepo.FeedRepository {
 	// Setup the test database
 	// TODO: Move out connection string and database name to be read from settings or env vars
 	configuration := config.Configuration{
-		Mode: config.Persistent,
 		FeedsStorage: config.FeedsStorage{
+			Driver:           config.MongoDriver,
 			ConnectionString: "mongodb://localhost:27017",
 			DatabaseName:     "Tests_FeedsDb",
 			CollectionName:   "Feeds",
@@ -35,79 +36,84 @@ func setupFeedRepo() feedrepo.FeedRepository {
 
 func Test_CreateFeed(t *testing.T) {
 	feedRepo := setupFeedRepo()
+	ctx := context.Background()
 	name := "testFeed"
 
 	// Create a new feed
-	err := feedRepo.CreateFeed(name)
+	err := feedRepo.CreateFeed(ctx, name)
 	assert.NoError(t, err, "Error creating feed")
 
 	// Try to create the same feed again, it should not return an error
-	err = feedRepo.CreateFeed(name)
+	err = feedRepo.CreateFeed(ctx, name)
 	assert.NoError(t, err, "Error creating feed")
 
 	// Verify that the feed exists in the repository
-	feed, err := feedRepo.GetFeedByName(name)
+	feed, err := feedRepo.GetFeedByName(ctx, name)
 	assert.NoError(t, err, "Error getting feed by name")
 	assert.NotNil(t, feed, "Expected feed to exist, but it doesn't.")
 
 	// Test DeleteFeed
-	deleted := feedRepo.DeleteFeed(name)
-	assert.True(t, deleted, "DeleteFeed should return true for successful deletion")
+	err = feedRepo.DeleteFeed(ctx, name)
+	assert.NoError(t, err, "DeleteFeed should succeed for an existing feed")
 }
 
 func Test_GetFeeds(t *testing.T) {
 	feedRepo := setupFeedRepo()
+	ctx := context.Background()
 	name := "testFeed"
 
 	// Get feeds from an empty repository
-	feeds, err := feedRepo.GetFeeds()
+	feeds, err := feedRepo.GetFeeds(ctx)
 	assert.NoError(t, err, "Error getting feeds")
 	assert.Empty(t, feeds, "Expected no feeds, got %d feeds", len(feeds))
 
 	// Create a new feed
-	err = feedRepo.CreateFeed(name)
+	err = feedRepo.CreateFeed(ctx, name)
 	assert.NoError(t, err, "Error creating feed")
 
 	// Get feeds after creating one
-	feeds, err = feedRepo.GetFeeds()
+	feeds, err = feedRepo.GetFeeds(ctx)
 	assert.NoError(t, err, "Error getting feeds")
 	assert.Len(t, feeds, 1, "Expected 1 feed, got %d feeds", len(feeds))
 
-	deleted := feedRepo.DeleteFeed(name)
-	assert.True(t, deleted, "DeleteFeed should return true for successful deletion")
+	err = feedRepo.DeleteFeed(ctx, name)
+	assert.NoError(t, err, "DeleteFeed should succeed for an existing feed")
 }
 
 func TestGetFeedByName(t *testing.T) {
 	feedRepo := setupFeedRepo()
+	ctx := context.Background()
 
 	feedName := "TechNews"
-	feedRepo.CreateFeed(feedName)
+	feedRepo.CreateFeed(ctx, feedName)
 
-	feed, err := feedRepo.GetFeedByName(feedName)
+	feed, err := feedRepo.GetFeedByName(ctx, feedName)
 
 	// Assert that the feed can be retrieved by its name without errors
 	assert.NoError(t, err, "Should retrieve the feed without errors")
 	assert.Equal(t, feedName, feed.Name, "The feed name should match the expected name")
 
-	deleted := feedRepo.DeleteFeed(feedName)
-	assert.True(t, deleted, "DeleteFeed should return true for successful deletion")
+	err = feedRepo.DeleteFeed(ctx, feedName)
+	assert.NoError(t, err, "DeleteFeed should succeed for an existing feed")
 }
 
 func TestAppendTweet(t *testing.T) {
 	feedRepo := setupFeedRepo()
+	ctx := context.Background()
 
 	feedName := "TechNews"
-	feedRepo.CreateFeed(feedName)
+	feedRepo.CreateFeed(ctx, feedName)
 
 	expectedTweet := createTweet()
 
-	err := feedRepo.AppendTweet(expectedTweet)
+	appended, err := feedRepo.AppendTweet(ctx, expectedTweet)
 
 	// Assert that the tweet is appended without errors
 	assert.NoError(t, err, "Tweet should be appended without errors")
+	assert.True(t, appended, "Tweet should be reported as appended")
 
 	// Optionally, verify that the tweet was added to the feed
-	feed, err := feedRepo.GetFeedByName(feedName)
+	feed, err := feedRepo.GetFeedByName(ctx, feedName)
 	assert.NoError(t, err, "Feed should be retrieved without errors")
 
 	// Assuming Feed has a Tweets field that is a slice of tweets
@@ -122,25 +128,27 @@ func TestAppendTweet(t *testing.T) {
 	// Compare only the Unix timestamp part of the time
 	assert.Equal(t, expectedTweet.CreatedAt.Unix(), actualTweet.CreatedAt.Unix(), "CreatedAt times should be equal")
 
-	deleted := feedRepo.DeleteFeed(feedName)
-	assert.True(t, deleted, "DeleteFeed should return true for successful deletion")
+	err = feedRepo.DeleteFeed(ctx, feedName)
+	assert.NoError(t, err, "DeleteFeed should succeed for an existing feed")
 }
 
 func TestDeleteTweet(t *testing.T) {
 	feedRepo := setupFeedRepo()
+	ctx := context.Background()
 
 	feedName := "TechNews"
-	feedRepo.CreateFeed(feedName)
+	feedRepo.CreateFeed(ctx, feedName)
 
 	expectedTweet := createTweet()
 
-	err := feedRepo.AppendTweet(expectedTweet)
+	_, err := feedRepo.AppendTweet(ctx, expectedTweet)
 
 	// Assert that the tweet is appended without errors
 	assert.NoError(t, err, "Tweet should be appended without errors")
 
-	deleted := feedRepo.DeleteTweet(expectedTweet)
-	assert.True(t, deleted, "DeleteTweet should return true for successful deletion")
+	deleted, err := feedRepo.DeleteTweet(ctx, expectedTweet)
+	assert.NoError(t, err, "DeleteTweet should succeed for an indexed tweet")
+	assert.True(t, deleted, "Tweet should be reported as deleted")
 }
 
 func createTweet() models.Tweet {
@@ -154,3 +162,7 @@ func createTweet() models.Tweet {
 	}
 	return expectedTweet
 }
+
+func TestAppendTweetIsIdempotent(t *testing.T) {
+	testAppendTweetIsIdempotent(t, setupFeedRepo())
+}
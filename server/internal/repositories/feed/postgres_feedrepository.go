@@ -0,0 +1,258 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/postgresdb"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"github.com/lib/pq"
+)
+
+// PostgresFeedRepository materializes every feed as rows of feed_tweets, one per tweet of the feed.
+// The tweet is stored as JSON next to the columns used to order the feed.
+type PostgresFeedRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresFeedRepository(configuration config.Configuration) (*PostgresFeedRepository, error) {
+	db, err := postgresdb.Open(configuration.FeedsStorage.ConnectionString, configuration.FeedsStorage.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS feeds (
+		name VARCHAR(255) PRIMARY KEY
+	);
+
+	CREATE TABLE IF NOT EXISTS feed_tweets (
+		feed_name VARCHAR(255) REFERENCES feeds(name) ON DELETE CASCADE,
+		tweet_id VARCHAR(36),
+		created_at TIMESTAMPTZ NOT NULL,
+		tweet JSONB NOT NULL,
+		PRIMARY KEY (feed_name, tweet_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS feed_tweets_page_idx ON feed_tweets (feed_name, created_at DESC, tweet_id DESC);
+	CREATE INDEX IF NOT EXISTS feed_tweets_tweet_id_idx ON feed_tweets (tweet_id)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating feed tables: %w", err)
+	}
+
+	return &PostgresFeedRepository{db: db}, nil
+}
+
+func (repo *PostgresFeedRepository) CreateFeed(ctx context.Context, name string) error {
+	_, err := repo.db.ExecContext(ctx, "INSERT INTO feeds (name) VALUES ($1) ON CONFLICT DO NOTHING", name)
+	if err != nil {
+		return postgresdb.Error(err, "creating feed")
+	}
+	return nil
+}
+
+// GetFeeds returns the feeds ordered by name
+func (repo *PostgresFeedRepository) GetFeeds(ctx context.Context) ([]models.Feed, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT f.name, ft.tweet
+		FROM feeds f
+		LEFT JOIN feed_tweets ft ON ft.feed_name = f.name
+		ORDER BY f.name, ft.created_at, ft.tweet_id`)
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving feeds")
+	}
+	defer rows.Close()
+
+	feeds := []models.Feed{}
+	for rows.Next() {
+		var name string
+		var encodedTweet []byte
+		if err := rows.Scan(&name, &encodedTweet); err != nil {
+			return nil, postgresdb.Error(err, "scanning feed row")
+		}
+
+		if len(feeds) == 0 || feeds[len(feeds)-1].Name != name {
+			feeds = append(feeds, models.Feed{Name: name, Tweets: []models.Tweet{}})
+		}
+
+		// Feeds without tweets are joined with a NULL tweet
+		if encodedTweet == nil {
+			continue
+		}
+
+		var tweet models.Tweet
+		if err := json.Unmarshal(encodedTweet, &tweet); err != nil {
+			return nil, repoerrors.Unavailable(err)
+		}
+		feeds[len(feeds)-1].Tweets = append(feeds[len(feeds)-1].Tweets, tweet)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, postgresdb.Error(err, "iterating over feed rows")
+	}
+
+	return feeds, nil
+}
+
+func (repo *PostgresFeedRepository) GetFeedByName(ctx context.Context, name string) (*models.Feed, error) {
+	if err := repo.checkFeedExists(ctx, name); err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT tweet FROM feed_tweets
+		WHERE feed_name = $1
+		ORDER BY created_at, tweet_id`, name)
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving feed")
+	}
+
+	tweets, err := scanFeedTweets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Feed{Name: name, Tweets: tweets}, nil
+}
+
+func (repo *PostgresFeedRepository) GetFeedPage(ctx context.Context, name string, page models.PageRequest) (*models.FeedPage, error) {
+	if err := repo.checkFeedExists(ctx, name); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT tweet FROM feed_tweets
+		WHERE feed_name = $1`
+	args := []any{name}
+
+	if page.Cursor != nil {
+		query += `
+		AND (created_at, tweet_id) < ($2, $3)`
+		args = append(args, page.Cursor.CreatedAt, page.Cursor.ID)
+	}
+
+	// One extra row tells whether there is a next page
+	query += fmt.Sprintf(`
+		ORDER BY created_at DESC, tweet_id DESC
+		LIMIT $%d`, len(args)+1)
+	args = append(args, page.Limit+1)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving feed page")
+	}
+
+	tweets, err := scanFeedTweets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	pageTweets, nextCursor := models.TrimPage(tweets, page.Limit)
+	return &models.FeedPage{Name: name, Tweets: pageTweets, NextCursor: nextCursor}, nil
+}
+
+// AppendTweet adds the tweet to the existing feeds of its tags, a tweet already in a feed is kept as is
+func (repo *PostgresFeedRepository) AppendTweet(ctx context.Context, tweet models.Tweet) (bool, error) {
+	if len(tweet.Tags) == 0 {
+		return false, nil
+	}
+
+	// The stored tweet must match the created_at column, cursors are built from it
+	tweet.CreatedAt.Time = tweet.CreatedAt.Truncate(time.Microsecond)
+	encodedTweet, err := json.Marshal(tweet)
+	if err != nil {
+		return false, err
+	}
+
+	// Parameters in a select list are not typed by the insert target, hence the casts
+	result, err := repo.db.ExecContext(ctx, `
+	INSERT INTO feed_tweets (feed_name, tweet_id, created_at, tweet)
+		SELECT name, $2::varchar, $3::timestamptz, $4::jsonb FROM feeds WHERE name = ANY($1)
+		ON CONFLICT DO NOTHING
+	`, pq.Array(tweet.Tags), tweet.ID, tweet.CreatedAt.Time, string(encodedTweet))
+	if err != nil {
+		return false, postgresdb.Error(err, "appending tweet to feeds")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, postgresdb.Error(err, "getting rows affected after appending tweet")
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (repo *PostgresFeedRepository) DeleteFeed(ctx context.Context, name string) error {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM feeds WHERE name = $1", name)
+	if err != nil {
+		return postgresdb.Error(err, "deleting feed")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return postgresdb.Error(err, "getting rows affected after feed deletion")
+	}
+
+	if rowsAffected == 0 {
+		return repoerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *PostgresFeedRepository) DeleteTweet(ctx context.Context, deletedTweet models.Tweet) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM feed_tweets WHERE tweet_id = $1 AND feed_name = ANY($2)",
+		deletedTweet.ID, pq.Array(deletedTweet.Tags))
+	if err != nil {
+		return false, postgresdb.Error(err, "deleting tweet from feeds")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, postgresdb.Error(err, "getting rows affected after tweet deletion")
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (repo *PostgresFeedRepository) checkFeedExists(ctx context.Context, name string) error {
+	var exists bool
+	err := repo.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM feeds WHERE name = $1)", name).Scan(&exists)
+	if err != nil {
+		return postgresdb.Error(err, "checking feed existence")
+	}
+	if !exists {
+		return repoerrors.ErrNotFound
+	}
+	return nil
+}
+
+func scanFeedTweets(rows *sql.Rows) ([]models.Tweet, error) {
+	defer rows.Close()
+
+	tweets := []models.Tweet{}
+	for rows.Next() {
+		var encodedTweet []byte
+		if err := rows.Scan(&encodedTweet); err != nil {
+			return nil, postgresdb.Error(err, "scanning feed tweet row")
+		}
+
+		var tweet models.Tweet
+		if err := json.Unmarshal(encodedTweet, &tweet); err != nil {
+			return nil, repoerrors.Unavailable(err)
+		}
+		tweets = append(tweets, tweet)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, postgresdb.Error(err, "iterating over feed tweet rows")
+	}
+
+	return tweets, nil
+}
@@ -1,23 +1,15 @@
 package repositories
 
-import "twitter-clone/internal/models"
+import (
+	"slices"
+	"twitter-clone/internal/models"
+)
 
-// ContainsTag checks if a given tag is present in the tags slice
-func ContainsTag(tags []string, tag string) bool {
-	for _, t := range tags {
-		if t == tag {
-			return true
-		}
-	}
-	return false
-}
+// NewFeedPage sorts the embedded tweets of the feed and returns the requested page
+func NewFeedPage(feed models.Feed, page models.PageRequest) *models.FeedPage {
+	tweets := slices.Clone(feed.Tweets)
+	models.SortTweetsNewestFirst(tweets)
 
-// ContainsTweet checks if a given tweet ID is present in the tweets slice
-func ContainsTweet(tweets []models.Tweet, tweetId string) bool {
-	for _, t := range tweets {
-		if t.ID == tweetId {
-			return true
-		}
-	}
-	return false
+	pageTweets, nextCursor := models.PaginateTweets(tweets, page)
+	return &models.FeedPage{Name: feed.Name, Tweets: pageTweets, NextCursor: nextCursor}
 }
@@ -0,0 +1,18 @@
+-- IF NOT EXISTS adopts databases created before migrations were introduced
+CREATE TABLE IF NOT EXISTS users (
+	id VARCHAR(36) PRIMARY KEY,
+	first_name VARCHAR(255),
+	last_name VARCHAR(255),
+	email VARCHAR(255) UNIQUE,
+	picture TEXT
+);
+
+CREATE TABLE IF NOT EXISTS tweets (
+	id VARCHAR(36) PRIMARY KEY,
+	title VARCHAR(255),
+	content TEXT,
+	created_at TIMESTAMP,
+	user_id VARCHAR(36),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	tags TEXT
+);
@@ -0,0 +1,15 @@
+-- The user is copied onto the token, users only get a row once they tweet
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id VARCHAR(36) PRIMARY KEY,
+	token_hash CHAR(64) UNIQUE,
+	name VARCHAR(255),
+	scopes TEXT,
+	user_id VARCHAR(255),
+	first_name VARCHAR(255),
+	last_name VARCHAR(255),
+	email VARCHAR(255),
+	picture TEXT,
+	created_at TIMESTAMP,
+	expires_at TIMESTAMP NULL,
+	INDEX (user_id)
+);
@@ -0,0 +1 @@
+DROP INDEX idx_tweets_created_at ON tweets;
@@ -0,0 +1,2 @@
+-- Serves the newest first keyset pagination of GetTweetsPage
+CREATE INDEX idx_tweets_created_at ON tweets (created_at, id);
@@ -0,0 +1,24 @@
+-- The ordinal keeps the order in which the tags were given
+CREATE TABLE tweet_tags (
+	tweet_id VARCHAR(36) NOT NULL,
+	ordinal INT NOT NULL,
+	tag VARCHAR(255) NOT NULL,
+	PRIMARY KEY (tweet_id, ordinal),
+	INDEX idx_tweet_tags_tag (tag, tweet_id),
+	CONSTRAINT fk_tweet_tags_tweet FOREIGN KEY (tweet_id) REFERENCES tweets(id) ON DELETE CASCADE
+);
+
+-- Split the comma-joined tags column into one row per tag
+INSERT INTO tweet_tags (tweet_id, ordinal, tag)
+WITH RECURSIVE split (tweet_id, ordinal, tag, rest) AS (
+	SELECT id, 1, SUBSTRING_INDEX(tags, ',', 1),
+	       IF(LOCATE(',', tags) > 0, SUBSTRING(tags, LOCATE(',', tags) + 1), NULL)
+	FROM tweets
+	WHERE tags IS NOT NULL AND tags <> ''
+	UNION ALL
+	SELECT tweet_id, ordinal + 1, SUBSTRING_INDEX(rest, ',', 1),
+	       IF(LOCATE(',', rest) > 0, SUBSTRING(rest, LOCATE(',', rest) + 1), NULL)
+	FROM split
+	WHERE rest IS NOT NULL
+)
+SELECT tweet_id, ordinal, tag FROM split;
@@ -0,0 +1,8 @@
+ALTER TABLE tweets ADD COLUMN tags TEXT;
+
+UPDATE tweets t
+SET t.tags = (
+	SELECT GROUP_CONCAT(tt.tag ORDER BY tt.ordinal SEPARATOR ',')
+	FROM tweet_tags tt
+	WHERE tt.tweet_id = t.id
+);
@@ -0,0 +1,2 @@
+-- Tags live in tweet_tags since migration 4
+ALTER TABLE tweets DROP COLUMN tags;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS tweet_outbox;
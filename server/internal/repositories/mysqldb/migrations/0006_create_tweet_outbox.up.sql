@@ -0,0 +1,9 @@
+-- Tweet events written with the tweet changes, the sequence keeps their order until they are published
+CREATE TABLE tweet_outbox (
+	sequence BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	id VARCHAR(36) NOT NULL,
+	topic VARCHAR(255) NOT NULL,
+	payload MEDIUMBLOB NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	UNIQUE INDEX idx_tweet_outbox_id (id)
+);
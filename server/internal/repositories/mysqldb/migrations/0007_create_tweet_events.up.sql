@@ -0,0 +1,9 @@
+-- Append-only history of the published tweet events, read in the order of their sequence
+CREATE TABLE tweet_events (
+	sequence BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	id VARCHAR(36) NOT NULL,
+	topic VARCHAR(255) NOT NULL,
+	payload MEDIUMBLOB NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	UNIQUE INDEX idx_tweet_events_id (id)
+);
@@ -0,0 +1,9 @@
+-- Emails unique again: of the users sharing an email only the row with the lowest id keeps it
+UPDATE users u
+	JOIN (SELECT email, MIN(id) AS kept_id FROM users WHERE email IS NOT NULL GROUP BY email HAVING COUNT(*) > 1) duplicates
+		ON u.email = duplicates.email AND u.id <> duplicates.kept_id
+	SET u.email = NULL;
+ALTER TABLE users ADD UNIQUE INDEX email (email);
+ALTER TABLE users DROP INDEX idx_users_email;
+ALTER TABLE users DROP INDEX idx_users_subject;
+ALTER TABLE users DROP COLUMN subject;
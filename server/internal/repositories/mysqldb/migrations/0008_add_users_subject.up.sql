@@ -0,0 +1,7 @@
+-- Users are keyed by the subject of their identity provider, the id stays an internal key.
+-- Existing rows get no subject, their tweets are matched by email until the first sign-in with
+-- that verified email claims the row. Emails may repeat across providers.
+ALTER TABLE users ADD COLUMN subject VARCHAR(255) NULL;
+ALTER TABLE users ADD UNIQUE INDEX idx_users_subject (subject);
+ALTER TABLE users ADD INDEX idx_users_email (email);
+ALTER TABLE users DROP INDEX email;
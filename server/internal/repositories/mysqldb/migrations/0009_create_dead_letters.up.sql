@@ -0,0 +1,11 @@
+-- Feed updates which failed after all retries, kept until an admin replays them
+CREATE TABLE dead_letters (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	topic VARCHAR(255) NOT NULL,
+	handler VARCHAR(255) NOT NULL,
+	reason TEXT NOT NULL,
+	payload MEDIUMBLOB NOT NULL,
+	metadata TEXT NOT NULL,
+	failed_at TIMESTAMP(6) NOT NULL,
+	INDEX idx_dead_letters_failed_at (failed_at, id)
+);
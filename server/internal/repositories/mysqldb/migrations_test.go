@@ -0,0 +1,73 @@
+package mysqldb_test
+
+import (
+	"testing"
+	"testing/fstest"
+	"twitter-clone/internal/repositories/mysqldb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_index.up.sql":      {Data: []byte("CREATE INDEX idx ON tweets (created_at);")},
+		"migrations/0002_add_index.down.sql":    {Data: []byte("DROP INDEX idx ON tweets;")},
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id VARCHAR(36));")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	}
+
+	migrations, err := mysqldb.LoadMigrations(fsys, "migrations")
+
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, 1, migrations[0].Version, "Migrations should be ordered by version")
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE users;", migrations[0].Down)
+	assert.Equal(t, 2, migrations[1].Version)
+	assert.Equal(t, "CREATE INDEX idx ON tweets (created_at);", migrations[1].Up)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{name: "missing down", fsys: fstest.MapFS{
+			"migrations/0001_create_users.up.sql": {Data: []byte("CREATE TABLE users (id VARCHAR(36));")},
+		}},
+		{name: "invalid file name", fsys: fstest.MapFS{
+			"migrations/create_users.sql": {Data: []byte("CREATE TABLE users (id VARCHAR(36));")},
+		}},
+		{name: "duplicate version", fsys: fstest.MapFS{
+			"migrations/0001_create_users.up.sql":    {Data: []byte("CREATE TABLE users (id VARCHAR(36));")},
+			"migrations/0001_create_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+			"migrations/0001_create_tweets.up.sql":   {Data: []byte("CREATE TABLE tweets (id VARCHAR(36));")},
+			"migrations/0001_create_tweets.down.sql": {Data: []byte("DROP TABLE tweets;")},
+		}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := mysqldb.LoadMigrations(testCase.fsys, "migrations")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewMigrator_EmbeddedMigrationsAreValid(t *testing.T) {
+	// Loading does not touch the database
+	_, err := mysqldb.NewMigrator(nil)
+	assert.NoError(t, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- Comment; with a semicolon
+CREATE TABLE users (id VARCHAR(36));
+
+CREATE TABLE tweets (id VARCHAR(36));
+`
+
+	statements := mysqldb.SplitStatements(script)
+
+	assert.Equal(t, []string{"CREATE TABLE users (id VARCHAR(36))", "CREATE TABLE tweets (id VARCHAR(36))"}, statements)
+}
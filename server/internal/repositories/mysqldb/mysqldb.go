@@ -0,0 +1,52 @@
+// Package mysqldb connects the repositories of the mysql driver to their database and versions its schema
+package mysqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Open connects to the database, creating it first if it does not exist.
+// The connection string has no database name, e.g. myuser:mypassword@tcp(127.0.0.1:3306)
+func Open(connectionString string, databaseName string) (*sql.DB, error) {
+	// Get the connection string without the database name
+	connString := fmt.Sprintf("%s/", connectionString)
+	log.Println("Connecting without database:", connString)
+
+	// Open the database connection (without specifying a database)
+	db, err := sql.Open("mysql", connString)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", databaseName))
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating database: %v", err)
+	}
+
+	// Now that the database exists, close the connection and reopen with the database name
+	err = db.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error closing connection: %v", err)
+	}
+
+	// Reconnect with the database specified
+	connStringWithDB := fmt.Sprintf("%s/%s", connectionString, databaseName)
+	db, err = sql.Open("mysql", connStringWithDB)
+	if err != nil {
+		return nil, fmt.Errorf("error reconnecting to database: %v", err)
+	}
+
+	// Ping the database to ensure connectivity
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,23 @@
+// Package repoerrors holds the errors shared by all repository backends, so
+// callers can tell missing entities and conflicts apart from storage failures.
+package repoerrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("already exists")
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
+// Unavailable wraps a backend failure. The cause stays in the chain, so a
+// cancelled request context can still be detected with errors.Is.
+func Unavailable(err error) error {
+	if err == nil || errors.Is(err, ErrUnavailable) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
@@ -1,34 +1,118 @@
 package repositories
 
 import (
-	"errors"
+	"fmt"
 	"twitter-clone/internal/config"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/repositories/boltdb"
 	feedrepo "twitter-clone/internal/repositories/feed"
 	tweetrepo "twitter-clone/internal/repositories/tweet"
 )
 
 func CreateTweetRepository(configuration config.Configuration) (tweetrepo.TweetRepository, error) {
-	switch configuration.Mode {
-	case config.InMemory:
+	switch configuration.TweetsStorage.Driver {
+	case config.MemoryDriver:
 		return &tweetrepo.InMemoryTweetRepository{}, nil
-	case config.Persistent:
+	case config.MySQLDriver:
 		return tweetrepo.NewPersistentTweetRepository(configuration)
-	case config.Cloud:
+	case config.PostgresDriver:
+		return tweetrepo.NewPostgresTweetRepository(configuration)
+	case config.FirestoreDriver:
 		return tweetrepo.NewFirestoreTweetRepository(configuration)
+	case config.BoltDriver:
+		db, err := boltdb.Open(configuration.EmbeddedStorage.Path)
+		if err != nil {
+			return nil, err
+		}
+		return tweetrepo.NewBoltTweetRepository(db)
 	default:
-		return nil, errors.New("unknown mode")
+		return nil, fmt.Errorf("unknown tweets storage driver %q", configuration.TweetsStorage.Driver)
 	}
 }
 
 func CreateFeedRepository(configuration config.Configuration) (feedrepo.FeedRepository, error) {
-	switch configuration.Mode {
-	case config.InMemory:
+	switch configuration.FeedsStorage.Driver {
+	case config.MemoryDriver:
 		return &feedrepo.InMemoryFeedRepository{}, nil
-	case config.Persistent:
+	case config.MongoDriver:
 		return feedrepo.NewPersistentFeedRepository(configuration)
-	case config.Cloud:
+	case config.PostgresDriver:
+		return feedrepo.NewPostgresFeedRepository(configuration)
+	case config.FirestoreDriver:
 		return feedrepo.NewFirestoreFeedRepository(configuration)
+	case config.BoltDriver:
+		db, err := boltdb.Open(configuration.EmbeddedStorage.Path)
+		if err != nil {
+			return nil, err
+		}
+		return feedrepo.NewBoltFeedRepository(db)
 	default:
-		return nil, errors.New("unknown mode")
+		return nil, fmt.Errorf("unknown feeds storage driver %q", configuration.FeedsStorage.Driver)
+	}
+}
+
+// CreateTokenRepository stores API tokens next to the tweets
+func CreateTokenRepository(configuration config.Configuration) (tweetrepo.TokenRepository, error) {
+	switch configuration.TweetsStorage.Driver {
+	case config.MemoryDriver:
+		return &tweetrepo.InMemoryTokenRepository{}, nil
+	case config.MySQLDriver:
+		return tweetrepo.NewPersistentTokenRepository(configuration)
+	case config.PostgresDriver:
+		return tweetrepo.NewPostgresTokenRepository(configuration)
+	case config.FirestoreDriver:
+		return tweetrepo.NewFirestoreTokenRepository(configuration)
+	case config.BoltDriver:
+		db, err := boltdb.Open(configuration.EmbeddedStorage.Path)
+		if err != nil {
+			return nil, err
+		}
+		return tweetrepo.NewBoltTokenRepository(db)
+	default:
+		return nil, fmt.Errorf("unknown tweets storage driver %q", configuration.TweetsStorage.Driver)
+	}
+}
+
+// CreateEventStore stores the published tweet events next to the tweets
+func CreateEventStore(configuration config.Configuration) (tweetrepo.EventStore, error) {
+	switch configuration.TweetsStorage.Driver {
+	case config.MemoryDriver:
+		return &tweetrepo.InMemoryEventStore{}, nil
+	case config.MySQLDriver:
+		return tweetrepo.NewPersistentEventStore(configuration)
+	case config.PostgresDriver:
+		return tweetrepo.NewPostgresEventStore(configuration)
+	case config.FirestoreDriver:
+		return tweetrepo.NewFirestoreEventStore(configuration)
+	case config.BoltDriver:
+		db, err := boltdb.Open(configuration.EmbeddedStorage.Path)
+		if err != nil {
+			return nil, err
+		}
+		return tweetrepo.NewBoltEventStore(db)
+	default:
+		return nil, fmt.Errorf("unknown tweets storage driver %q", configuration.TweetsStorage.Driver)
+	}
+}
+
+// CreateDeadLetterStore keeps the failed feed updates next to the tweets
+func CreateDeadLetterStore(configuration config.Configuration) (messaging.DeadLetterStore, error) {
+	switch configuration.TweetsStorage.Driver {
+	case config.MemoryDriver:
+		return &tweetrepo.InMemoryDeadLetterStore{}, nil
+	case config.MySQLDriver:
+		return tweetrepo.NewPersistentDeadLetterStore(configuration)
+	case config.PostgresDriver:
+		return tweetrepo.NewPostgresDeadLetterStore(configuration)
+	case config.FirestoreDriver:
+		return tweetrepo.NewFirestoreDeadLetterStore(configuration)
+	case config.BoltDriver:
+		db, err := boltdb.Open(configuration.EmbeddedStorage.Path)
+		if err != nil {
+			return nil, err
+		}
+		return tweetrepo.NewBoltDeadLetterStore(db)
+	default:
+		return nil, fmt.Errorf("unknown tweets storage driver %q", configuration.TweetsStorage.Driver)
 	}
 }
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"go.etcd.io/bbolt"
+)
+
+var deadLettersBucket = []byte("dead_letters") // Dead letters keyed by their id
+
+// BoltDeadLetterStore stores the dead letters in the bbolt file of the tweets
+type BoltDeadLetterStore struct {
+	db *bbolt.DB
+}
+
+func NewBoltDeadLetterStore(db *bbolt.DB) (*BoltDeadLetterStore, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(deadLettersBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltDeadLetterStore{db: db}, nil
+}
+
+func (store *BoltDeadLetterStore) AddDeadLetter(ctx context.Context, letter models.DeadLetter) error {
+	err := store.db.Update(func(tx *bbolt.Tx) error {
+		return putJSON(tx.Bucket(deadLettersBucket), letter.ID, letter)
+	})
+	return boltError(err)
+}
+
+func (store *BoltDeadLetterStore) GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	letters := []models.DeadLetter{}
+	err := store.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(deadLettersBucket).ForEach(func(key, value []byte) error {
+			var letter models.DeadLetter
+			if err := json.Unmarshal(value, &letter); err != nil {
+				return err
+			}
+			letters = append(letters, letter)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	// The bucket is ordered by id
+	sortDeadLetters(letters)
+	return letters, nil
+}
+
+func (store *BoltDeadLetterStore) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	var letter models.DeadLetter
+	err := store.db.View(func(tx *bbolt.Tx) error {
+		return getJSON(tx.Bucket(deadLettersBucket), id, &letter)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return &letter, nil
+}
+
+func (store *BoltDeadLetterStore) DeleteDeadLetter(ctx context.Context, id string) error {
+	err := store.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(deadLettersBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return repoerrors.ErrNotFound
+		}
+		return bucket.Delete([]byte(id))
+	})
+	return boltError(err)
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"twitter-clone/internal/models"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	eventsBucket   = []byte("tweet_events")    // Stored events keyed by their big-endian sequence
+	eventIDsBucket = []byte("tweet_event_ids") // Sequences by event ID
+)
+
+// BoltEventStore stores the events in the bbolt file of the tweets, its sequences have no gaps
+type BoltEventStore struct {
+	db *bbolt.DB
+}
+
+func NewBoltEventStore(db *bbolt.DB) (*BoltEventStore, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(eventsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(eventIDsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltEventStore{db: db}, nil
+}
+
+func (store *BoltEventStore) AppendEvents(ctx context.Context, events []models.OutboxEvent) error {
+	err := store.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(eventsBucket)
+		ids := tx.Bucket(eventIDsBucket)
+
+		for _, event := range events {
+			if ids.Get([]byte(event.ID)) != nil {
+				continue
+			}
+
+			sequence, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			key := binary.BigEndian.AppendUint64(nil, sequence)
+
+			encoded, err := json.Marshal(models.StoredEvent{
+				Sequence:  int64(sequence),
+				ID:        event.ID,
+				Topic:     event.Topic,
+				Payload:   event.Payload,
+				CreatedAt: event.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, encoded); err != nil {
+				return err
+			}
+			if err := ids.Put([]byte(event.ID), key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return boltError(err)
+}
+
+func (store *BoltEventStore) GetEvents(ctx context.Context, since int64, limit int) ([]models.StoredEvent, error) {
+	events := []models.StoredEvent{}
+	err := store.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(eventsBucket).Cursor()
+		start := binary.BigEndian.AppendUint64(nil, uint64(max(since, 0))+1)
+		for key, value := cursor.Seek(start); key != nil && len(events) < limit; key, value = cursor.Next() {
+			var event models.StoredEvent
+			if err := json.Unmarshal(value, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return events, nil
+}
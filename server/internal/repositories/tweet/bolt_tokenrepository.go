@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	apiTokensBucket      = []byte("api_tokens")
+	apiTokenHashesBucket = []byte("api_token_hashes") // Token IDs by hash
+)
+
+// boltAPIToken keeps the fields which are hidden from API responses
+type boltAPIToken struct {
+	models.APIToken
+	User models.User `json:"user"`
+	Hash string      `json:"hash"`
+}
+
+func (record boltAPIToken) token() models.APIToken {
+	token := record.APIToken
+	token.User = record.User
+	token.Hash = record.Hash
+	return token
+}
+
+// BoltTokenRepository stores API tokens in the bbolt file of the tweets
+type BoltTokenRepository struct {
+	db *bbolt.DB
+}
+
+func NewBoltTokenRepository(db *bbolt.DB) (*BoltTokenRepository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(apiTokensBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(apiTokenHashesBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltTokenRepository{db: db}, nil
+}
+
+func (repo *BoltTokenRepository) CreateToken(ctx context.Context, token models.APIToken) (*models.APIToken, error) {
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket(apiTokensBucket)
+		hashes := tx.Bucket(apiTokenHashesBucket)
+		if tokens.Get([]byte(token.ID)) != nil || hashes.Get([]byte(token.Hash)) != nil {
+			return repoerrors.ErrConflict
+		}
+
+		if err := putJSON(tokens, token.ID, boltAPIToken{APIToken: token, User: token.User, Hash: token.Hash}); err != nil {
+			return err
+		}
+		return hashes.Put([]byte(token.Hash), []byte(token.ID))
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return &token, nil
+}
+
+func (repo *BoltTokenRepository) GetTokensByUser(ctx context.Context, userID string) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := repo.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(apiTokensBucket).ForEach(func(key, value []byte) error {
+			var record boltAPIToken
+			if err := json.Unmarshal(value, &record); err != nil {
+				return err
+			}
+			if record.User.ID == userID {
+				tokens = append(tokens, record.token())
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return tokens, nil
+}
+
+func (repo *BoltTokenRepository) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	var record boltAPIToken
+	err := repo.db.View(func(tx *bbolt.Tx) error {
+		id := tx.Bucket(apiTokenHashesBucket).Get([]byte(hash))
+		if id == nil {
+			return repoerrors.ErrNotFound
+		}
+		return getJSON(tx.Bucket(apiTokensBucket), string(id), &record)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	token := record.token()
+	return &token, nil
+}
+
+func (repo *BoltTokenRepository) DeleteToken(ctx context.Context, id string, userID string) error {
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket(apiTokensBucket)
+
+		var record boltAPIToken
+		if err := getJSON(tokens, id, &record); err != nil {
+			return err
+		}
+		if record.User.ID != userID {
+			return repoerrors.ErrNotFound
+		}
+
+		if err := tx.Bucket(apiTokenHashesBucket).Delete([]byte(record.Hash)); err != nil {
+			return err
+		}
+		return tokens.Delete([]byte(id))
+	})
+	return boltError(err)
+}
@@ -0,0 +1,289 @@
+package repositories
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	tweetsBucket = []byte("tweets")
+	outboxBucket = []byte("tweet_outbox") // Outbox events keyed by a big-endian sequence, oldest first
+)
+
+// BoltTweetRepository stores tweets as JSON in a local bbolt file, keyed by tweet ID
+type BoltTweetRepository struct {
+	db *bbolt.DB
+}
+
+func NewBoltTweetRepository(db *bbolt.DB) (*BoltTweetRepository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(tweetsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(outboxBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltTweetRepository{db: db}, nil
+}
+
+// boltError passes the repository errors returned from transactions through,
+// any other failure is reported as unavailable storage
+func boltError(err error) error {
+	if err == nil || errors.Is(err, repoerrors.ErrNotFound) || errors.Is(err, repoerrors.ErrConflict) {
+		return err
+	}
+	return repoerrors.Unavailable(err)
+}
+
+func (repo *BoltTweetRepository) CreateTweet(ctx context.Context, createTweetRequest models.CreateTweetRequest, user models.User) (*models.Tweet, error) {
+	tweet := CreateNewTweet(createTweetRequest, user)
+
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(tweetsBucket)
+		if bucket.Get([]byte(tweet.ID)) != nil {
+			return repoerrors.ErrConflict
+		}
+		if err := putJSON(bucket, tweet.ID, tweet); err != nil {
+			return err
+		}
+
+		event, err := TweetCreatedOutboxEvent(tweet)
+		if err != nil {
+			return err
+		}
+		return putOutboxEvent(tx, event)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return &tweet, nil
+}
+
+// GetTweets returns the tweets in the order they were created
+func (repo *BoltTweetRepository) GetTweets(ctx context.Context) ([]models.Tweet, error) {
+	tweets, err := repo.readTweets()
+	if err != nil {
+		return nil, err
+	}
+
+	models.SortTweetsNewestFirst(tweets)
+	slices.Reverse(tweets)
+	return tweets, nil
+}
+
+func (repo *BoltTweetRepository) GetTweetsPage(ctx context.Context, page models.PageRequest) (*models.TweetsPage, error) {
+	tweets, err := repo.readTweets()
+	if err != nil {
+		return nil, err
+	}
+
+	models.SortTweetsNewestFirst(tweets)
+	pageTweets, nextCursor := models.PaginateTweets(tweets, page)
+	return &models.TweetsPage{Tweets: pageTweets, NextCursor: nextCursor}, nil
+}
+
+// GetTweetsByTag scans all tweets, bbolt has no secondary indexes
+func (repo *BoltTweetRepository) GetTweetsByTag(ctx context.Context, tag string, page models.PageRequest) (*models.TweetsPage, error) {
+	tweets, err := repo.readTweets()
+	if err != nil {
+		return nil, err
+	}
+
+	tweets = slices.DeleteFunc(tweets, func(tweet models.Tweet) bool {
+		return !slices.Contains(tweet.Tags, tag)
+	})
+
+	models.SortTweetsNewestFirst(tweets)
+	pageTweets, nextCursor := models.PaginateTweets(tweets, page)
+	return &models.TweetsPage{Tweets: pageTweets, NextCursor: nextCursor}, nil
+}
+
+func (repo *BoltTweetRepository) GetTweetById(ctx context.Context, id string) (*models.Tweet, error) {
+	var tweet models.Tweet
+	err := repo.db.View(func(tx *bbolt.Tx) error {
+		return getJSON(tx.Bucket(tweetsBucket), id, &tweet)
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return &tweet, nil
+}
+
+func (repo *BoltTweetRepository) UpdateTweet(ctx context.Context, id string, updateTweetRequest models.UpdateTweetRequest, check TweetCheck) (*models.Tweet, error) {
+	var tweet models.Tweet
+	var checkErr error
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(tweetsBucket)
+		if err := getJSON(bucket, id, &tweet); err != nil {
+			return err
+		}
+		if checkErr = CheckTweet(check, tweet); checkErr != nil {
+			return checkErr
+		}
+
+		updatedTweet := ApplyUpdateTweetRequest(tweet, updateTweetRequest)
+		event, err := TweetUpdatedOutboxEvent(tweet, updatedTweet)
+		if err != nil {
+			return err
+		}
+
+		tweet = updatedTweet
+		if err := putJSON(bucket, id, tweet); err != nil {
+			return err
+		}
+		return putOutboxEvent(tx, event)
+	})
+	if checkErr != nil {
+		return nil, checkErr
+	}
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return &tweet, nil
+}
+
+func (repo *BoltTweetRepository) DeleteTweet(ctx context.Context, id string, check TweetCheck) error {
+	var checkErr error
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(tweetsBucket)
+		var tweet models.Tweet
+		if err := getJSON(bucket, id, &tweet); err != nil {
+			return err
+		}
+		if checkErr = CheckTweet(check, tweet); checkErr != nil {
+			return checkErr
+		}
+		if err := bucket.Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		event, err := TweetDeletedOutboxEvent(tweet)
+		if err != nil {
+			return err
+		}
+		return putOutboxEvent(tx, event)
+	})
+	if checkErr != nil {
+		return checkErr
+	}
+	return boltError(err)
+}
+
+func (repo *BoltTweetRepository) GetOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	events := []models.OutboxEvent{}
+	err := repo.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(outboxBucket).Cursor()
+		for key, value := cursor.First(); key != nil && len(events) < limit; key, value = cursor.Next() {
+			var event models.OutboxEvent
+			if err := json.Unmarshal(value, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return events, nil
+}
+
+func (repo *BoltTweetRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	err := repo.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(outboxBucket)
+
+		var keys [][]byte
+		err := bucket.ForEach(func(key, value []byte) error {
+			var event models.OutboxEvent
+			if err := json.Unmarshal(value, &event); err != nil {
+				return err
+			}
+			if slices.Contains(ids, event.ID) {
+				keys = append(keys, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Keys are deleted after iterating, bbolt cursors skip entries deleted while iterating
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return boltError(err)
+}
+
+func (repo *BoltTweetRepository) ForwardOutboxEvents(ctx context.Context, limit int, forward func(events []models.OutboxEvent) ([]string, error)) error {
+	return forwardOutboxEvents(ctx, repo, limit, forward)
+}
+
+func (repo *BoltTweetRepository) readTweets() ([]models.Tweet, error) {
+	tweets := []models.Tweet{}
+	err := repo.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(tweetsBucket).ForEach(func(key, value []byte) error {
+			var tweet models.Tweet
+			if err := json.Unmarshal(value, &tweet); err != nil {
+				return err
+			}
+			tweets = append(tweets, tweet)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return tweets, nil
+}
+
+func putOutboxEvent(tx *bbolt.Tx, event models.OutboxEvent) error {
+	bucket := tx.Bucket(outboxBucket)
+	sequence, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	key := binary.BigEndian.AppendUint64(nil, sequence)
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, encoded)
+}
+
+func putJSON(bucket *bbolt.Bucket, key string, value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key), encoded)
+}
+
+// getJSON decodes the value stored under key, a missing key yields repoerrors.ErrNotFound
+func getJSON(bucket *bbolt.Bucket, key string, value any) error {
+	encoded := bucket.Get([]byte(key))
+	if encoded == nil {
+		return repoerrors.ErrNotFound
+	}
+	return json.Unmarshal(encoded, value)
+}
@@ -0,0 +1,163 @@
+package repositories_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+	repositories "twitter-clone/internal/repositories/tweet"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func openBoltDB(t *testing.T, path string) *bbolt.DB {
+	db, err := bbolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestBoltTweetRepository(t *testing.T) {
+	repo, err := repositories.NewBoltTweetRepository(openBoltDB(t, filepath.Join(t.TempDir(), "tweets.db")))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Test CreateTweet
+	createdTweet, err := repo.CreateTweet(ctx, repositories.TestCreateTweetRequest, repositories.TestUser)
+	require.NoError(t, err, "CreateTweet should return the created tweet")
+	tweetID := createdTweet.ID
+
+	// Test GetTweets
+	allTweets, err := repo.GetTweets(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, allTweets, 1, "GetTweets should return a single tweet")
+
+	// Test GetTweetById
+	foundTweet, err := repo.GetTweetById(ctx, tweetID)
+	require.NoError(t, err, "GetTweetById should find the tweet")
+	assert.Equal(t, createdTweet.Content, foundTweet.Content)
+	assert.Equal(t, createdTweet.User, foundTweet.User)
+	assert.True(t, createdTweet.CreatedAt.Equal(foundTweet.CreatedAt.Time), "Creation time should survive encoding")
+
+	_, err = repo.GetTweetById(ctx, "non-existing-id")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "GetTweetById should return ErrNotFound for non-existing tweet")
+
+	// Test UpdateTweet
+	updatedTweet, err := repo.UpdateTweet(ctx, tweetID, models.UpdateTweetRequest{Title: "new title", Content: "new content", Tags: []string{"tag2"}}, nil)
+	require.NoError(t, err, "UpdateTweet should return the updated tweet")
+	assert.Equal(t, []string{"tag2"}, updatedTweet.Tags)
+	foundTweet, err = repo.GetTweetById(ctx, tweetID)
+	require.NoError(t, err)
+	assert.Equal(t, "new content", foundTweet.Content, "GetTweetById should return the updated tweet")
+
+	_, err = repo.UpdateTweet(ctx, "non-existing-id", models.UpdateTweetRequest{}, nil)
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "UpdateTweet should return ErrNotFound for non-existing tweet")
+
+	// Test DeleteTweet
+	assert.NoError(t, repo.DeleteTweet(ctx, tweetID, nil), "DeleteTweet should succeed for an existing tweet")
+	assert.ErrorIs(t, repo.DeleteTweet(ctx, tweetID, nil), repoerrors.ErrNotFound, "DeleteTweet should return ErrNotFound for non-existing tweet deletion")
+
+	remainingTweets, err := repo.GetTweets(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, remainingTweets, "GetTweets should return no tweets after deletion")
+}
+
+func TestBoltTweetRepository_GetTweetsByTag(t *testing.T) {
+	repo, err := repositories.NewBoltTweetRepository(openBoltDB(t, filepath.Join(t.TempDir(), "tweets.db")))
+	require.NoError(t, err)
+
+	testGetTweetsByTag(t, repo)
+}
+
+func TestBoltTweetRepository_UserSubjects(t *testing.T) {
+	repo, err := repositories.NewBoltTweetRepository(openBoltDB(t, filepath.Join(t.TempDir(), "tweets.db")))
+	require.NoError(t, err)
+
+	testUserSubjects(t, repo)
+}
+
+func TestBoltTweetRepository_OutboxEvents(t *testing.T) {
+	repo, err := repositories.NewBoltTweetRepository(openBoltDB(t, filepath.Join(t.TempDir(), "tweets.db")))
+	require.NoError(t, err)
+
+	testOutboxEvents(t, repo)
+}
+
+func TestBoltEventStore(t *testing.T) {
+	store, err := repositories.NewBoltEventStore(openBoltDB(t, filepath.Join(t.TempDir(), "tweets.db")))
+	require.NoError(t, err)
+
+	testEventStore(t, store)
+}
+
+func TestBoltDeadLetterStore(t *testing.T) {
+	store, err := repositories.NewBoltDeadLetterStore(openBoltDB(t, filepath.Join(t.TempDir(), "tweets.db")))
+	require.NoError(t, err)
+
+	testDeadLetterStore(t, store)
+}
+
+func TestBoltTweetRepository_PersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tweets.db")
+	ctx := context.Background()
+
+	db, err := bbolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	repo, err := repositories.NewBoltTweetRepository(db)
+	require.NoError(t, err)
+	createdTweet, err := repo.CreateTweet(ctx, repositories.TestCreateTweetRequest, repositories.TestUser)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// Reopen the file as a restarted server would
+	repo, err = repositories.NewBoltTweetRepository(openBoltDB(t, path))
+	require.NoError(t, err)
+
+	tweetsPage, err := repo.GetTweetsPage(ctx, models.PageRequest{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, tweetsPage.Tweets, 1)
+	assert.Equal(t, createdTweet.ID, tweetsPage.Tweets[0].ID)
+}
+
+func TestBoltTokenRepository(t *testing.T) {
+	repo, err := repositories.NewBoltTokenRepository(openBoltDB(t, filepath.Join(t.TempDir(), "tokens.db")))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	user := repositories.TestUser
+	user.ID = "user-id"
+	token := models.APIToken{ID: "token-id", Name: "bot", Hash: "hash", User: user, Scopes: []string{models.ScopeTweetsWrite}}
+
+	// Test CreateToken
+	_, err = repo.CreateToken(ctx, token)
+	assert.NoError(t, err, "CreateToken should return the created token")
+	_, err = repo.CreateToken(ctx, token)
+	assert.ErrorIs(t, err, repoerrors.ErrConflict, "CreateToken should reject a duplicate token")
+
+	// Test GetTokensByUser
+	tokens, err := repo.GetTokensByUser(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1, "GetTokensByUser should return the user's token")
+	tokens, err = repo.GetTokensByUser(ctx, "other-user")
+	assert.NoError(t, err)
+	assert.Empty(t, tokens, "GetTokensByUser should not return tokens of other users")
+
+	// Test GetTokenByHash, the fields hidden from API responses are stored as well
+	foundToken, err := repo.GetTokenByHash(ctx, "hash")
+	require.NoError(t, err, "GetTokenByHash should find the token")
+	assert.Equal(t, token.ID, foundToken.ID)
+	assert.Equal(t, user.ID, foundToken.User.ID)
+	assert.Equal(t, "hash", foundToken.Hash)
+	assert.Equal(t, token.Scopes, foundToken.Scopes)
+	_, err = repo.GetTokenByHash(ctx, "unknown-hash")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "GetTokenByHash should return ErrNotFound for unknown hashes")
+
+	// Test DeleteToken
+	assert.ErrorIs(t, repo.DeleteToken(ctx, token.ID, "other-user"), repoerrors.ErrNotFound, "DeleteToken should not delete tokens of other users")
+	assert.NoError(t, repo.DeleteToken(ctx, token.ID, user.ID), "DeleteToken should delete the token")
+	_, err = repo.GetTokenByHash(ctx, "hash")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "Deleted token should not be found")
+}
@@ -0,0 +1,68 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDeadLetterStore adds, replaces and deletes letters of any store, letters of earlier
+// tests sharing the database are ignored
+func testDeadLetterStore(t *testing.T, store messaging.DeadLetterStore) {
+	ctx := context.Background()
+
+	// Whole seconds are kept by every store
+	failedAt := time.Now().UTC().Truncate(time.Second)
+	first := models.DeadLetter{
+		ID:       uuid.NewString(),
+		Topic:    messaging.TweetCreatedTopic,
+		Handler:  messaging.UpdateFeedsOnNewTweetCreated,
+		Reason:   "feed storage is down",
+		Payload:  `{"tweet":{"id":"tweet1"}}`,
+		Metadata: map[string]string{"content-type": messaging.JSONContentType},
+		FailedAt: failedAt,
+	}
+	second := first
+	second.ID = uuid.NewString()
+	second.FailedAt = failedAt.Add(time.Second)
+
+	require.NoError(t, store.AddDeadLetter(ctx, second))
+	require.NoError(t, store.AddDeadLetter(ctx, first))
+	t.Cleanup(func() {
+		store.DeleteDeadLetter(ctx, first.ID)
+		store.DeleteDeadLetter(ctx, second.ID)
+	})
+
+	// A redelivered letter replaces the stored one
+	first.Reason = "feed storage is still down"
+	require.NoError(t, store.AddDeadLetter(ctx, first))
+
+	found, err := store.GetDeadLetter(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.Reason, found.Reason)
+	assert.Equal(t, first.Payload, found.Payload)
+	assert.Equal(t, first.Metadata, found.Metadata)
+	assert.True(t, first.FailedAt.Equal(found.FailedAt), "Expected %v, got %v", first.FailedAt, found.FailedAt)
+
+	letters, err := store.GetDeadLetters(ctx)
+	require.NoError(t, err)
+	var ids []string
+	for _, letter := range letters {
+		if letter.ID == first.ID || letter.ID == second.ID {
+			ids = append(ids, letter.ID)
+		}
+	}
+	assert.Equal(t, []string{first.ID, second.ID}, ids, "Letters should be listed oldest first, once each")
+
+	require.NoError(t, store.DeleteDeadLetter(ctx, first.ID))
+	_, err = store.GetDeadLetter(ctx, first.ID)
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound)
+	assert.ErrorIs(t, store.DeleteDeadLetter(ctx, first.ID), repoerrors.ErrNotFound)
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+	"twitter-clone/internal/models"
+)
+
+// EventStore is the append-only history of the published tweet events, stored next to the tweets.
+// Sequences increase with every append but may have gaps. Appending an event whose ID is already
+// stored keeps the stored event, so events sent again by the outbox relay are recorded once.
+// Only the outbox relay appends, one replica at a time with the mysql and postgres storages, so
+// GetEvents never skips an event whose append committed late. It reports errors the same way as
+// TweetRepository.
+type EventStore interface {
+	AppendEvents(ctx context.Context, events []models.OutboxEvent) error
+	// GetEvents returns up to limit events with a sequence greater than since, oldest first
+	GetEvents(ctx context.Context, since int64, limit int) ([]models.StoredEvent, error)
+}
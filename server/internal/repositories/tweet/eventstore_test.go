@@ -0,0 +1,72 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+	repositories "twitter-clone/internal/repositories/tweet"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEventStore appends events to any store and reads them back, earlier events of a shared
+// database are skipped
+func testEventStore(t *testing.T, store repositories.EventStore) {
+	ctx := context.Background()
+
+	// Start after the events of earlier tests sharing the database
+	var since int64
+	for {
+		events, err := store.GetEvents(ctx, since, 100)
+		require.NoError(t, err)
+		if len(events) == 0 {
+			break
+		}
+		since = events[len(events)-1].Sequence
+	}
+
+	empty, err := store.GetEvents(ctx, since, 10)
+	require.NoError(t, err)
+	assert.NotNil(t, empty, "No events should be an empty list")
+	assert.Empty(t, empty)
+
+	createdAt := time.Now().UTC().Truncate(time.Second)
+	events := []models.OutboxEvent{
+		{ID: uuid.NewString(), Topic: messaging.TweetCreatedTopic, Payload: []byte(`{"id":1}`), CreatedAt: createdAt},
+		{ID: uuid.NewString(), Topic: messaging.TweetUpdatedTopic, Payload: []byte(`{"id":2}`), CreatedAt: createdAt},
+		{ID: uuid.NewString(), Topic: messaging.TweetDeletedTopic, Payload: []byte(`{"id":3}`), CreatedAt: createdAt},
+	}
+	require.NoError(t, store.AppendEvents(ctx, events[:2]))
+
+	// Resent events are recorded once
+	require.NoError(t, store.AppendEvents(ctx, events[1:]))
+	require.NoError(t, store.AppendEvents(ctx, nil))
+
+	stored, err := store.GetEvents(ctx, since, 10)
+	require.NoError(t, err)
+	require.Len(t, stored, 3, "Expected every event once")
+	for i, event := range events {
+		assert.Equal(t, event.ID, stored[i].ID)
+		assert.Equal(t, event.Topic, stored[i].Topic)
+		assert.Equal(t, event.Payload, stored[i].Payload)
+		assert.True(t, event.CreatedAt.Equal(stored[i].CreatedAt), "Expected %v, got %v", event.CreatedAt, stored[i].CreatedAt)
+		assert.Greater(t, stored[i].Sequence, since, "Sequences should increase")
+		since = stored[i].Sequence
+	}
+
+	// The limit keeps the oldest events and since skips up to the given sequence
+	first, err := store.GetEvents(ctx, stored[0].Sequence-1, 2)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, events[0].ID, first[0].ID)
+	assert.Equal(t, events[1].ID, first[1].ID)
+
+	rest, err := store.GetEvents(ctx, stored[1].Sequence, 10)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, events[2].ID, rest[0].ID)
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// deadLettersCollection keeps the dead letters keyed by their id
+const deadLettersCollection = "dead_letters"
+
+// FirestoreDeadLetterStore keeps the dead letters in a collection of the tweets project
+type FirestoreDeadLetterStore struct {
+	client *firestore.Client
+}
+
+func NewFirestoreDeadLetterStore(configuration config.Configuration) (*FirestoreDeadLetterStore, error) {
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, configuration.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FirestoreDeadLetterStore{client: client}, nil
+}
+
+func (r *FirestoreDeadLetterStore) AddDeadLetter(ctx context.Context, letter models.DeadLetter) error {
+	_, err := r.client.Collection(deadLettersCollection).Doc(letter.ID).Set(ctx, letter)
+	return firestoreError(err)
+}
+
+func (r *FirestoreDeadLetterStore) GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	letters := []models.DeadLetter{}
+	iter := r.client.Collection(deadLettersCollection).OrderBy("FailedAt", firestore.Asc).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, firestoreError(err)
+		}
+		var letter models.DeadLetter
+		if err := doc.DataTo(&letter); err != nil {
+			return nil, repoerrors.Unavailable(err)
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+func (r *FirestoreDeadLetterStore) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	doc, err := r.client.Collection(deadLettersCollection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, firestoreError(err)
+	}
+
+	var letter models.DeadLetter
+	if err := doc.DataTo(&letter); err != nil {
+		return nil, repoerrors.Unavailable(err)
+	}
+	return &letter, nil
+}
+
+func (r *FirestoreDeadLetterStore) DeleteDeadLetter(ctx context.Context, id string) error {
+	// The precondition reports a missing letter as not found
+	_, err := r.client.Collection(deadLettersCollection).Doc(id).Delete(ctx, firestore.Exists)
+	return firestoreError(err)
+}
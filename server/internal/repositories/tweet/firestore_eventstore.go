@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	eventsCollection   = "tweet_events" // Stored events keyed by their id
+	countersCollection = "counters"
+	eventsCounter      = "tweet_events" // Last assigned event sequence
+)
+
+// FirestoreEventStore numbers the events with a counter document updated in the appending transaction
+type FirestoreEventStore struct {
+	client *firestore.Client
+}
+
+func NewFirestoreEventStore(configuration config.Configuration) (*FirestoreEventStore, error) {
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, configuration.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FirestoreEventStore{client: client}, nil
+}
+
+func (r *FirestoreEventStore) AppendEvents(ctx context.Context, events []models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	counterRef := r.client.Collection(countersCollection).Doc(eventsCounter)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// Transactions read every document before writing
+		eventRefs := make([]*firestore.DocumentRef, len(events))
+		for i, event := range events {
+			eventRefs[i] = r.client.Collection(eventsCollection).Doc(event.ID)
+		}
+		snapshots, err := tx.GetAll(eventRefs)
+		if err != nil {
+			return err
+		}
+
+		var counter struct {
+			Sequence int64
+		}
+		counterSnapshot, err := tx.Get(counterRef)
+		switch {
+		case status.Code(err) == codes.NotFound:
+			// The first events start the counter
+		case err != nil:
+			return err
+		default:
+			if err := counterSnapshot.DataTo(&counter); err != nil {
+				return err
+			}
+		}
+
+		for i, event := range events {
+			if snapshots[i].Exists() {
+				continue
+			}
+			counter.Sequence++
+			err := tx.Create(eventRefs[i], models.StoredEvent{
+				Sequence:  counter.Sequence,
+				ID:        event.ID,
+				Topic:     event.Topic,
+				Payload:   event.Payload,
+				CreatedAt: event.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Set(counterRef, counter)
+	})
+	return firestoreError(err)
+}
+
+func (r *FirestoreEventStore) GetEvents(ctx context.Context, since int64, limit int) ([]models.StoredEvent, error) {
+	events := []models.StoredEvent{}
+	iter := r.client.Collection(eventsCollection).
+		Where("Sequence", ">", since).
+		OrderBy("Sequence", firestore.Asc).
+		Limit(limit).
+		Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, firestoreError(err)
+		}
+		var event models.StoredEvent
+		if err := doc.DataTo(&event); err != nil {
+			return nil, repoerrors.Unavailable(err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+type FirestoreTokenRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreTokenRepository(configuration config.Configuration) (*FirestoreTokenRepository, error) {
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, configuration.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FirestoreTokenRepository{client: client}, nil
+}
+
+func (r *FirestoreTokenRepository) CreateToken(ctx context.Context, token models.APIToken) (*models.APIToken, error) {
+	_, err := r.client.Collection("api_tokens").Doc(token.ID).Create(ctx, token)
+	if err != nil {
+		return nil, firestoreError(err)
+	}
+	return &token, nil
+}
+
+func (r *FirestoreTokenRepository) GetTokensByUser(ctx context.Context, userID string) ([]models.APIToken, error) {
+	return r.queryTokens(ctx, r.client.Collection("api_tokens").Where("User.ID", "==", userID))
+}
+
+func (r *FirestoreTokenRepository) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	tokens, err := r.queryTokens(ctx, r.client.Collection("api_tokens").Where("Hash", "==", hash).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, repoerrors.ErrNotFound
+	}
+	return &tokens[0], nil
+}
+
+func (r *FirestoreTokenRepository) DeleteToken(ctx context.Context, id string, userID string) error {
+	doc, err := r.client.Collection("api_tokens").Doc(id).Get(ctx)
+	if err != nil {
+		return firestoreError(err)
+	}
+
+	var token models.APIToken
+	if err := doc.DataTo(&token); err != nil {
+		return repoerrors.Unavailable(err)
+	}
+	if token.User.ID != userID {
+		return repoerrors.ErrNotFound
+	}
+
+	_, err = doc.Ref.Delete(ctx)
+	return firestoreError(err)
+}
+
+func (r *FirestoreTokenRepository) queryTokens(ctx context.Context, query firestore.Query) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	iter := query.Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, firestoreError(err)
+		}
+		var token models.APIToken
+		if err := doc.DataTo(&token); err != nil {
+			return nil, repoerrors.Unavailable(err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
@@ -2,12 +2,14 @@ package repositories
 
 import (
 	"context"
-	"log"
 	"twitter-clone/internal/config"
 	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type FirestoreTweetRepository struct {
@@ -24,53 +26,224 @@ func NewFirestoreTweetRepository(configuration config.Configuration) (*Firestore
 	return &FirestoreTweetRepository{client: client}, nil
 }
 
-func (r *FirestoreTweetRepository) CreateTweet(createTweetRequest models.CreateTweetRequest, user models.User) *models.Tweet {
+// firestoreError translates the gRPC status of a failed Firestore call into the repository errors
+func firestoreError(err error) error {
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.NotFound:
+		return repoerrors.ErrNotFound
+	case codes.AlreadyExists:
+		return repoerrors.ErrConflict
+	default:
+		return repoerrors.Unavailable(err)
+	}
+}
+
+// outboxCollection keeps the pending tweet events keyed by their id
+const outboxCollection = "tweet_outbox"
+
+func (r *FirestoreTweetRepository) CreateTweet(ctx context.Context, createTweetRequest models.CreateTweetRequest, user models.User) (*models.Tweet, error) {
 	tweet := CreateNewTweet(createTweetRequest, user)
-	_, err := r.client.Collection("tweets").Doc(tweet.ID).Set(context.Background(), tweet)
+	event, err := TweetCreatedOutboxEvent(tweet)
 	if err != nil {
-		log.Printf("Failed to create tweet: %v", err)
-		return nil
+		return nil, err
+	}
+
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Create(r.client.Collection("tweets").Doc(tweet.ID), tweet); err != nil {
+			return err
+		}
+		return tx.Create(r.client.Collection(outboxCollection).Doc(event.ID), event)
+	})
+	if err != nil {
+		return nil, firestoreError(err)
+	}
+	return &tweet, nil
+}
+
+func (r *FirestoreTweetRepository) GetTweets(ctx context.Context) ([]models.Tweet, error) {
+	return r.queryTweets(ctx, r.client.Collection("tweets").Query)
+}
+
+func (r *FirestoreTweetRepository) GetTweetsPage(ctx context.Context, page models.PageRequest) (*models.TweetsPage, error) {
+	// Tweets are stored without firestore tags, so the field paths follow the Go field names.
+	// This ordering requires a composite index on (CreatedAt.Time desc, ID desc).
+	query := r.client.Collection("tweets").
+		OrderBy("CreatedAt.Time", firestore.Desc).
+		OrderBy("ID", firestore.Desc)
+
+	if page.Cursor != nil {
+		query = query.StartAfter(page.Cursor.CreatedAt, page.Cursor.ID)
+	}
+
+	tweets, err := r.queryTweets(ctx, query.Limit(page.Limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	pageTweets, nextCursor := models.TrimPage(tweets, page.Limit)
+	return &models.TweetsPage{Tweets: pageTweets, NextCursor: nextCursor}, nil
+}
+
+func (r *FirestoreTweetRepository) GetTweetsByTag(ctx context.Context, tag string, page models.PageRequest) (*models.TweetsPage, error) {
+	// This ordering requires a composite index on (Tags array-contains, CreatedAt.Time desc, ID desc).
+	query := r.client.Collection("tweets").
+		Where("Tags", "array-contains", tag).
+		OrderBy("CreatedAt.Time", firestore.Desc).
+		OrderBy("ID", firestore.Desc)
+
+	if page.Cursor != nil {
+		query = query.StartAfter(page.Cursor.CreatedAt, page.Cursor.ID)
+	}
+
+	tweets, err := r.queryTweets(ctx, query.Limit(page.Limit+1))
+	if err != nil {
+		return nil, err
 	}
-	return &tweet
+
+	pageTweets, nextCursor := models.TrimPage(tweets, page.Limit)
+	return &models.TweetsPage{Tweets: pageTweets, NextCursor: nextCursor}, nil
 }
 
-func (r *FirestoreTweetRepository) GetTweets() []models.Tweet {
+func (r *FirestoreTweetRepository) queryTweets(ctx context.Context, query firestore.Query) ([]models.Tweet, error) {
 	var tweets []models.Tweet
-	iter := r.client.Collection("tweets").Documents(context.Background())
+	iter := query.Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			log.Printf("Failed to fetch tweet: %v", err)
-			return nil
+			return nil, firestoreError(err)
 		}
 		var tweet models.Tweet
 		if err := doc.DataTo(&tweet); err != nil {
-			log.Printf("Failed to decode tweet: %v", err)
-			return nil
+			return nil, repoerrors.Unavailable(err)
 		}
 		tweets = append(tweets, tweet)
 	}
-	return tweets
+	return tweets, nil
 }
 
-func (r *FirestoreTweetRepository) GetTweetById(id string) *models.Tweet {
-	doc, err := r.client.Collection("tweets").Doc(id).Get(context.Background())
+func (r *FirestoreTweetRepository) GetTweetById(ctx context.Context, id string) (*models.Tweet, error) {
+	doc, err := r.client.Collection("tweets").Doc(id).Get(ctx)
 	if err != nil {
-		log.Printf("Failed to fetch tweet by ID: %v", err)
-		return nil
+		return nil, firestoreError(err)
 	}
 	var tweet models.Tweet
 	if err := doc.DataTo(&tweet); err != nil {
-		log.Printf("Failed to decode tweet: %v", err)
-		return nil
+		return nil, repoerrors.Unavailable(err)
+	}
+	return &tweet, nil
+}
+
+func (r *FirestoreTweetRepository) UpdateTweet(ctx context.Context, id string, updateTweetRequest models.UpdateTweetRequest, check TweetCheck) (*models.Tweet, error) {
+	var tweet models.Tweet
+	var checkErr error
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		ref := r.client.Collection("tweets").Doc(id)
+		existingTweet, err := getTweetInTransaction(tx, ref)
+		if err != nil {
+			return err
+		}
+		if checkErr = CheckTweet(check, existingTweet); checkErr != nil {
+			return checkErr
+		}
+
+		tweet = ApplyUpdateTweetRequest(existingTweet, updateTweetRequest)
+		event, err := TweetUpdatedOutboxEvent(existingTweet, tweet)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Set(ref, tweet); err != nil {
+			return err
+		}
+		return tx.Create(r.client.Collection(outboxCollection).Doc(event.ID), event)
+	})
+	if checkErr != nil {
+		return nil, checkErr
+	}
+	if err != nil {
+		return nil, firestoreError(err)
+	}
+	return &tweet, nil
+}
+
+func (r *FirestoreTweetRepository) DeleteTweet(ctx context.Context, id string, check TweetCheck) error {
+	var checkErr error
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		ref := r.client.Collection("tweets").Doc(id)
+		deletedTweet, err := getTweetInTransaction(tx, ref)
+		if err != nil {
+			return err
+		}
+		if checkErr = CheckTweet(check, deletedTweet); checkErr != nil {
+			return checkErr
+		}
+
+		event, err := TweetDeletedOutboxEvent(deletedTweet)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(ref); err != nil {
+			return err
+		}
+		return tx.Create(r.client.Collection(outboxCollection).Doc(event.ID), event)
+	})
+	if checkErr != nil {
+		return checkErr
+	}
+	return firestoreError(err)
+}
+
+// getTweetInTransaction reads the tweet, so the transaction fails when it changes concurrently
+func getTweetInTransaction(tx *firestore.Transaction, ref *firestore.DocumentRef) (models.Tweet, error) {
+	var tweet models.Tweet
+	doc, err := tx.Get(ref)
+	if err != nil {
+		return tweet, err
+	}
+	if err := doc.DataTo(&tweet); err != nil {
+		return tweet, repoerrors.Unavailable(err)
+	}
+	return tweet, nil
+}
+
+func (r *FirestoreTweetRepository) GetOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	events := []models.OutboxEvent{}
+	iter := r.client.Collection(outboxCollection).OrderBy("CreatedAt", firestore.Asc).Limit(limit).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, firestoreError(err)
+		}
+		var event models.OutboxEvent
+		if err := doc.DataTo(&event); err != nil {
+			return nil, repoerrors.Unavailable(err)
+		}
+		events = append(events, event)
 	}
-	return &tweet
+	return events, nil
+}
+
+func (r *FirestoreTweetRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		for _, id := range ids {
+			if err := tx.Delete(r.client.Collection(outboxCollection).Doc(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return firestoreError(err)
 }
 
-func (r *FirestoreTweetRepository) DeleteTweet(id string) bool {
-	_, err := r.client.Collection("tweets").Doc(id).Delete(context.Background())
-	return err == nil
+func (r *FirestoreTweetRepository) ForwardOutboxEvents(ctx context.Context, limit int, forward func(events []models.OutboxEvent) ([]string, error)) error {
+	return forwardOutboxEvents(ctx, r, limit, forward)
 }
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+)
+
+// InMemoryDeadLetterStore is safe for concurrent use, its letters are lost on restart
+type InMemoryDeadLetterStore struct {
+	mutex   sync.RWMutex
+	letters []models.DeadLetter
+}
+
+func (store *InMemoryDeadLetterStore) AddDeadLetter(ctx context.Context, letter models.DeadLetter) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	letter.Metadata = maps.Clone(letter.Metadata)
+	store.letters = slices.DeleteFunc(store.letters, func(existing models.DeadLetter) bool {
+		return existing.ID == letter.ID
+	})
+	store.letters = append(store.letters, letter)
+	return nil
+}
+
+func (store *InMemoryDeadLetterStore) GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	letters := slices.Clone(store.letters)
+	for i := range letters {
+		letters[i].Metadata = maps.Clone(letters[i].Metadata)
+	}
+	sortDeadLetters(letters)
+	return letters, nil
+}
+
+func (store *InMemoryDeadLetterStore) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	index := slices.IndexFunc(store.letters, func(letter models.DeadLetter) bool {
+		return letter.ID == id
+	})
+	if index < 0 {
+		return nil, repoerrors.ErrNotFound
+	}
+
+	letter := store.letters[index]
+	letter.Metadata = maps.Clone(letter.Metadata)
+	return &letter, nil
+}
+
+func (store *InMemoryDeadLetterStore) DeleteDeadLetter(ctx context.Context, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	count := len(store.letters)
+	store.letters = slices.DeleteFunc(store.letters, func(letter models.DeadLetter) bool {
+		return letter.ID == id
+	})
+	if len(store.letters) == count {
+		return repoerrors.ErrNotFound
+	}
+	return nil
+}
+
+// sortDeadLetters orders the letters by the time they failed, like the SQL stores
+func sortDeadLetters(letters []models.DeadLetter) {
+	slices.SortStableFunc(letters, func(a, b models.DeadLetter) int {
+		return cmp.Or(a.FailedAt.Compare(b.FailedAt), cmp.Compare(a.ID, b.ID))
+	})
+}
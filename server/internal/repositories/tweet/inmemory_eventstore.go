@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"twitter-clone/internal/models"
+)
+
+// InMemoryEventStore is safe for concurrent use, its sequences have no gaps
+type InMemoryEventStore struct {
+	mutex  sync.RWMutex
+	events []models.StoredEvent
+	ids    map[string]struct{}
+}
+
+func (store *InMemoryEventStore) AppendEvents(ctx context.Context, events []models.OutboxEvent) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.ids == nil {
+		store.ids = map[string]struct{}{}
+	}
+
+	for _, event := range events {
+		if _, found := store.ids[event.ID]; found {
+			continue
+		}
+		store.ids[event.ID] = struct{}{}
+		store.events = append(store.events, models.StoredEvent{
+			Sequence:  int64(len(store.events) + 1),
+			ID:        event.ID,
+			Topic:     event.Topic,
+			Payload:   slices.Clone(event.Payload),
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	return nil
+}
+
+func (store *InMemoryEventStore) GetEvents(ctx context.Context, since int64, limit int) ([]models.StoredEvent, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	// The sequence of an event is its position plus one
+	start := min(max(since, 0), int64(len(store.events)))
+	end := min(start+int64(max(limit, 0)), int64(len(store.events)))
+
+	events := append([]models.StoredEvent{}, store.events[start:end]...)
+	for i := range events {
+		events[i].Payload = slices.Clone(events[i].Payload)
+	}
+	return events, nil
+}
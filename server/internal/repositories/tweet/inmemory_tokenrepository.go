@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+)
+
+// InMemoryTokenRepository is safe for concurrent use
+type InMemoryTokenRepository struct {
+	mutex  sync.RWMutex
+	tokens []models.APIToken
+}
+
+func (repo *InMemoryTokenRepository) CreateToken(ctx context.Context, token models.APIToken) (*models.APIToken, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	idx := slices.IndexFunc(repo.tokens, func(t models.APIToken) bool { return t.ID == token.ID || t.Hash == token.Hash })
+	if idx != -1 {
+		return nil, repoerrors.ErrConflict
+	}
+
+	repo.tokens = append(repo.tokens, token)
+	return &token, nil
+}
+
+func (repo *InMemoryTokenRepository) GetTokensByUser(ctx context.Context, userID string) ([]models.APIToken, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var tokens []models.APIToken
+	for _, token := range repo.tokens {
+		if token.User.ID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (repo *InMemoryTokenRepository) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	idx := slices.IndexFunc(repo.tokens, func(t models.APIToken) bool { return t.Hash == hash })
+	if idx == -1 {
+		return nil, repoerrors.ErrNotFound
+	}
+
+	token := repo.tokens[idx]
+	return &token, nil
+}
+
+func (repo *InMemoryTokenRepository) DeleteToken(ctx context.Context, id string, userID string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	idx := slices.IndexFunc(repo.tokens, func(t models.APIToken) bool { return t.ID == id && t.User.ID == userID })
+	if idx == -1 {
+		return repoerrors.ErrNotFound
+	}
+
+	repo.tokens = slices.Delete(repo.tokens, idx, idx+1)
+	return nil
+}
@@ -0,0 +1,48 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+	repositories "twitter-clone/internal/repositories/tweet"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryTokenRepository(t *testing.T) {
+	// Initialize the repository
+	repo := repositories.InMemoryTokenRepository{}
+	ctx := context.Background()
+
+	user := repositories.TestUser
+	user.ID = "user-id"
+	token := models.APIToken{ID: "token-id", Name: "bot", Hash: "hash", User: user}
+
+	// Test CreateToken
+	_, err := repo.CreateToken(ctx, token)
+	assert.NoError(t, err, "CreateToken should return the created token")
+	_, err = repo.CreateToken(ctx, token)
+	assert.ErrorIs(t, err, repoerrors.ErrConflict, "CreateToken should reject a duplicate token")
+
+	// Test GetTokensByUser
+	tokens, err := repo.GetTokensByUser(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1, "GetTokensByUser should return the user's token")
+	tokens, err = repo.GetTokensByUser(ctx, "other-user")
+	assert.NoError(t, err)
+	assert.Empty(t, tokens, "GetTokensByUser should not return tokens of other users")
+
+	// Test GetTokenByHash
+	foundToken, err := repo.GetTokenByHash(ctx, "hash")
+	assert.NoError(t, err, "GetTokenByHash should find the token")
+	assert.Equal(t, token.ID, foundToken.ID, "Found token should have the same ID")
+	_, err = repo.GetTokenByHash(ctx, "unknown-hash")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "GetTokenByHash should return ErrNotFound for unknown hashes")
+
+	// Test DeleteToken
+	assert.ErrorIs(t, repo.DeleteToken(ctx, token.ID, "other-user"), repoerrors.ErrNotFound, "DeleteToken should not delete tokens of other users")
+	assert.NoError(t, repo.DeleteToken(ctx, token.ID, user.ID), "DeleteToken should delete the token")
+	_, err = repo.GetTokenByHash(ctx, "hash")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "Deleted token should not be found")
+}
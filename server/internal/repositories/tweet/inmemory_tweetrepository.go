@@ -1,46 +1,196 @@
 package repositories
 
 import (
+	"context"
 	"slices"
+	"sync"
 	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
 )
 
+// InMemoryTweetRepository is safe for concurrent use. Tweets are indexed by ID and by tag,
+// and callers always receive copies so they never observe later modifications.
 type InMemoryTweetRepository struct {
-	tweets []models.Tweet
+	mutex  sync.RWMutex
+	tweets map[string]models.Tweet
+	tags   map[string]map[string]struct{} // Tweet IDs by tag
+	outbox []models.OutboxEvent
 }
 
-func (repo *InMemoryTweetRepository) CreateTweet(createTweetRequest models.CreateTweetRequest, user models.User) *models.Tweet {
-	tweet := CreateNewTweet(createTweetRequest, user)
-	idx := slices.IndexFunc(repo.tweets, func(t models.Tweet) bool { return t.ID == tweet.ID })
-	if idx != -1 {
-		return nil
+func (repo *InMemoryTweetRepository) CreateTweet(ctx context.Context, createTweetRequest models.CreateTweetRequest, user models.User) (*models.Tweet, error) {
+	tweet := CreateNewTweet(createTweetRequest, user).Clone()
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, found := repo.tweets[tweet.ID]; found {
+		return nil, repoerrors.ErrConflict
+	}
+
+	event, err := TweetCreatedOutboxEvent(tweet)
+	if err != nil {
+		return nil, err
+	}
+
+	if repo.tweets == nil {
+		repo.tweets = map[string]models.Tweet{}
 	}
+	repo.tweets[tweet.ID] = tweet
+	repo.indexTags(tweet)
+	repo.outbox = append(repo.outbox, event)
 
-	repo.tweets = append(repo.tweets, tweet)
-	return &tweet
+	createdTweet := tweet.Clone()
+	return &createdTweet, nil
 }
 
-func (repo *InMemoryTweetRepository) GetTweets() []models.Tweet {
-	return repo.tweets
+// GetTweets returns the tweets in the order they were created
+func (repo *InMemoryTweetRepository) GetTweets(ctx context.Context) ([]models.Tweet, error) {
+	tweets := repo.snapshot()
+	models.SortTweetsNewestFirst(tweets)
+	slices.Reverse(tweets)
+	return tweets, nil
+}
+
+func (repo *InMemoryTweetRepository) GetTweetsPage(ctx context.Context, page models.PageRequest) (*models.TweetsPage, error) {
+	tweets := repo.snapshot()
+	models.SortTweetsNewestFirst(tweets)
+
+	pageTweets, nextCursor := models.PaginateTweets(tweets, page)
+	return &models.TweetsPage{Tweets: pageTweets, NextCursor: nextCursor}, nil
+}
+
+// GetTweetsByTag reads the tweets from the tag index
+func (repo *InMemoryTweetRepository) GetTweetsByTag(ctx context.Context, tag string, page models.PageRequest) (*models.TweetsPage, error) {
+	repo.mutex.RLock()
+	tweets := make([]models.Tweet, 0, len(repo.tags[tag]))
+	for id := range repo.tags[tag] {
+		tweets = append(tweets, repo.tweets[id].Clone())
+	}
+	repo.mutex.RUnlock()
+
+	models.SortTweetsNewestFirst(tweets)
+	pageTweets, nextCursor := models.PaginateTweets(tweets, page)
+	return &models.TweetsPage{Tweets: pageTweets, NextCursor: nextCursor}, nil
 }
 
-func (repo *InMemoryTweetRepository) GetTweetById(id string) *models.Tweet {
-	idx := slices.IndexFunc(repo.tweets, func(t models.Tweet) bool { return t.ID == id })
-	if idx == -1 {
-		return nil
+func (repo *InMemoryTweetRepository) GetTweetById(ctx context.Context, id string) (*models.Tweet, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	tweet, found := repo.tweets[id]
+	if !found {
+		return nil, repoerrors.ErrNotFound
 	}
 
-	return &repo.tweets[idx]
+	tweet = tweet.Clone()
+	return &tweet, nil
 }
 
-func (repo *InMemoryTweetRepository) DeleteTweet(id string) bool {
-	idx := slices.IndexFunc(repo.tweets, func(t models.Tweet) bool { return t.ID == id })
-	if idx == -1 {
-		return false
+func (repo *InMemoryTweetRepository) UpdateTweet(ctx context.Context, id string, updateTweetRequest models.UpdateTweetRequest, check TweetCheck) (*models.Tweet, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	tweet, found := repo.tweets[id]
+	if !found {
+		return nil, repoerrors.ErrNotFound
+	}
+	if err := CheckTweet(check, tweet.Clone()); err != nil {
+		return nil, err
+	}
+
+	updatedTweet := ApplyUpdateTweetRequest(tweet.Clone(), updateTweetRequest).Clone()
+	event, err := TweetUpdatedOutboxEvent(tweet, updatedTweet)
+	if err != nil {
+		return nil, err
 	}
 
-	repo.tweets[idx] = repo.tweets[len(repo.tweets)-1]
-	repo.tweets = repo.tweets[:len(repo.tweets)-1]
+	repo.unindexTags(tweet)
+	tweet = updatedTweet
+	repo.tweets[id] = tweet
+	repo.indexTags(tweet)
+	repo.outbox = append(repo.outbox, event)
+
+	updatedTweet = tweet.Clone()
+	return &updatedTweet, nil
+}
+
+func (repo *InMemoryTweetRepository) DeleteTweet(ctx context.Context, id string, check TweetCheck) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	tweet, found := repo.tweets[id]
+	if !found {
+		return repoerrors.ErrNotFound
+	}
+	if err := CheckTweet(check, tweet.Clone()); err != nil {
+		return err
+	}
 
-	return true
+	event, err := TweetDeletedOutboxEvent(tweet)
+	if err != nil {
+		return err
+	}
+
+	repo.unindexTags(tweet)
+	delete(repo.tweets, id)
+	repo.outbox = append(repo.outbox, event)
+
+	return nil
+}
+
+func (repo *InMemoryTweetRepository) GetOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	return slices.Clone(repo.outbox[:min(limit, len(repo.outbox))]), nil
+}
+
+func (repo *InMemoryTweetRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.outbox = slices.DeleteFunc(repo.outbox, func(event models.OutboxEvent) bool {
+		return slices.Contains(ids, event.ID)
+	})
+	return nil
+}
+
+func (repo *InMemoryTweetRepository) ForwardOutboxEvents(ctx context.Context, limit int, forward func(events []models.OutboxEvent) ([]string, error)) error {
+	return forwardOutboxEvents(ctx, repo, limit, forward)
+}
+
+// snapshot copies all tweets so they can be sorted and returned without holding the lock
+func (repo *InMemoryTweetRepository) snapshot() []models.Tweet {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	tweets := make([]models.Tweet, 0, len(repo.tweets))
+	for _, tweet := range repo.tweets {
+		tweets = append(tweets, tweet.Clone())
+	}
+	return tweets
+}
+
+// indexTags adds the tweet to the tag index, the caller must hold the write lock
+func (repo *InMemoryTweetRepository) indexTags(tweet models.Tweet) {
+	if repo.tags == nil {
+		repo.tags = map[string]map[string]struct{}{}
+	}
+
+	for _, tag := range tweet.Tags {
+		if repo.tags[tag] == nil {
+			repo.tags[tag] = map[string]struct{}{}
+		}
+		repo.tags[tag][tweet.ID] = struct{}{}
+	}
+}
+
+// unindexTags removes the tweet from the tag index, the caller must hold the write lock
+func (repo *InMemoryTweetRepository) unindexTags(tweet models.Tweet) {
+	for _, tag := range tweet.Tags {
+		delete(repo.tags[tag], tweet.ID)
+		if len(repo.tags[tag]) == 0 {
+			delete(repo.tags, tag)
+		}
+	}
 }
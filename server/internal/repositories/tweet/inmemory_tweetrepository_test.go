@@ -1,46 +1,216 @@
 package repositories_test
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
 	repositories "twitter-clone/internal/repositories/tweet"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInMemoryTweetRepository(t *testing.T) {
 	// Initialize the repository
 	repo := repositories.InMemoryTweetRepository{}
+	ctx := context.Background()
 
 	tweet := repositories.TestCreateTweetRequest
 	user := repositories.TestUser
 
 	// Test CreateTweet
-	createdTweet := repo.CreateTweet(tweet, user)
-	assert.NotNil(t, createdTweet, "CreateTweet should return the created tweet")
+	createdTweet, err := repo.CreateTweet(ctx, tweet, user)
+	require.NoError(t, err, "CreateTweet should return the created tweet")
 	tweetID := createdTweet.ID
 
 	// Test GetTweets
-	allTweets := repo.GetTweets()
+	allTweets, err := repo.GetTweets(ctx)
+	assert.NoError(t, err)
 	assert.Len(t, allTweets, 1, "GetTweets should return a single tweet")
 
 	// Test GetTweetById
-	foundTweet := repo.GetTweetById(tweetID)
-	assert.NotNil(t, foundTweet, "GetTweetById should find the tweet")
+	foundTweet, err := repo.GetTweetById(ctx, tweetID)
+	assert.NoError(t, err, "GetTweetById should find the tweet")
 	assert.Equal(t, tweetID, foundTweet.ID, "Found tweet should have the same ID")
 
-	// Attempt to get a non-existing tweet by ID (should return nil)
-	nonExistingTweet := repo.GetTweetById("non-existing-id")
-	assert.Nil(t, nonExistingTweet, "GetTweetById should return nil for non-existing tweet")
+	// Attempt to get a non-existing tweet by ID
+	_, err = repo.GetTweetById(ctx, "non-existing-id")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "GetTweetById should return ErrNotFound for non-existing tweet")
+
+	// Test UpdateTweet
+	updateTweetRequest := models.UpdateTweetRequest{
+		Title:   "new title",
+		Content: "new content",
+		Tags:    []string{"tag2"},
+	}
+	updatedTweet, err := repo.UpdateTweet(ctx, tweetID, updateTweetRequest, nil)
+	require.NoError(t, err, "UpdateTweet should return the updated tweet")
+	assert.Equal(t, updateTweetRequest.Tags, updatedTweet.Tags, "Updated tweet should have the new tags")
+	assert.Equal(t, createdTweet.CreatedAt, updatedTweet.CreatedAt, "Updated tweet should keep its creation time")
+	foundTweet, err = repo.GetTweetById(ctx, tweetID)
+	assert.NoError(t, err)
+	assert.Equal(t, "new content", foundTweet.Content, "GetTweetById should return the updated tweet")
+
+	// Attempt to update a non-existing tweet
+	_, err = repo.UpdateTweet(ctx, "non-existing-id", updateTweetRequest, nil)
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "UpdateTweet should return ErrNotFound for non-existing tweet")
 
 	// Test DeleteTweet
-	deleted := repo.DeleteTweet(tweetID)
-	assert.True(t, deleted, "DeleteTweet should return true for successful deletion")
+	err = repo.DeleteTweet(ctx, tweetID, nil)
+	assert.NoError(t, err, "DeleteTweet should succeed for an existing tweet")
 
-	// Attempt to delete the same tweet again (should return false)
-	nonExistingDelete := repo.DeleteTweet(tweetID)
-	assert.False(t, nonExistingDelete, "DeleteTweet should return false for non-existing tweet deletion")
+	// Attempt to delete the same tweet again
+	err = repo.DeleteTweet(ctx, tweetID, nil)
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "DeleteTweet should return ErrNotFound for non-existing tweet deletion")
 
 	// Ensure no tweets are left after deletion
-	remainingTweets := repo.GetTweets()
+	remainingTweets, err := repo.GetTweets(ctx)
+	assert.NoError(t, err)
 	assert.Len(t, remainingTweets, 0, "GetTweets should return no tweets after deletion")
 }
+
+func TestInMemoryTweetRepository_GetTweetsPage(t *testing.T) {
+	repo := repositories.InMemoryTweetRepository{}
+	ctx := context.Background()
+
+	for range 5 {
+		_, err := repo.CreateTweet(ctx, repositories.TestCreateTweetRequest, repositories.TestUser)
+		assert.NoError(t, err)
+	}
+
+	// Walk through all pages and collect tweets
+	var pagedTweets []models.Tweet
+	page := models.PageRequest{Limit: 2}
+	for pages := 1; ; pages++ {
+		tweetsPage, err := repo.GetTweetsPage(ctx, page)
+		require.NoError(t, err, "GetTweetsPage should return a page")
+		assert.LessOrEqual(t, len(tweetsPage.Tweets), 2, "Page should not exceed the limit")
+		pagedTweets = append(pagedTweets, tweetsPage.Tweets...)
+
+		if tweetsPage.NextCursor == "" {
+			assert.Equal(t, 3, pages, "Expected 5 tweets to be split into 3 pages")
+			break
+		}
+
+		cursor, err := models.DecodeCursor(tweetsPage.NextCursor)
+		assert.NoError(t, err, "Next cursor should be decodable")
+		page.Cursor = cursor
+	}
+
+	// Pages should cover every tweet exactly once, newest first
+	assert.Len(t, pagedTweets, 5)
+	for i := 1; i < len(pagedTweets); i++ {
+		assert.True(t, models.CursorOf(pagedTweets[i-1]).Follows(pagedTweets[i]), "Tweets should be ordered newest first")
+	}
+}
+
+func TestInMemoryTweetRepository_GetTweetsByTag(t *testing.T) {
+	testGetTweetsByTag(t, &repositories.InMemoryTweetRepository{})
+}
+
+func TestInMemoryTweetRepository_UserSubjects(t *testing.T) {
+	testUserSubjects(t, &repositories.InMemoryTweetRepository{})
+}
+
+func TestInMemoryTweetRepository_OutboxEvents(t *testing.T) {
+	testOutboxEvents(t, &repositories.InMemoryTweetRepository{})
+}
+
+func TestInMemoryTweetRepository_TagsHashtags(t *testing.T) {
+	repo := repositories.InMemoryTweetRepository{}
+	ctx := context.Background()
+
+	tweet, err := repo.CreateTweet(ctx, models.CreateTweetRequest{
+		Title:   "Release #Go",
+		Content: "Read https://go.dev/doc#news and try `#define`, #GoLang #go",
+		Tags:    []string{" News", "GO"},
+	}, repositories.TestUser)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"news", "go", "golang"}, tweet.Tags, "Hashtags should be merged with the explicit tags and normalized")
+
+	page, err := repo.GetTweetsByTag(ctx, "golang", models.PageRequest{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Tweets, 1, "Tweet should be found by its hashtag")
+
+	updated, err := repo.UpdateTweet(ctx, tweet.ID, models.UpdateTweetRequest{Title: "Release", Content: "Now #Rust"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rust"}, updated.Tags, "Updated hashtags should replace the tags")
+}
+
+func TestInMemoryEventStore(t *testing.T) {
+	testEventStore(t, &repositories.InMemoryEventStore{})
+}
+
+func TestInMemoryTweetRepository_ReturnsCopies(t *testing.T) {
+	repo := repositories.InMemoryTweetRepository{}
+	ctx := context.Background()
+
+	createdTweet, err := repo.CreateTweet(ctx, models.CreateTweetRequest{Content: "content", Tags: []string{"golang"}}, repositories.TestUser)
+	require.NoError(t, err)
+
+	// Modifying returned tweets must not change the stored tweet
+	createdTweet.Tags[0] = "modified"
+	foundTweet, err := repo.GetTweetById(ctx, createdTweet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"golang"}, foundTweet.Tags)
+
+	foundTweet.Content = "modified"
+	allTweets, err := repo.GetTweets(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "content", allTweets[0].Content)
+}
+
+// Run with -race to detect unsynchronized access
+func TestInMemoryTweetRepository_ConcurrentAccess(t *testing.T) {
+	repo := repositories.InMemoryTweetRepository{}
+	ctx := context.Background()
+
+	const workers = 8
+	const tweetsPerWorker = 50
+
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tweetsPerWorker {
+				request := models.CreateTweetRequest{
+					Content: fmt.Sprintf("tweet %d of worker %d", i, worker),
+					Tags:    []string{"golang", fmt.Sprintf("worker%d", worker)},
+				}
+				tweet, err := repo.CreateTweet(ctx, request, repositories.TestUser)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				_, err = repo.UpdateTweet(ctx, tweet.ID, models.UpdateTweetRequest{Content: "updated", Tags: []string{"golang"}}, nil)
+				assert.NoError(t, err)
+
+				_, err = repo.GetTweets(ctx)
+				assert.NoError(t, err)
+				_, err = repo.GetTweetsPage(ctx, models.PageRequest{Limit: 10})
+				assert.NoError(t, err)
+
+				// Delete every other tweet
+				if i%2 == 0 {
+					assert.NoError(t, repo.DeleteTweet(ctx, tweet.ID, nil))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	allTweets, err := repo.GetTweets(ctx)
+	require.NoError(t, err)
+	assert.Len(t, allTweets, workers*tweetsPerWorker/2, "Expected every other tweet to remain")
+	for _, tweet := range allTweets {
+		assert.Equal(t, "updated", tweet.Content)
+	}
+}
+
+func TestInMemoryDeadLetterStore(t *testing.T) {
+	testDeadLetterStore(t, &repositories.InMemoryDeadLetterStore{})
+}
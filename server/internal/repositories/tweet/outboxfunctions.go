@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"time"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TweetCreatedOutboxEvent(tweet models.Tweet) (models.OutboxEvent, error) {
+	occurredAt := time.Now().UTC()
+	return newOutboxEvent(messaging.TweetCreatedTopic, occurredAt, messaging.TweetCreated{
+		Tweet:      tweet,
+		OccurredAt: occurredAt,
+	})
+}
+
+func TweetUpdatedOutboxEvent(originalTweet models.Tweet, newTweet models.Tweet) (models.OutboxEvent, error) {
+	occurredAt := time.Now().UTC()
+	return newOutboxEvent(messaging.TweetUpdatedTopic, occurredAt, messaging.TweetUpdated{
+		OriginalTweet: originalTweet,
+		NewTweet:      newTweet,
+		OccurredAt:    occurredAt,
+	})
+}
+
+func TweetDeletedOutboxEvent(deletedTweet models.Tweet) (models.OutboxEvent, error) {
+	occurredAt := time.Now().UTC()
+	return newOutboxEvent(messaging.TweetDeletedTopic, occurredAt, messaging.TweetDeleted{
+		DeletedTweet: deletedTweet,
+		OccurredAt:   occurredAt,
+	})
+}
+
+// newOutboxEvent wraps the event in a CloudEvents JSON envelope whose id is the id of the published message.
+// Events are stored as JSON whatever the messaging encoding, the relay encodes them for publishing.
+func newOutboxEvent(topic string, occurredAt time.Time, event interface{}) (models.OutboxEvent, error) {
+	id := uuid.NewString()
+	payload, err := messaging.JSONCodec{}.Encode(topic, id, occurredAt, event)
+	if err != nil {
+		return models.OutboxEvent{}, err
+	}
+
+	return models.OutboxEvent{
+		ID:        id,
+		Topic:     topic,
+		Payload:   payload,
+		CreatedAt: occurredAt,
+	}, nil
+}
+
+// outboxStore reads and deletes the outbox events of a storage without row locks
+type outboxStore interface {
+	GetOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	DeleteOutboxEvents(ctx context.Context, ids []string) error
+}
+
+// forwardOutboxEvents forwards the events of a storage without row locks. Embedded storages are used
+// by a single process, with firestore replicas may forward the same events at the same time.
+func forwardOutboxEvents(ctx context.Context, store outboxStore, limit int, forward func(events []models.OutboxEvent) ([]string, error)) error {
+	events, err := store.GetOutboxEvents(ctx, limit)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	forwarded, forwardErr := forward(events)
+	if len(forwarded) > 0 {
+		if err := store.DeleteOutboxEvents(ctx, forwarded); err != nil {
+			return err
+		}
+	}
+	return forwardErr
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+)
+
+// PersistentDeadLetterStore stores the dead letters in the MySQL database of the tweets
+type PersistentDeadLetterStore struct {
+	db *sql.DB
+}
+
+func NewPersistentDeadLetterStore(configuration config.Configuration) (*PersistentDeadLetterStore, error) {
+	db, err := openDatabase(configuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	return &PersistentDeadLetterStore{db: db}, nil
+}
+
+func (store *PersistentDeadLetterStore) AddDeadLetter(ctx context.Context, letter models.DeadLetter) error {
+	metadata, err := json.Marshal(letter.Metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.db.ExecContext(ctx, `
+	INSERT INTO dead_letters (id, topic, handler, reason, payload, metadata, failed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE topic = VALUES(topic), handler = VALUES(handler), reason = VALUES(reason),
+			payload = VALUES(payload), metadata = VALUES(metadata), failed_at = VALUES(failed_at)
+	`, letter.ID, letter.Topic, letter.Handler, letter.Reason, []byte(letter.Payload), metadata, letter.FailedAt)
+	if err != nil {
+		return mySQLError(err, "adding dead letter")
+	}
+
+	return nil
+}
+
+const selectDeadLettersSQL = `
+		SELECT id, topic, handler, reason, payload, metadata, failed_at FROM dead_letters`
+
+func (store *PersistentDeadLetterStore) GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	rows, err := store.db.QueryContext(ctx, selectDeadLettersSQL+`
+		ORDER BY failed_at, id`)
+	if err != nil {
+		return nil, mySQLError(err, "retrieving dead letters")
+	}
+	defer rows.Close()
+
+	letters := []models.DeadLetter{}
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, mySQLError(err, "scanning dead letter row")
+		}
+		letters = append(letters, letter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, mySQLError(err, "iterating over dead letter rows")
+	}
+
+	return letters, nil
+}
+
+func (store *PersistentDeadLetterStore) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	letter, err := scanDeadLetter(store.db.QueryRowContext(ctx, selectDeadLettersSQL+`
+		WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repoerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, mySQLError(err, "retrieving dead letter")
+	}
+
+	return &letter, nil
+}
+
+func (store *PersistentDeadLetterStore) DeleteDeadLetter(ctx context.Context, id string) error {
+	result, err := store.db.ExecContext(ctx, "DELETE FROM dead_letters WHERE id = ?", id)
+	if err != nil {
+		return mySQLError(err, "deleting dead letter")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return mySQLError(err, "getting rows affected after dead letter deletion")
+	}
+
+	if rowsAffected == 0 {
+		return repoerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanDeadLetter(row rowScanner) (models.DeadLetter, error) {
+	var letter models.DeadLetter
+	var payload, metadata []byte
+	var failedAt models.MySQLTimestamp
+
+	err := row.Scan(&letter.ID, &letter.Topic, &letter.Handler, &letter.Reason, &payload, &metadata, &failedAt)
+	if err != nil {
+		return letter, err
+	}
+
+	letter.Payload = string(payload)
+	letter.FailedAt = failedAt.Time
+	return letter, json.Unmarshal(metadata, &letter.Metadata)
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+)
+
+// PersistentEventStore stores the events in the MySQL database of the tweets, with the pool of the
+// tweet repository. Sequences come from AUTO_INCREMENT, so an append committing after a later one
+// would be skipped by readers which already passed its sequence. Only the outbox relay appends,
+// while it holds the locks of the forwarded outbox events, so appends never overlap.
+type PersistentEventStore struct {
+	db *sql.DB
+}
+
+func NewPersistentEventStore(configuration config.Configuration) (*PersistentEventStore, error) {
+	db, err := openDatabase(configuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	return &PersistentEventStore{db: db}, nil
+}
+
+func (store *PersistentEventStore) AppendEvents(ctx context.Context, events []models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(events))
+	args := make([]any, 0, len(events)*4)
+	for i, event := range events {
+		placeholders[i] = "(?, ?, ?, ?)"
+		args = append(args, event.ID, event.Topic, event.Payload, event.CreatedAt)
+	}
+
+	// Recorded events keep their sequence, the update is a no-op
+	_, err := store.db.ExecContext(ctx, `
+	INSERT INTO tweet_events (id, topic, payload, created_at)
+		VALUES `+strings.Join(placeholders, ", ")+`
+		ON DUPLICATE KEY UPDATE id = id
+	`, args...)
+	if err != nil {
+		return mySQLError(err, "appending events")
+	}
+
+	return nil
+}
+
+func (store *PersistentEventStore) GetEvents(ctx context.Context, since int64, limit int) ([]models.StoredEvent, error) {
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT sequence, id, topic, payload, created_at FROM tweet_events
+		WHERE sequence > ?
+		ORDER BY sequence
+		LIMIT ?`, since, limit)
+	if err != nil {
+		return nil, mySQLError(err, "retrieving events")
+	}
+	defer rows.Close()
+
+	events := []models.StoredEvent{}
+	for rows.Next() {
+		var event models.StoredEvent
+		var createdAt models.MySQLTimestamp
+		if err := rows.Scan(&event.Sequence, &event.ID, &event.Topic, &event.Payload, &createdAt); err != nil {
+			return nil, mySQLError(err, "scanning event row")
+		}
+		event.CreatedAt = createdAt.Time
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, mySQLError(err, "iterating over event rows")
+	}
+
+	return events, nil
+}
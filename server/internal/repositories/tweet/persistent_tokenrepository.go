@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/repoerrors"
+)
+
+type PersistentTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPersistentTokenRepository(configuration config.Configuration) (*PersistentTokenRepository, error) {
+	db, err := openDatabase(configuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	return &PersistentTokenRepository{db: db}, nil
+}
+
+func (repo *PersistentTokenRepository) CreateToken(ctx context.Context, token models.APIToken) (*models.APIToken, error) {
+	_, err := repo.db.ExecContext(ctx, `
+	INSERT INTO api_tokens (id, token_hash, name, scopes, user_id, first_name, last_name, email, picture, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, token.ID, token.Hash, token.Name, strings.Join(token.Scopes, ","),
+		token.User.ID, token.User.FirstName, token.User.LastName, token.User.Email, token.User.Picture,
+		token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return nil, mySQLError(err, "inserting API token")
+	}
+
+	return &token, nil
+}
+
+const selectTokensSQL = `
+		SELECT id, token_hash, name, scopes, user_id, first_name, last_name, email, picture, created_at, expires_at
+		FROM api_tokens`
+
+func scanToken(row rowScanner) (models.APIToken, error) {
+	var token models.APIToken
+	var scopes string
+	var createdAt models.MySQLTimestamp
+	var expiresAt sql.NullString
+
+	err := row.Scan(
+		&token.ID,
+		&token.Hash,
+		&token.Name,
+		&scopes,
+		&token.User.ID,
+		&token.User.FirstName,
+		&token.User.LastName,
+		&token.User.Email,
+		&token.User.Picture,
+		&createdAt,
+		&expiresAt,
+	)
+	if err != nil {
+		return token, err
+	}
+
+	token.CreatedAt = createdAt.Time
+	if scopes != "" {
+		token.Scopes = strings.Split(scopes, ",")
+	}
+	if expiresAt.Valid {
+		parsedExpiresAt, err := time.Parse("2006-01-02 15:04:05", expiresAt.String)
+		if err != nil {
+			return token, err
+		}
+		token.ExpiresAt = &parsedExpiresAt
+	}
+
+	return token, nil
+}
+
+func (repo *PersistentTokenRepository) GetTokensByUser(ctx context.Context, userID string) ([]models.APIToken, error) {
+	rows, err := repo.db.QueryContext(ctx, selectTokensSQL+`
+		WHERE user_id = ?
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, mySQLError(err, "retrieving API tokens")
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, mySQLError(err, "scanning API token row")
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, mySQLError(err, "iterating over API token rows")
+	}
+
+	return tokens, nil
+}
+
+func (repo *PersistentTokenRepository) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	row := repo.db.QueryRowContext(ctx, selectTokensSQL+`
+		WHERE token_hash = ?
+	`, hash)
+
+	token, err := scanToken(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repoerrors.ErrNotFound
+		}
+		return nil, mySQLError(err, "retrieving API token")
+	}
+
+	return &token, nil
+}
+
+func (repo *PersistentTokenRepository) DeleteToken(ctx context.Context, id string, userID string) error {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return mySQLError(err, "deleting API token")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return mySQLError(err, "getting rows affected after API token deletion")
+	}
+
+	if rowsAffected == 0 {
+		return repoerrors.ErrNotFound
+	}
+
+	return nil
+}
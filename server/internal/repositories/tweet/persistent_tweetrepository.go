@@ -1,14 +1,19 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"twitter-clone/internal/config"
 	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/mysqldb"
+	"twitter-clone/internal/repositories/repoerrors"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 )
 
@@ -36,248 +41,609 @@ func NewPersistentTweetRepository(configuration config.Configuration) (*Persiste
 }
 
 func (repo *PersistentTweetRepository) init(configuration config.Configuration) error {
-	// Get the connection string without the database name
-	connString := fmt.Sprintf("%s/", configuration.TweetsStorage.ConnectionString)
-	log.Println("Connecting without database:", connString)
-
-	// Open the database connection (without specifying a database)
-	db, err := sql.Open("mysql", connString)
+	db, err := openDatabase(configuration)
 	if err != nil {
 		return err
 	}
 
-	// Check if the database exists
-	dbName := configuration.TweetsStorage.DatabaseName
-	_, err = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", dbName))
-	if err != nil {
-		return fmt.Errorf("error creating database: %v", err)
+	repo.db = db
+
+	return nil
+}
+
+var (
+	databasesMutex sync.Mutex
+	databases      = map[string]*sql.DB{}
+)
+
+// openDatabase returns the pool of the tweets database. The tweet repository and the token, event
+// and dead letter stores share one pool per database, connected and migrated when first opened.
+func openDatabase(configuration config.Configuration) (*sql.DB, error) {
+	databasesMutex.Lock()
+	defer databasesMutex.Unlock()
+
+	key := configuration.TweetsStorage.ConnectionString + "/" + configuration.TweetsStorage.DatabaseName
+	if db, found := databases[key]; found {
+		return db, nil
 	}
 
-	// Now that the database exists, close the connection and reopen with the database name
-	err = db.Close()
+	db, err := connectDatabase(configuration)
 	if err != nil {
-		return fmt.Errorf("error closing connection: %v", err)
+		return nil, err
 	}
 
-	// Reconnect with the database specified
-	connStringWithDB := fmt.Sprintf("%s/%s", configuration.TweetsStorage.ConnectionString, dbName)
-	db, err = sql.Open("mysql", connStringWithDB)
+	databases[key] = db
+	return db, nil
+}
+
+// connectDatabase connects to the tweets database and brings its schema up to date when
+// automatic migrations are enabled, otherwise pending migrations are only reported
+func connectDatabase(configuration config.Configuration) (*sql.DB, error) {
+	db, err := mysqldb.Open(configuration.TweetsStorage.ConnectionString, configuration.TweetsStorage.DatabaseName)
 	if err != nil {
-		return fmt.Errorf("error reconnecting to database: %v", err)
+		return nil, err
 	}
 
-	// Ping the database to ensure connectivity
-	err = db.Ping()
+	migrator, err := mysqldb.NewMigrator(db)
 	if err != nil {
-		return err
+		db.Close()
+		return nil, err
 	}
 
-	repo.db = db
+	if configuration.TweetsStorage.AutoMigrate {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return db, nil
+	}
 
-	// SQL to create the 'tweets' table if it does not exist
-	createUsersTableSQL := `
-	CREATE TABLE IF NOT EXISTS users (
-		id VARCHAR(36) PRIMARY KEY,
-		first_name VARCHAR(255),
-		last_name VARCHAR(255),
-		email VARCHAR(255) UNIQUE,
-		picture TEXT
-	)`
-
-	_, err = repo.db.Exec(createUsersTableSQL)
+	pending, err := migrator.Pending(context.Background())
 	if err != nil {
-		log.Printf("Error creating 'users' table: %v", err)
-		return err
+		db.Close()
+		return nil, err
+	}
+	if len(pending) > 0 {
+		log.Printf("Tweets database has %d pending migrations, run the migrate command or enable TweetsStorage.AutoMigrate", len(pending))
 	}
 
-	createTweetsTableSQL := `
-	CREATE TABLE IF NOT EXISTS tweets (
-		id VARCHAR(36) PRIMARY KEY,
-		title VARCHAR(255),
-		content TEXT,
-		created_at TIMESTAMP,
-		user_id VARCHAR(36),
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-    	tags TEXT
-	)`
+	return db, nil
+}
 
-	_, err = repo.db.Exec(createTweetsTableSQL)
-	if err != nil {
-		log.Printf("Error creating 'tweets' table: %v", err)
-		return err
+// mySQLError translates duplicate key violations into repoerrors.ErrConflict,
+// any other failure is reported as unavailable storage
+func mySQLError(err error, action string) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return repoerrors.ErrConflict
 	}
 
-	return nil
+	return repoerrors.Unavailable(fmt.Errorf("error %s: %w", action, err))
 }
 
-func (repo *PersistentTweetRepository) CreateTweet(createTweetRequest models.CreateTweetRequest, user models.User) *models.Tweet {
+func (repo *PersistentTweetRepository) CreateTweet(ctx context.Context, createTweetRequest models.CreateTweetRequest, user models.User) (*models.Tweet, error) {
 	tweet := CreateNewTweet(createTweetRequest, user)
-	// Check if the tweet with the given ID already exists
-	if existingTweet := repo.GetTweetById(tweet.ID); existingTweet != nil {
-		log.Printf("Tweet with ID '%s' already exists", tweet.ID)
-		return nil
+
+	// The tweet and its tags are stored together
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, mySQLError(err, "starting transaction")
 	}
+	defer tx.Rollback()
 
-	// Insert or update the user
+	userID, err := upsertUser(ctx, tx, tweet.User)
+	if err != nil {
+		return nil, mySQLError(err, "upserting user")
+	}
 
-	// Check if user already exists by email
-	var userID string
-	err := repo.db.QueryRow("SELECT id FROM users WHERE email = ?", tweet.User.Email).Scan(&userID)
-	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Error checking user existence in database: %v", err)
-		return nil
+	// Insert the tweet with a reference to the user_id, a duplicate ID is reported as conflict
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO tweets (id, title, content, created_at, user_id) 
+		VALUES (?, ?, ?, ?, ?)
+	`, tweet.ID, tweet.Title, tweet.Content, tweet.CreatedAt.Time, userID)
+	if err != nil {
+		return nil, mySQLError(err, "inserting tweet")
 	}
 
-	// If the user does not exist, insert a new user
-	if err == sql.ErrNoRows {
-		userID = uuid.NewString()
-		_, err := repo.db.Exec(`
-        INSERT INTO users (id, first_name, last_name, email, picture) 
-        VALUES (?, ?, ?, ?, ?)
-    `, userID, tweet.User.FirstName, tweet.User.LastName, tweet.User.Email, tweet.User.Picture)
-		if err != nil {
-			log.Printf("Error inserting new user into database: %v", err)
-			return nil
-		}
+	if err := insertTags(ctx, tx, tweet.ID, tweet.Tags); err != nil {
+		return nil, mySQLError(err, "inserting tweet tags")
 	}
 
-	// Insert the tweet with a reference to the user_id
-	_, err = repo.db.Exec(`
-	INSERT INTO tweets (id, title, content, created_at, user_id, tags) 
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, tweet.ID, tweet.Title, tweet.Content, tweet.CreatedAt.Time, userID, strings.Join(tweet.Tags, ","))
+	event, err := TweetCreatedOutboxEvent(tweet)
 	if err != nil {
-		log.Printf("Error inserting tweet into database: %v", err)
-		return nil
+		return nil, err
+	}
+	if err := insertOutboxEvent(ctx, tx, event); err != nil {
+		return nil, mySQLError(err, "inserting outbox event")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, mySQLError(err, "committing tweet")
 	}
 
 	// Return the created tweet
-	return &tweet
+	return &tweet, nil
 }
 
-func (repo *PersistentTweetRepository) GetTweets() []models.Tweet {
-	// Query to fetch tweets along with user details
-	rows, err := repo.db.Query(`
-		SELECT t.id, t.title, t.content, t.created_at, 
-		       u.id AS user_id, u.first_name, u.last_name, u.email, u.picture,
-			   t.tags
-		FROM tweets t
-		JOIN users u ON t.user_id = u.id
-	`)
-	if err != nil {
-		log.Printf("Error retrieving tweets from database: %v", err)
-		return nil
+// upsertUser returns the internal key of the user with the given provider subject, creating
+// the row on first use and refreshing the profile otherwise. Users without a subject only
+// exist when authentication is disabled, each of their tweets gets a new row.
+func upsertUser(ctx context.Context, tx *sql.Tx, user models.User) (string, error) {
+	if user.ID == "" {
+		userID := uuid.NewString()
+		_, err := tx.ExecContext(ctx, `
+		INSERT INTO users (id, subject, first_name, last_name, email, picture)
+			VALUES (?, NULL, ?, ?, ?, ?)
+		`, userID, user.FirstName, user.LastName, user.Email, user.Picture)
+		return userID, err
 	}
-	defer rows.Close()
 
-	var tweets []models.Tweet
-	for rows.Next() {
-		var tweet models.Tweet
-		var user models.User
-		var userID string
-		var tags sql.NullString
-
-		// Scan the values from the row into the tweet and user structs
-		err := rows.Scan(
-			&tweet.ID,
-			&tweet.Title,
-			&tweet.Content,
-			&tweet.CreatedAt,
-			&userID,
-			&user.FirstName,
-			&user.LastName,
-			&user.Email,
-			&user.Picture,
-			&tags,
-		)
-		if err != nil {
-			log.Printf("Error scanning tweet row: %v", err)
-			return nil
-		}
+	if err := claimLegacyUser(ctx, tx, user); err != nil {
+		return "", err
+	}
 
-		// Set the user struct in the tweet and add to the tweets slice
-		tweet.User = user
-		if tags.Valid {
-			tweet.Tags = strings.Split(tags.String, ",") // Split tags into an array
-		} else {
-			tweet.Tags = []string{}
-		}
-		tweets = append(tweets, tweet)
+	_, err := tx.ExecContext(ctx, `
+	INSERT INTO users (id, subject, first_name, last_name, email, picture)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE first_name = VALUES(first_name), last_name = VALUES(last_name),
+			email = VALUES(email), picture = VALUES(picture)
+	`, uuid.NewString(), user.ID, user.FirstName, user.LastName, user.Email, user.Picture)
+	if err != nil {
+		return "", err
 	}
 
-	if err := rows.Err(); err != nil {
-		log.Printf("Error iterating over tweet rows: %v", err)
+	var userID string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM users WHERE subject = ?", user.ID).Scan(&userID)
+	return userID, err
+}
+
+// claimLegacyUser gives the row of a user stored before subjects were recorded the subject of the
+// first sign-in with its email, emails are only set when the provider verified them. Such rows
+// were keyed by email, so there is at most one per email.
+func claimLegacyUser(ctx context.Context, tx *sql.Tx, user models.User) error {
+	if user.Email == "" {
 		return nil
 	}
 
-	return tweets
+	var known int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE subject = ?", user.ID).Scan(&known); err != nil || known > 0 {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "UPDATE users SET subject = ? WHERE subject IS NULL AND email = ? LIMIT 1", user.ID, user.Email)
+	return err
 }
 
-func (repo *PersistentTweetRepository) GetTweetById(id string) *models.Tweet {
-	// Query to fetch a single tweet along with user details by tweet ID
-	row := repo.db.QueryRow(`
+const selectTweetsSQL = `
 		SELECT t.id, t.title, t.content, t.created_at, 
-		       u.id AS user_id, u.first_name, u.last_name, u.email, u.picture,
-			   t.tags
+		       u.subject, u.first_name, u.last_name, u.email, u.picture
 		FROM tweets t
-		JOIN users u ON t.user_id = u.id
-		WHERE t.id = ?
-	`, id)
+		JOIN users u ON t.user_id = u.id`
 
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTweet(row rowScanner) (models.Tweet, error) {
 	var tweet models.Tweet
 	var user models.User
-	var userID string
-	var tags sql.NullString
+	var subject sql.NullString
 
-	// Scan the result into the tweet and user structs
+	// Scan the values from the row into the tweet and user structs
 	err := row.Scan(
 		&tweet.ID,
 		&tweet.Title,
 		&tweet.Content,
 		&tweet.CreatedAt,
-		&userID,
+		&subject,
 		&user.FirstName,
 		&user.LastName,
 		&user.Email,
 		&user.Picture,
-		&tags,
 	)
+	if err != nil {
+		return tweet, err
+	}
+
+	// Set the user struct in the tweet, users are identified by their provider subject
+	user.ID = subject.String
+	tweet.User = user
+	tweet.Tags = []string{}
+
+	return tweet, nil
+}
+
+func scanTweets(rows *sql.Rows) ([]models.Tweet, error) {
+	defer rows.Close()
+
+	var tweets []models.Tweet
+	for rows.Next() {
+		tweet, err := scanTweet(rows)
+		if err != nil {
+			return nil, mySQLError(err, "scanning tweet row")
+		}
+		tweets = append(tweets, tweet)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, mySQLError(err, "iterating over tweet rows")
+	}
+
+	return tweets, nil
+}
+
+// insertTags stores the tags of a tweet in their given order
+func insertTags(ctx context.Context, tx *sql.Tx, tweetID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(tags))
+	args := make([]any, 0, len(tags)*3)
+	for i, tag := range tags {
+		placeholders[i] = "(?, ?, ?)"
+		args = append(args, tweetID, i+1, tag)
+	}
+
+	_, err := tx.ExecContext(ctx, "INSERT INTO tweet_tags (tweet_id, ordinal, tag) VALUES "+strings.Join(placeholders, ", "), args...)
+	return err
+}
+
+// assignTags reads tweet_id, tag rows ordered by ordinal into the tags of the matching tweets
+func assignTags(rows *sql.Rows, tweets []models.Tweet) error {
+	defer rows.Close()
+
+	indexes := make(map[string]int, len(tweets))
+	for i, tweet := range tweets {
+		indexes[tweet.ID] = i
+	}
+
+	for rows.Next() {
+		var tweetID, tag string
+		if err := rows.Scan(&tweetID, &tag); err != nil {
+			return mySQLError(err, "scanning tweet tag row")
+		}
+		if i, found := indexes[tweetID]; found {
+			tweets[i].Tags = append(tweets[i].Tags, tag)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return mySQLError(err, "iterating over tweet tag rows")
+	}
+
+	return nil
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// loadTags fills in the tags of the tweets with a single query
+func loadTags(ctx context.Context, q queryer, tweets []models.Tweet) error {
+	if len(tweets) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(tweets))
+	args := make([]any, len(tweets))
+	for i, tweet := range tweets {
+		placeholders[i] = "?"
+		args[i] = tweet.ID
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT tweet_id, tag FROM tweet_tags
+		WHERE tweet_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY tweet_id, ordinal`, args...)
+	if err != nil {
+		return mySQLError(err, "retrieving tweet tags")
+	}
+
+	return assignTags(rows, tweets)
+}
+
+func (repo *PersistentTweetRepository) GetTweets(ctx context.Context) ([]models.Tweet, error) {
+	// Query to fetch tweets along with user details
+	rows, err := repo.db.QueryContext(ctx, selectTweetsSQL)
+	if err != nil {
+		return nil, mySQLError(err, "retrieving tweets")
+	}
+
+	tweets, err := scanTweets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every tweet is returned, so the tags are read without filtering by ID
+	rows, err = repo.db.QueryContext(ctx, "SELECT tweet_id, tag FROM tweet_tags ORDER BY tweet_id, ordinal")
+	if err != nil {
+		return nil, mySQLError(err, "retrieving tweet tags")
+	}
+
+	if err := assignTags(rows, tweets); err != nil {
+		return nil, err
+	}
+
+	return tweets, nil
+}
+
+func (repo *PersistentTweetRepository) GetTweetsPage(ctx context.Context, page models.PageRequest) (*models.TweetsPage, error) {
+	return repo.queryTweetsPage(ctx, page, nil, nil)
+}
+
+// GetTweetsByTag answers the query from the tweet_tags index
+func (repo *PersistentTweetRepository) GetTweetsByTag(ctx context.Context, tag string, page models.PageRequest) (*models.TweetsPage, error) {
+	conditions := []string{"t.id IN (SELECT tweet_id FROM tweet_tags WHERE tag = ?)"}
+	return repo.queryTweetsPage(ctx, page, conditions, []any{tag})
+}
+
+// queryTweetsPage selects a page of the tweets matching all conditions, newest first
+func (repo *PersistentTweetRepository) queryTweetsPage(ctx context.Context, page models.PageRequest, conditions []string, args []any) (*models.TweetsPage, error) {
+	query := selectTweetsSQL
+
+	if page.Cursor != nil {
+		conditions = append(conditions, "(t.created_at, t.id) < (?, ?)")
+		args = append(args, page.Cursor.CreatedAt, page.Cursor.ID)
+	}
+
+	if len(conditions) > 0 {
+		query += `
+		WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	// One extra row tells whether there is a next page
+	query += `
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT ?`
+	args = append(args, page.Limit+1)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mySQLError(err, "retrieving tweets page")
+	}
+
+	tweets, err := scanTweets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	pageTweets, nextCursor := models.TrimPage(tweets, page.Limit)
+	if err := loadTags(ctx, repo.db, pageTweets); err != nil {
+		return nil, err
+	}
+
+	return &models.TweetsPage{Tweets: pageTweets, NextCursor: nextCursor}, nil
+}
+
+func (repo *PersistentTweetRepository) GetTweetById(ctx context.Context, id string) (*models.Tweet, error) {
+	return getTweet(ctx, repo.db, id, "")
+}
+
+// getTweet fetches a single tweet along with user details, the suffix may lock its rows
+func getTweet(ctx context.Context, q queryer, id string, suffix string) (*models.Tweet, error) {
+	row := q.QueryRowContext(ctx, selectTweetsSQL+`
+		WHERE t.id = ?
+	`+suffix, id)
+
+	// Scan the result into the tweet and user structs
+	tweet, err := scanTweet(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil
+			return nil, repoerrors.ErrNotFound
 		}
-		log.Printf("Error retrieving tweet by ID from database: %v", err)
+		return nil, mySQLError(err, "retrieving tweet by ID")
+	}
+
+	tweets := []models.Tweet{tweet}
+	if err := loadTags(ctx, q, tweets); err != nil {
+		return nil, err
+	}
+
+	return &tweets[0], nil
+}
+
+func (repo *PersistentTweetRepository) UpdateTweet(ctx context.Context, id string, updateTweetRequest models.UpdateTweetRequest, check TweetCheck) (*models.Tweet, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, mySQLError(err, "starting transaction")
+	}
+	defer tx.Rollback()
+
+	// The row stays locked until the commit, so concurrent changes build their events from the latest version
+	existingTweet, err := getTweet(ctx, tx, id, "FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckTweet(check, *existingTweet); err != nil {
+		return nil, err
+	}
+
+	tweet := ApplyUpdateTweetRequest(*existingTweet, updateTweetRequest)
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE tweets SET title = ?, content = ?
+		WHERE id = ?
+	`, tweet.Title, tweet.Content, id)
+	if err != nil {
+		return nil, mySQLError(err, "updating tweet")
+	}
+
+	// The tags are replaced as a whole
+	_, err = tx.ExecContext(ctx, "DELETE FROM tweet_tags WHERE tweet_id = ?", id)
+	if err != nil {
+		return nil, mySQLError(err, "deleting tweet tags")
+	}
+
+	if err := insertTags(ctx, tx, id, tweet.Tags); err != nil {
+		return nil, mySQLError(err, "inserting tweet tags")
+	}
+
+	event, err := TweetUpdatedOutboxEvent(*existingTweet, tweet)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertOutboxEvent(ctx, tx, event); err != nil {
+		return nil, mySQLError(err, "inserting outbox event")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, mySQLError(err, "committing tweet")
+	}
+
+	return &tweet, nil
+}
+
+func (repo *PersistentTweetRepository) DeleteTweet(ctx context.Context, id string, check TweetCheck) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return mySQLError(err, "starting transaction")
+	}
+	defer tx.Rollback()
+
+	// The deleted tweet is part of the event, its row stays locked until the commit
+	deletedTweet, err := getTweet(ctx, tx, id, "FOR UPDATE")
+	if err != nil {
+		return err
+	}
+	if err := CheckTweet(check, *deletedTweet); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tweets WHERE id = ?", id); err != nil {
+		return mySQLError(err, "deleting tweet")
+	}
+
+	event, err := TweetDeletedOutboxEvent(*deletedTweet)
+	if err != nil {
+		return err
+	}
+	if err := insertOutboxEvent(ctx, tx, event); err != nil {
+		return mySQLError(err, "inserting outbox event")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return mySQLError(err, "committing tweet deletion")
+	}
+
+	return nil
+}
+
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, event models.OutboxEvent) error {
+	_, err := tx.ExecContext(ctx, `
+	INSERT INTO tweet_outbox (id, topic, payload, created_at)
+		VALUES (?, ?, ?, ?)
+	`, event.ID, event.Topic, event.Payload, event.CreatedAt)
+	return err
+}
+
+func (repo *PersistentTweetRepository) GetOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT sequence, id, topic, payload, created_at FROM tweet_outbox
+		ORDER BY sequence
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, mySQLError(err, "retrieving outbox events")
+	}
+
+	events, _, err := scanOutboxEvents(rows)
+	return events, err
+}
+
+func (repo *PersistentTweetRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
 		return nil
 	}
 
-	// Set the user struct in the tweet
-	tweet.User = user
-	if tags.Valid {
-		tweet.Tags = strings.Split(tags.String, ",") // Split tags into an array
-	} else {
-		tweet.Tags = []string{}
+	condition, args := outboxIDsCondition(ids)
+	_, err := repo.db.ExecContext(ctx, "DELETE FROM tweet_outbox WHERE "+condition, args...)
+	if err != nil {
+		return mySQLError(err, "deleting outbox events")
 	}
 
-	return &tweet
+	return nil
 }
 
-func (repo *PersistentTweetRepository) DeleteTweet(id string) bool {
-	result, err := repo.db.Exec("DELETE FROM tweets WHERE id = ?", id)
+// ForwardOutboxEvents locks the oldest events with FOR UPDATE SKIP LOCKED until the forwarded ones
+// are deleted. Locked events are skipped, so when the oldest event is not among the rest another
+// relay is forwarding older events and none are passed.
+func (repo *PersistentTweetRepository) ForwardOutboxEvents(ctx context.Context, limit int, forward func(events []models.OutboxEvent) ([]string, error)) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("Error deleting tweet from database: %v", err)
-		return false
+		return mySQLError(err, "starting outbox transaction")
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	rows, err := tx.QueryContext(ctx, `
+		SELECT sequence, id, topic, payload, created_at FROM tweet_outbox
+		ORDER BY sequence
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED`, limit)
 	if err != nil {
-		log.Printf("Error getting rows affected after tweet deletion: %v", err)
-		return false
+		return mySQLError(err, "locking outbox events")
+	}
+	events, sequences, err := scanOutboxEvents(rows)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	var oldest int64
+	if err := tx.QueryRowContext(ctx, "SELECT MIN(sequence) FROM tweet_outbox").Scan(&oldest); err != nil {
+		return mySQLError(err, "retrieving the oldest outbox event")
+	}
+	if oldest < sequences[0] {
+		return nil
+	}
+
+	forwarded, forwardErr := forward(events)
+	if len(forwarded) > 0 {
+		condition, args := outboxIDsCondition(forwarded)
+		if _, err := tx.ExecContext(ctx, "DELETE FROM tweet_outbox WHERE "+condition, args...); err != nil {
+			return mySQLError(err, "deleting outbox events")
+		}
 	}
 
-	if rowsAffected == 0 {
-		// No tweet found with the given ID
-		return false
+	if err := tx.Commit(); err != nil {
+		return mySQLError(err, "committing forwarded outbox events")
 	}
 
-	return true
+	return forwardErr
+}
+
+// scanOutboxEvents reads and closes rows of sequence, id, topic, payload and created_at
+func scanOutboxEvents(rows *sql.Rows) ([]models.OutboxEvent, []int64, error) {
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	var sequences []int64
+	for rows.Next() {
+		var event models.OutboxEvent
+		var sequence int64
+		var createdAt models.MySQLTimestamp
+		if err := rows.Scan(&sequence, &event.ID, &event.Topic, &event.Payload, &createdAt); err != nil {
+			return nil, nil, mySQLError(err, "scanning outbox event row")
+		}
+		event.CreatedAt = createdAt.Time
+		events = append(events, event)
+		sequences = append(sequences, sequence)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, mySQLError(err, "iterating over outbox event rows")
+	}
+
+	return events, sequences, nil
+}
+
+func outboxIDsCondition(ids []string) (string, []any) {
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return "id IN (" + strings.Join(placeholders, ", ") + ")", args
 }
@@ -1,29 +1,35 @@
 package repositories_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
 	repositories "twitter-clone/internal/repositories"
+	"twitter-clone/internal/repositories/mysqldb"
+	"twitter-clone/internal/repositories/repoerrors"
 	tweetrepo "twitter-clone/internal/repositories/tweet"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// TODO: Move out connection string and database name to be read from settings or env vars
+var mySQLTweetsConfiguration = config.Configuration{
+	TweetsStorage: config.TweetsStorage{
+		Driver:           config.MySQLDriver,
+		ConnectionString: "myuser:mypassword@tcp(127.0.0.1:3306)",
+		DatabaseName:     "Tests_TweetsDb",
+		AutoMigrate:      true,
+	},
+}
+
 func setupTweetRepo() tweetrepo.TweetRepository {
 	// Setup the test database
-	// TODO: Move out connection string and database name to be read from settings or env vars
-	configuration := config.Configuration{
-		Mode: config.Persistent,
-		TweetsStorage: config.TweetsStorage{
-			ConnectionString: "myuser:mypassword@tcp(127.0.0.1:3306)",
-			DatabaseName:     "Tests_TweetsDb",
-		},
-	}
-
-	repo, err := repositories.CreateTweetRepository(configuration)
+	repo, err := repositories.CreateTweetRepository(mySQLTweetsConfiguration)
 	if err != nil {
 		fmt.Println("Failed to create tweet repository: ", err)
 		return nil
@@ -34,37 +40,144 @@ func setupTweetRepo() tweetrepo.TweetRepository {
 
 func TestCreateTweet(t *testing.T) {
 	repo := setupTweetRepo()
+	ctx := context.Background()
 
 	tweet := tweetrepo.TestCreateTweetRequest
 	user := tweetrepo.TestUser
 
 	// Test the CreateTweet method
-	createdTweet := repo.CreateTweet(tweet, user)
-	assert.NotNil(t, createdTweet, "CreateTweet should return the created tweet")
+	createdTweet, err := repo.CreateTweet(ctx, tweet, user)
+	assert.NoError(t, err, "CreateTweet should return the created tweet")
 	tweetId := createdTweet.ID
 
 	// Test GetTweets
-	allTweets := repo.GetTweets()
+	allTweets, err := repo.GetTweets(ctx)
+	assert.NoError(t, err)
 	assert.Len(t, allTweets, 1, "GetTweets should return a single tweet")
 
 	// Test GetTweetById
-	foundTweet := repo.GetTweetById(tweetId)
-	assert.NotNil(t, foundTweet, "GetTweetById should find the tweet")
+	foundTweet, err := repo.GetTweetById(ctx, tweetId)
+	assert.NoError(t, err, "GetTweetById should find the tweet")
 	assert.Equal(t, tweetId, foundTweet.ID, "Found tweet should have the same ID")
 
-	// Attempt to get a non-existing tweet by ID (should return nil)
-	nonExistingTweet := repo.GetTweetById("non-existing-id")
-	assert.Nil(t, nonExistingTweet, "GetTweetById should return nil for non-existing tweet")
+	// Attempt to get a non-existing tweet by ID
+	_, err = repo.GetTweetById(ctx, "non-existing-id")
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "GetTweetById should return ErrNotFound for non-existing tweet")
 
 	// Test DeleteTweet
-	deleted := repo.DeleteTweet(tweetId)
-	assert.True(t, deleted, "DeleteTweet should return true for successful deletion")
+	err = repo.DeleteTweet(ctx, tweetId, nil)
+	assert.NoError(t, err, "DeleteTweet should succeed for an existing tweet")
 
-	// Attempt to delete the same tweet again (should return false)
-	nonExistingDelete := repo.DeleteTweet(tweetId)
-	assert.False(t, nonExistingDelete, "DeleteTweet should return false for non-existing tweet deletion")
+	// Attempt to delete the same tweet again
+	err = repo.DeleteTweet(ctx, tweetId, nil)
+	assert.ErrorIs(t, err, repoerrors.ErrNotFound, "DeleteTweet should return ErrNotFound for non-existing tweet deletion")
 
 	// Ensure no tweets are left after deletion
-	remainingTweets := repo.GetTweets()
+	remainingTweets, err := repo.GetTweets(ctx)
+	assert.NoError(t, err)
 	assert.Len(t, remainingTweets, 0, "GetTweets should return no tweets after deletion")
 }
+
+func TestGetTweetsByTag(t *testing.T) {
+	testGetTweetsByTag(t, setupTweetRepo())
+}
+
+func TestUserSubjects(t *testing.T) {
+	testUserSubjects(t, setupTweetRepo())
+}
+
+// Rows created before users were keyed by subject have an internal ID that differs from the subject
+func TestCreateTweet_ExistingUserRow(t *testing.T) {
+	repo := setupTweetRepo()
+	ctx := context.Background()
+
+	db, err := mysqldb.Open(mySQLTweetsConfiguration.TweetsStorage.ConnectionString, mySQLTweetsConfiguration.TweetsStorage.DatabaseName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, `
+	INSERT INTO users (id, subject, first_name, last_name, email, picture)
+		VALUES ('8a4c1f7e-legacy-row', 'google|legacy', 'Old', 'Name', 'legacy@gmail.com', '')`)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.ExecContext(ctx, "DELETE FROM users WHERE subject = 'google|legacy'") })
+
+	user := models.User{ID: "google|legacy", FirstName: "New", LastName: "Name", Email: "legacy@gmail.com"}
+	created, err := repo.CreateTweet(ctx, tweetrepo.TestCreateTweetRequest, user)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.DeleteTweet(ctx, created.ID, nil) })
+	assert.Equal(t, "google|legacy", created.User.ID)
+
+	found, err := repo.GetTweetById(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "google|legacy", found.User.ID, "Read tweet should carry the subject, not the row ID")
+	assert.Equal(t, "New", found.User.FirstName, "Profile should be refreshed from the signed-in user")
+
+	var userID string
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT user_id FROM tweets WHERE id = ?", created.ID).Scan(&userID))
+	assert.Equal(t, "8a4c1f7e-legacy-row", userID, "Tweet should reference the existing user row")
+}
+
+func TestCreateTweet_LegacyUserRow(t *testing.T) {
+	repo := setupTweetRepo()
+	ctx := context.Background()
+
+	db, err := mysqldb.Open(mySQLTweetsConfiguration.TweetsStorage.ConnectionString, mySQLTweetsConfiguration.TweetsStorage.DatabaseName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Rows stored before the subject was recorded have none
+	_, err = db.ExecContext(ctx, `
+	INSERT INTO users (id, subject, first_name, last_name, email, picture)
+		VALUES ('5d2e9b0a-legacy-row', NULL, 'Old', 'Name', 'unclaimed@gmail.com', '')`)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.ExecContext(ctx, "DELETE FROM users WHERE id = '5d2e9b0a-legacy-row'") })
+	_, err = db.ExecContext(ctx, `
+	INSERT INTO tweets (id, title, content, created_at, user_id)
+		VALUES ('5d2e9b0a-legacy-tweet', 'Old', 'Old tweet', NOW(), '5d2e9b0a-legacy-row')`)
+	require.NoError(t, err)
+
+	legacy, err := repo.GetTweetById(ctx, "5d2e9b0a-legacy-tweet")
+	require.NoError(t, err)
+	assert.Empty(t, legacy.User.ID, "Tweets of unclaimed rows should fall back to the email")
+	assert.Equal(t, "unclaimed@gmail.com", legacy.User.Email)
+
+	// The first sign-in with the verified email claims the row
+	user := models.User{ID: "google|unclaimed", FirstName: "New", LastName: "Name", Email: "unclaimed@gmail.com"}
+	created, err := repo.CreateTweet(ctx, tweetrepo.TestCreateTweetRequest, user)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.DeleteTweet(ctx, created.ID, nil) })
+
+	var userID string
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT user_id FROM tweets WHERE id = ?", created.ID).Scan(&userID))
+	assert.Equal(t, "5d2e9b0a-legacy-row", userID, "Tweet should reference the claimed user row")
+
+	legacy, err = repo.GetTweetById(ctx, "5d2e9b0a-legacy-tweet")
+	require.NoError(t, err)
+	assert.Equal(t, "google|unclaimed", legacy.User.ID, "Old tweets should carry the claimed subject")
+}
+
+func TestOutboxEvents(t *testing.T) {
+	testOutboxEvents(t, setupTweetRepo())
+}
+
+func TestLockedOutboxEvents(t *testing.T) {
+	testLockedOutboxEvents(t, setupTweetRepo())
+}
+
+func TestEventStore(t *testing.T) {
+	store, err := repositories.CreateEventStore(mySQLTweetsConfiguration)
+	if err != nil {
+		t.Fatalf("Failed to create event store: %v", err)
+	}
+
+	testEventStore(t, store)
+}
+
+func TestDeadLetterStore(t *testing.T) {
+	store, err := repositories.CreateDeadLetterStore(mySQLTweetsConfiguration)
+	if err != nil {
+		t.Fatalf("Failed to create dead letter store: %v", err)
+	}
+
+	testDeadLetterStore(t, store)
+}
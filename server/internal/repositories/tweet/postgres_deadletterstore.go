@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/postgresdb"
+	"twitter-clone/internal/repositories/repoerrors"
+)
+
+// PostgresDeadLetterStore stores the dead letters in the PostgreSQL database of the tweets
+type PostgresDeadLetterStore struct {
+	db *sql.DB
+}
+
+func NewPostgresDeadLetterStore(configuration config.Configuration) (*PostgresDeadLetterStore, error) {
+	db, err := postgresdb.Open(configuration.TweetsStorage.ConnectionString, configuration.TweetsStorage.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS dead_letters (
+		id VARCHAR(36) PRIMARY KEY,
+		topic VARCHAR(255) NOT NULL,
+		handler VARCHAR(255) NOT NULL,
+		reason TEXT NOT NULL,
+		payload BYTEA NOT NULL,
+		metadata JSONB NOT NULL,
+		failed_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS dead_letters_failed_at_idx ON dead_letters (failed_at, id)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating 'dead_letters' table: %w", err)
+	}
+
+	return &PostgresDeadLetterStore{db: db}, nil
+}
+
+func (store *PostgresDeadLetterStore) AddDeadLetter(ctx context.Context, letter models.DeadLetter) error {
+	metadata, err := json.Marshal(letter.Metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.db.ExecContext(ctx, `
+	INSERT INTO dead_letters (id, topic, handler, reason, payload, metadata, failed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET topic = EXCLUDED.topic, handler = EXCLUDED.handler, reason = EXCLUDED.reason,
+			payload = EXCLUDED.payload, metadata = EXCLUDED.metadata, failed_at = EXCLUDED.failed_at
+	`, letter.ID, letter.Topic, letter.Handler, letter.Reason, []byte(letter.Payload), string(metadata), letter.FailedAt)
+	if err != nil {
+		return postgresdb.Error(err, "adding dead letter")
+	}
+
+	return nil
+}
+
+const selectPostgresDeadLettersSQL = `
+		SELECT id, topic, handler, reason, payload, metadata, failed_at FROM dead_letters`
+
+func (store *PostgresDeadLetterStore) GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	rows, err := store.db.QueryContext(ctx, selectPostgresDeadLettersSQL+`
+		ORDER BY failed_at, id`)
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving dead letters")
+	}
+	defer rows.Close()
+
+	letters := []models.DeadLetter{}
+	for rows.Next() {
+		letter, err := scanPostgresDeadLetter(rows)
+		if err != nil {
+			return nil, postgresdb.Error(err, "scanning dead letter row")
+		}
+		letters = append(letters, letter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, postgresdb.Error(err, "iterating over dead letter rows")
+	}
+
+	return letters, nil
+}
+
+func (store *PostgresDeadLetterStore) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	letter, err := scanPostgresDeadLetter(store.db.QueryRowContext(ctx, selectPostgresDeadLettersSQL+`
+		WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repoerrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving dead letter")
+	}
+
+	return &letter, nil
+}
+
+func (store *PostgresDeadLetterStore) DeleteDeadLetter(ctx context.Context, id string) error {
+	result, err := store.db.ExecContext(ctx, "DELETE FROM dead_letters WHERE id = $1", id)
+	if err != nil {
+		return postgresdb.Error(err, "deleting dead letter")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return postgresdb.Error(err, "getting rows affected after dead letter deletion")
+	}
+
+	if rowsAffected == 0 {
+		return repoerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanPostgresDeadLetter(row rowScanner) (models.DeadLetter, error) {
+	var letter models.DeadLetter
+	var payload, metadata []byte
+
+	err := row.Scan(&letter.ID, &letter.Topic, &letter.Handler, &letter.Reason, &payload, &metadata, &letter.FailedAt)
+	if err != nil {
+		return letter, err
+	}
+
+	letter.Payload = string(payload)
+	return letter, json.Unmarshal(metadata, &letter.Metadata)
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/postgresdb"
+)
+
+// PostgresEventStore stores the events in the PostgreSQL database of the tweets. Like with mysql,
+// only the outbox relay holding the locks of the forwarded outbox events appends, so appends never
+// overlap and readers never skip an event committed after a later sequence.
+type PostgresEventStore struct {
+	db *sql.DB
+}
+
+func NewPostgresEventStore(configuration config.Configuration) (*PostgresEventStore, error) {
+	db, err := postgresdb.Open(configuration.TweetsStorage.ConnectionString, configuration.TweetsStorage.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS tweet_events (
+		sequence BIGSERIAL PRIMARY KEY,
+		id VARCHAR(36) NOT NULL UNIQUE,
+		topic VARCHAR(255) NOT NULL,
+		payload BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating 'tweet_events' table: %w", err)
+	}
+
+	return &PostgresEventStore{db: db}, nil
+}
+
+func (store *PostgresEventStore) AppendEvents(ctx context.Context, events []models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(events))
+	args := make([]any, 0, len(events)*4)
+	for i, event := range events {
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d)", i*4+1, i*4+2, i*4+3, i*4+4)
+		args = append(args, event.ID, event.Topic, event.Payload, event.CreatedAt)
+	}
+
+	_, err := store.db.ExecContext(ctx, `
+	INSERT INTO tweet_events (id, topic, payload, created_at)
+		VALUES `+strings.Join(placeholders, ", ")+`
+		ON CONFLICT (id) DO NOTHING
+	`, args...)
+	if err != nil {
+		return postgresdb.Error(err, "appending events")
+	}
+
+	return nil
+}
+
+func (store *PostgresEventStore) GetEvents(ctx context.Context, since int64, limit int) ([]models.StoredEvent, error) {
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT sequence, id, topic, payload, created_at FROM tweet_events
+		WHERE sequence > $1
+		ORDER BY sequence
+		LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving events")
+	}
+	defer rows.Close()
+
+	events := []models.StoredEvent{}
+	for rows.Next() {
+		var event models.StoredEvent
+		if err := rows.Scan(&event.Sequence, &event.ID, &event.Topic, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, postgresdb.Error(err, "scanning event row")
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, postgresdb.Error(err, "iterating over event rows")
+	}
+
+	return events, nil
+}
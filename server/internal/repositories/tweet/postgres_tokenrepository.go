@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/postgresdb"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"github.com/lib/pq"
+)
+
+// PostgresTokenRepository stores API tokens in the PostgreSQL database of the tweets
+type PostgresTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTokenRepository(configuration config.Configuration) (*PostgresTokenRepository, error) {
+	db, err := postgresdb.Open(configuration.TweetsStorage.ConnectionString, configuration.TweetsStorage.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	// The user is copied onto the token, users only get a row once they tweet
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id VARCHAR(36) PRIMARY KEY,
+		token_hash CHAR(64) UNIQUE,
+		name VARCHAR(255),
+		scopes TEXT[] NOT NULL DEFAULT '{}',
+		user_id VARCHAR(255),
+		first_name VARCHAR(255),
+		last_name VARCHAR(255),
+		email VARCHAR(255),
+		picture TEXT,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ
+	);
+
+	CREATE INDEX IF NOT EXISTS api_tokens_user_id_idx ON api_tokens (user_id)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating 'api_tokens' table: %w", err)
+	}
+
+	return &PostgresTokenRepository{db: db}, nil
+}
+
+func (repo *PostgresTokenRepository) CreateToken(ctx context.Context, token models.APIToken) (*models.APIToken, error) {
+	_, err := repo.db.ExecContext(ctx, `
+	INSERT INTO api_tokens (id, token_hash, name, scopes, user_id, first_name, last_name, email, picture, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, token.ID, token.Hash, token.Name, pq.Array(emptyIfNil(token.Scopes)),
+		token.User.ID, token.User.FirstName, token.User.LastName, token.User.Email, token.User.Picture,
+		token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return nil, postgresdb.Error(err, "inserting API token")
+	}
+
+	return &token, nil
+}
+
+const selectPostgresTokensSQL = `
+		SELECT id, token_hash, name, scopes, user_id, first_name, last_name, email, picture, created_at, expires_at
+		FROM api_tokens`
+
+func scanPostgresToken(row rowScanner) (models.APIToken, error) {
+	var token models.APIToken
+	var expiresAt sql.NullTime
+
+	err := row.Scan(
+		&token.ID,
+		&token.Hash,
+		&token.Name,
+		pq.Array(&token.Scopes),
+		&token.User.ID,
+		&token.User.FirstName,
+		&token.User.LastName,
+		&token.User.Email,
+		&token.User.Picture,
+		&token.CreatedAt,
+		&expiresAt,
+	)
+	if err != nil {
+		return token, err
+	}
+
+	if len(token.Scopes) == 0 {
+		token.Scopes = nil
+	}
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+
+	return token, nil
+}
+
+func (repo *PostgresTokenRepository) GetTokensByUser(ctx context.Context, userID string) ([]models.APIToken, error) {
+	rows, err := repo.db.QueryContext(ctx, selectPostgresTokensSQL+`
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving API tokens")
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		token, err := scanPostgresToken(rows)
+		if err != nil {
+			return nil, postgresdb.Error(err, "scanning API token row")
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, postgresdb.Error(err, "iterating over API token rows")
+	}
+
+	return tokens, nil
+}
+
+func (repo *PostgresTokenRepository) GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	row := repo.db.QueryRowContext(ctx, selectPostgresTokensSQL+`
+		WHERE token_hash = $1
+	`, hash)
+
+	token, err := scanPostgresToken(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repoerrors.ErrNotFound
+		}
+		return nil, postgresdb.Error(err, "retrieving API token")
+	}
+
+	return &token, nil
+}
+
+func (repo *PostgresTokenRepository) DeleteToken(ctx context.Context, id string, userID string) error {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return postgresdb.Error(err, "deleting API token")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return postgresdb.Error(err, "getting rows affected after API token deletion")
+	}
+
+	if rowsAffected == 0 {
+		return repoerrors.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,462 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"twitter-clone/internal/config"
+	"twitter-clone/internal/models"
+	"twitter-clone/internal/repositories/postgresdb"
+	"twitter-clone/internal/repositories/repoerrors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PostgresTweetRepository stores tweets in PostgreSQL with their tags in a text[] column
+type PostgresTweetRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTweetRepository(configuration config.Configuration) (*PostgresTweetRepository, error) {
+	db, err := postgresdb.Open(configuration.TweetsStorage.ConnectionString, configuration.TweetsStorage.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %v", err)
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS users (
+		id VARCHAR(255) PRIMARY KEY,
+		subject VARCHAR(255) UNIQUE,
+		first_name VARCHAR(255),
+		last_name VARCHAR(255),
+		email VARCHAR(255),
+		picture TEXT
+	);
+
+	-- Users were keyed by email before the subject column existed, their rows are claimed by the
+	-- first sign-in with the verified email
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'subject') THEN
+			ALTER TABLE users ADD COLUMN subject VARCHAR(255) UNIQUE;
+			ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+		END IF;
+	END $$;
+
+	CREATE INDEX IF NOT EXISTS users_email_idx ON users (email);
+
+	CREATE TABLE IF NOT EXISTS tweets (
+		id VARCHAR(36) PRIMARY KEY,
+		title VARCHAR(255),
+		content TEXT,
+		created_at TIMESTAMPTZ NOT NULL,
+		user_id VARCHAR(255) REFERENCES users(id) ON DELETE CASCADE,
+		tags TEXT[] NOT NULL DEFAULT '{}'
+	);
+
+	CREATE INDEX IF NOT EXISTS tweets_created_at_id_idx ON tweets (created_at DESC, id DESC);
+	CREATE INDEX IF NOT EXISTS tweets_tags_idx ON tweets USING GIN (tags);
+
+	CREATE TABLE IF NOT EXISTS tweet_outbox (
+		sequence BIGSERIAL PRIMARY KEY,
+		id VARCHAR(36) NOT NULL UNIQUE,
+		topic VARCHAR(255) NOT NULL,
+		payload BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating tweet tables: %w", err)
+	}
+
+	return &PostgresTweetRepository{db: db}, nil
+}
+
+func (repo *PostgresTweetRepository) CreateTweet(ctx context.Context, createTweetRequest models.CreateTweetRequest, user models.User) (*models.Tweet, error) {
+	tweet := CreateNewTweet(createTweetRequest, user)
+	// Match the precision of timestamptz, so the returned tweet equals the stored one
+	tweet.CreatedAt.Time = tweet.CreatedAt.Truncate(time.Microsecond)
+
+	// The tweet and its event are stored together
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, postgresdb.Error(err, "starting transaction")
+	}
+	defer tx.Rollback()
+
+	userID, err := upsertPostgresUser(ctx, tx, tweet.User)
+	if err != nil {
+		return nil, postgresdb.Error(err, "upserting user")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO tweets (id, title, content, created_at, user_id, tags)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, tweet.ID, tweet.Title, tweet.Content, tweet.CreatedAt.Time, userID, pq.Array(emptyIfNil(tweet.Tags)))
+	if err != nil {
+		return nil, postgresdb.Error(err, "inserting tweet")
+	}
+
+	event, err := TweetCreatedOutboxEvent(tweet)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertPostgresOutboxEvent(ctx, tx, event); err != nil {
+		return nil, postgresdb.Error(err, "inserting outbox event")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, postgresdb.Error(err, "committing tweet")
+	}
+
+	return &tweet, nil
+}
+
+// upsertPostgresUser returns the internal key of the user with the given provider subject,
+// users without a subject only exist when authentication is disabled and get a row per tweet
+func upsertPostgresUser(ctx context.Context, tx *sql.Tx, user models.User) (string, error) {
+	var subject sql.NullString
+	if user.ID != "" {
+		subject = sql.NullString{String: user.ID, Valid: true}
+	}
+
+	// The row of a user stored before subjects were recorded gets the subject of the first sign-in
+	// with its email, emails are only set when the provider verified them
+	if user.ID != "" && user.Email != "" {
+		_, err := tx.ExecContext(ctx, `
+		UPDATE users SET subject = $1
+			WHERE id = (SELECT id FROM users WHERE subject IS NULL AND email = $2 LIMIT 1)
+				AND NOT EXISTS (SELECT 1 FROM users WHERE subject = $1)
+		`, user.ID, user.Email)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var userID string
+	err := tx.QueryRowContext(ctx, `
+	INSERT INTO users (id, subject, first_name, last_name, email, picture)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (subject) DO UPDATE SET first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name,
+			email = EXCLUDED.email, picture = EXCLUDED.picture
+		RETURNING id
+	`, uuid.NewString(), subject, user.FirstName, user.LastName, user.Email, user.Picture).Scan(&userID)
+	return userID, err
+}
+
+const selectPostgresTweetsSQL = `
+		SELECT t.id, t.title, t.content, t.created_at,
+		       u.subject, u.first_name, u.last_name, u.email, u.picture,
+		       t.tags
+		FROM tweets t
+		JOIN users u ON t.user_id = u.id`
+
+func scanPostgresTweet(row rowScanner) (models.Tweet, error) {
+	var tweet models.Tweet
+	var subject, firstName, lastName, email, picture sql.NullString
+
+	err := row.Scan(
+		&tweet.ID,
+		&tweet.Title,
+		&tweet.Content,
+		&tweet.CreatedAt,
+		&subject,
+		&firstName,
+		&lastName,
+		&email,
+		&picture,
+		pq.Array(&tweet.Tags),
+	)
+	if err != nil {
+		return tweet, err
+	}
+
+	tweet.User.ID = subject.String
+	tweet.User.FirstName = firstName.String
+	tweet.User.LastName = lastName.String
+	tweet.User.Email = email.String
+	tweet.User.Picture = picture.String
+	tweet.Tags = emptyIfNil(tweet.Tags)
+
+	return tweet, nil
+}
+
+func scanPostgresTweets(rows *sql.Rows) ([]models.Tweet, error) {
+	defer rows.Close()
+
+	var tweets []models.Tweet
+	for rows.Next() {
+		tweet, err := scanPostgresTweet(rows)
+		if err != nil {
+			return nil, postgresdb.Error(err, "scanning tweet row")
+		}
+		tweets = append(tweets, tweet)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, postgresdb.Error(err, "iterating over tweet rows")
+	}
+
+	return tweets, nil
+}
+
+func (repo *PostgresTweetRepository) GetTweets(ctx context.Context) ([]models.Tweet, error) {
+	rows, err := repo.db.QueryContext(ctx, selectPostgresTweetsSQL+`
+		ORDER BY t.created_at, t.id`)
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving tweets")
+	}
+
+	return scanPostgresTweets(rows)
+}
+
+func (repo *PostgresTweetRepository) GetTweetsPage(ctx context.Context, page models.PageRequest) (*models.TweetsPage, error) {
+	return repo.queryTweetsPage(ctx, page, nil, nil)
+}
+
+// GetTweetsByTag uses the GIN index on the tags column
+func (repo *PostgresTweetRepository) GetTweetsByTag(ctx context.Context, tag string, page models.PageRequest) (*models.TweetsPage, error) {
+	conditions := []string{"t.tags @> ARRAY[$1]::text[]"}
+	return repo.queryTweetsPage(ctx, page, conditions, []any{tag})
+}
+
+// queryTweetsPage selects a page of the tweets matching all conditions, newest first.
+// Conditions number their parameters after the args given with them.
+func (repo *PostgresTweetRepository) queryTweetsPage(ctx context.Context, page models.PageRequest, conditions []string, args []any) (*models.TweetsPage, error) {
+	query := selectPostgresTweetsSQL
+
+	if page.Cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(t.created_at, t.id) < ($%d, $%d)", len(args)+1, len(args)+2))
+		args = append(args, page.Cursor.CreatedAt, page.Cursor.ID)
+	}
+
+	if len(conditions) > 0 {
+		query += `
+		WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	// One extra row tells whether there is a next page
+	query += fmt.Sprintf(`
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT $%d`, len(args)+1)
+	args = append(args, page.Limit+1)
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving tweets page")
+	}
+
+	tweets, err := scanPostgresTweets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	pageTweets, nextCursor := models.TrimPage(tweets, page.Limit)
+	return &models.TweetsPage{Tweets: pageTweets, NextCursor: nextCursor}, nil
+}
+
+func (repo *PostgresTweetRepository) GetTweetById(ctx context.Context, id string) (*models.Tweet, error) {
+	return getPostgresTweet(ctx, repo.db, id, "")
+}
+
+// getPostgresTweet fetches a single tweet along with user details, the suffix may lock its row
+func getPostgresTweet(ctx context.Context, q queryer, id string, suffix string) (*models.Tweet, error) {
+	row := q.QueryRowContext(ctx, selectPostgresTweetsSQL+`
+		WHERE t.id = $1 `+suffix, id)
+
+	tweet, err := scanPostgresTweet(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repoerrors.ErrNotFound
+		}
+		return nil, postgresdb.Error(err, "retrieving tweet by ID")
+	}
+
+	return &tweet, nil
+}
+
+func (repo *PostgresTweetRepository) UpdateTweet(ctx context.Context, id string, updateTweetRequest models.UpdateTweetRequest, check TweetCheck) (*models.Tweet, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, postgresdb.Error(err, "starting transaction")
+	}
+	defer tx.Rollback()
+
+	// The row stays locked until the commit, so concurrent changes build their events from the latest version
+	existingTweet, err := getPostgresTweet(ctx, tx, id, "FOR UPDATE OF t")
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckTweet(check, *existingTweet); err != nil {
+		return nil, err
+	}
+
+	tweet := ApplyUpdateTweetRequest(*existingTweet, updateTweetRequest)
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE tweets SET title = $1, content = $2, tags = $3
+		WHERE id = $4
+	`, tweet.Title, tweet.Content, pq.Array(emptyIfNil(tweet.Tags)), id)
+	if err != nil {
+		return nil, postgresdb.Error(err, "updating tweet")
+	}
+
+	event, err := TweetUpdatedOutboxEvent(*existingTweet, tweet)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertPostgresOutboxEvent(ctx, tx, event); err != nil {
+		return nil, postgresdb.Error(err, "inserting outbox event")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, postgresdb.Error(err, "committing tweet")
+	}
+
+	return &tweet, nil
+}
+
+func (repo *PostgresTweetRepository) DeleteTweet(ctx context.Context, id string, check TweetCheck) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return postgresdb.Error(err, "starting transaction")
+	}
+	defer tx.Rollback()
+
+	// The deleted tweet is part of the event, its row stays locked until the commit
+	deletedTweet, err := getPostgresTweet(ctx, tx, id, "FOR UPDATE OF t")
+	if err != nil {
+		return err
+	}
+	if err := CheckTweet(check, *deletedTweet); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tweets WHERE id = $1", id); err != nil {
+		return postgresdb.Error(err, "deleting tweet")
+	}
+
+	event, err := TweetDeletedOutboxEvent(*deletedTweet)
+	if err != nil {
+		return err
+	}
+	if err := insertPostgresOutboxEvent(ctx, tx, event); err != nil {
+		return postgresdb.Error(err, "inserting outbox event")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return postgresdb.Error(err, "committing tweet deletion")
+	}
+
+	return nil
+}
+
+func insertPostgresOutboxEvent(ctx context.Context, tx *sql.Tx, event models.OutboxEvent) error {
+	_, err := tx.ExecContext(ctx, `
+	INSERT INTO tweet_outbox (id, topic, payload, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, event.ID, event.Topic, event.Payload, event.CreatedAt)
+	return err
+}
+
+func (repo *PostgresTweetRepository) GetOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT sequence, id, topic, payload, created_at FROM tweet_outbox
+		ORDER BY sequence
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, postgresdb.Error(err, "retrieving outbox events")
+	}
+
+	events, _, err := scanPostgresOutboxEvents(rows)
+	return events, err
+}
+
+func (repo *PostgresTweetRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	_, err := repo.db.ExecContext(ctx, "DELETE FROM tweet_outbox WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return postgresdb.Error(err, "deleting outbox events")
+	}
+
+	return nil
+}
+
+// ForwardOutboxEvents locks the oldest events with FOR UPDATE SKIP LOCKED until the forwarded ones
+// are deleted. Locked events are skipped, so when the oldest event is not among the rest another
+// relay is forwarding older events and none are passed.
+func (repo *PostgresTweetRepository) ForwardOutboxEvents(ctx context.Context, limit int, forward func(events []models.OutboxEvent) ([]string, error)) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return postgresdb.Error(err, "starting outbox transaction")
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT sequence, id, topic, payload, created_at FROM tweet_outbox
+		ORDER BY sequence
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return postgresdb.Error(err, "locking outbox events")
+	}
+	events, sequences, err := scanPostgresOutboxEvents(rows)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	var oldest int64
+	if err := tx.QueryRowContext(ctx, "SELECT MIN(sequence) FROM tweet_outbox").Scan(&oldest); err != nil {
+		return postgresdb.Error(err, "retrieving the oldest outbox event")
+	}
+	if oldest < sequences[0] {
+		return nil
+	}
+
+	forwarded, forwardErr := forward(events)
+	if len(forwarded) > 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM tweet_outbox WHERE id = ANY($1)", pq.Array(forwarded)); err != nil {
+			return postgresdb.Error(err, "deleting outbox events")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return postgresdb.Error(err, "committing forwarded outbox events")
+	}
+
+	return forwardErr
+}
+
+// scanPostgresOutboxEvents reads and closes rows of sequence, id, topic, payload and created_at
+func scanPostgresOutboxEvents(rows *sql.Rows) ([]models.OutboxEvent, []int64, error) {
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	var sequences []int64
+	for rows.Next() {
+		var event models.OutboxEvent
+		var sequence int64
+		if err := rows.Scan(&sequence, &event.ID, &event.Topic, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, nil, postgresdb.Error(err, "scanning outbox event row")
+		}
+		events = append(events, event)
+		sequences = append(sequences, sequence)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, postgresdb.Error(err, "iterating over outbox event rows")
+	}
+
+	return events, sequences, nil
+}
+
+// emptyIfNil avoids storing NULL arrays for tweets without tags and tokens without scopes
+func emptyIfNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
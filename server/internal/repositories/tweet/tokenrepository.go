@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"context"
+	"twitter-clone/internal/models"
+)
+
+// TokenRepository stores personal API tokens next to the tweets of their users.
+// It reports errors the same way as TweetRepository.
+type TokenRepository interface {
+	CreateToken(ctx context.Context, token models.APIToken) (*models.APIToken, error)
+	GetTokensByUser(ctx context.Context, userID string) ([]models.APIToken, error)
+	GetTokenByHash(ctx context.Context, hash string) (*models.APIToken, error)
+	DeleteToken(ctx context.Context, id string, userID string) error
+}
@@ -2,18 +2,41 @@ package repositories
 
 import (
 	"time"
+	"twitter-clone/internal/hashtags"
 	"twitter-clone/internal/models"
 
 	"github.com/google/uuid"
 )
 
+// CreateNewTweet tags the tweet with the explicit tags and the hashtags of its title and content, normalized
 func CreateNewTweet(createTweetRequest models.CreateTweetRequest, user models.User) models.Tweet {
 	return models.Tweet{
 		ID:        uuid.NewString(),
 		Title:     createTweetRequest.Title,
 		Content:   createTweetRequest.Content,
-		Tags:      createTweetRequest.Tags,
+		Tags:      hashtags.Merge(createTweetRequest.Tags, createTweetRequest.Title, createTweetRequest.Content),
 		CreatedAt: models.MySQLTimestamp{Time: time.Now()},
 		User:      user,
 	}
 }
+
+// ApplyUpdateTweetRequest tags the tweet like CreateNewTweet, so editing a hashtag moves the tweet between feeds
+func ApplyUpdateTweetRequest(tweet models.Tweet, updateTweetRequest models.UpdateTweetRequest) models.Tweet {
+	tweet.Title = updateTweetRequest.Title
+	tweet.Content = updateTweetRequest.Content
+	tweet.Tags = hashtags.Merge(updateTweetRequest.Tags, updateTweetRequest.Title, updateTweetRequest.Content)
+	return tweet
+}
+
+// TweetCheck is run on the stored tweet within the transaction of its update or deletion, before the
+// change is written. Its error aborts the change and is returned unchanged, the API denies the
+// change with it when the user may not make it.
+type TweetCheck func(tweet models.Tweet) error
+
+// CheckTweet runs the check when one is given
+func CheckTweet(check TweetCheck, tweet models.Tweet) error {
+	if check == nil {
+		return nil
+	}
+	return check(tweet)
+}
@@ -1,10 +1,29 @@
 package repositories
 
-import "twitter-clone/internal/models"
+import (
+	"context"
+	"twitter-clone/internal/models"
+)
 
+// TweetRepository returns repoerrors.ErrNotFound for missing tweets, repoerrors.ErrConflict
+// for duplicates and wraps storage failures with repoerrors.ErrUnavailable
 type TweetRepository interface {
-	CreateTweet(tweet models.CreateTweetRequest, user models.User) *models.Tweet
-	GetTweets() []models.Tweet
-	GetTweetById(id string) *models.Tweet
-	DeleteTweet(id string) bool
+	CreateTweet(ctx context.Context, tweet models.CreateTweetRequest, user models.User) (*models.Tweet, error)
+	GetTweets(ctx context.Context) ([]models.Tweet, error)
+	GetTweetsPage(ctx context.Context, page models.PageRequest) (*models.TweetsPage, error)
+	GetTweetsByTag(ctx context.Context, tag string, page models.PageRequest) (*models.TweetsPage, error)
+	GetTweetById(ctx context.Context, id string) (*models.Tweet, error)
+	// UpdateTweet and DeleteTweet read the tweet in the transaction of the change, the check and
+	// the event see the version which is changed
+	UpdateTweet(ctx context.Context, id string, tweet models.UpdateTweetRequest, check TweetCheck) (*models.Tweet, error)
+	DeleteTweet(ctx context.Context, id string, check TweetCheck) error
+
+	// The changes record their TweetCreated, TweetUpdated and TweetDeleted events in the outbox
+	// atomically, the events are read oldest first and deleted once they were published
+	GetOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	DeleteOutboxEvents(ctx context.Context, ids []string) error
+	// ForwardOutboxEvents passes up to limit of the oldest events to forward and deletes the ids it
+	// returns, also when it fails. The mysql and postgres storages lock the events meanwhile and pass
+	// none while another relay holds older events, so replicas forward them once and in order.
+	ForwardOutboxEvents(ctx context.Context, limit int, forward func(events []models.OutboxEvent) ([]string, error)) error
 }
@@ -0,0 +1,218 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"twitter-clone/internal/messaging"
+	"twitter-clone/internal/models"
+	repositories "twitter-clone/internal/repositories/tweet"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testGetTweetsByTag runs the tag queries against any repository, the created tweets are deleted afterwards
+func testGetTweetsByTag(t *testing.T, repo repositories.TweetRepository) {
+	ctx := context.Background()
+
+	// Commas are part of the tag, not a separator
+	tagSets := [][]string{{"golang", "news"}, {"news"}, {"golang"}, {"go,lang", "news"}}
+	var ids []string
+	for _, tags := range tagSets {
+		tweet, err := repo.CreateTweet(ctx, models.CreateTweetRequest{Title: "title", Content: "content", Tags: tags}, repositories.TestUser)
+		require.NoError(t, err)
+		ids = append(ids, tweet.ID)
+	}
+	t.Cleanup(func() {
+		for _, id := range ids {
+			repo.DeleteTweet(ctx, id, nil)
+		}
+	})
+
+	// Walk through all pages of a tag
+	tweetsByTag := func(tag string) []models.Tweet {
+		var tweets []models.Tweet
+		page := models.PageRequest{Limit: 2}
+		for {
+			tweetsPage, err := repo.GetTweetsByTag(ctx, tag, page)
+			require.NoError(t, err, "GetTweetsByTag should return a page")
+			assert.LessOrEqual(t, len(tweetsPage.Tweets), 2, "Page should not exceed the limit")
+			tweets = append(tweets, tweetsPage.Tweets...)
+
+			if tweetsPage.NextCursor == "" {
+				return tweets
+			}
+			page.Cursor, err = models.DecodeCursor(tweetsPage.NextCursor)
+			require.NoError(t, err)
+		}
+	}
+
+	news := tweetsByTag("news")
+	assert.Len(t, news, 3, "Expected 3 tweets tagged news")
+	for i := 1; i < len(news); i++ {
+		assert.True(t, models.CursorOf(news[i-1]).Follows(news[i]), "Tweets should be ordered newest first")
+	}
+
+	golang := tweetsByTag("golang")
+	assert.Len(t, golang, 2, "Expected 2 tweets tagged golang")
+
+	commaTagged := tweetsByTag("go,lang")
+	require.Len(t, commaTagged, 1, "Expected the tweet tagged go,lang")
+	assert.Equal(t, []string{"go,lang", "news"}, commaTagged[0].Tags, "Tags should keep their order and commas")
+
+	assert.Empty(t, tweetsByTag("go"), "Tags should only match exactly")
+
+	// Updated tags are reflected in the tag queries
+	_, err := repo.UpdateTweet(ctx, ids[1], models.UpdateTweetRequest{Title: "title", Content: "content", Tags: []string{"golang"}}, nil)
+	require.NoError(t, err)
+	assert.Len(t, tweetsByTag("news"), 2, "Updated tweet should no longer be tagged news")
+	assert.Len(t, tweetsByTag("golang"), 3, "Updated tweet should be tagged golang")
+}
+
+// testUserSubjects checks that authors are identified by their provider subject, even when they share an email
+func testUserSubjects(t *testing.T, repo repositories.TweetRepository) {
+	ctx := context.Background()
+
+	users := []models.User{
+		{ID: "google|1234", FirstName: "Alice", Email: "alice@gmail.com"},
+		{ID: "github|5678", FirstName: "Mallory", Email: "alice@gmail.com"},
+	}
+	for _, user := range users {
+		created, err := repo.CreateTweet(ctx, repositories.TestCreateTweetRequest, user)
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.DeleteTweet(ctx, created.ID, nil) })
+		assert.Equal(t, user.ID, created.User.ID, "Created tweet should carry the subject of its author")
+
+		found, err := repo.GetTweetById(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.User.ID, "Stored tweet should carry the subject of its author")
+		assert.Equal(t, user.FirstName, found.User.FirstName, "Users sharing an email should not be merged")
+	}
+}
+
+// testOutboxEvents checks that every tweet change records its event in the outbox, oldest first
+func testOutboxEvents(t *testing.T, repo repositories.TweetRepository) {
+	ctx := context.Background()
+
+	// Forget the events of earlier tests sharing the database
+	pending, err := repo.GetOutboxEvents(ctx, 1000)
+	require.NoError(t, err)
+	for _, event := range pending {
+		require.NoError(t, repo.DeleteOutboxEvents(ctx, []string{event.ID}))
+	}
+
+	tweet, err := repo.CreateTweet(ctx, repositories.TestCreateTweetRequest, repositories.TestUser)
+	require.NoError(t, err)
+
+	// Denied changes are aborted with the error of the check, which sees the stored tweet
+	errDenied := errors.New("denied")
+	deny := func(stored models.Tweet) error {
+		assert.Equal(t, tweet.ID, stored.ID)
+		assert.Equal(t, tweet.Content, stored.Content)
+		return errDenied
+	}
+	_, err = repo.UpdateTweet(ctx, tweet.ID, models.UpdateTweetRequest{Content: "denied content"}, deny)
+	require.ErrorIs(t, err, errDenied)
+	require.ErrorIs(t, repo.DeleteTweet(ctx, tweet.ID, deny), errDenied)
+	stored, err := repo.GetTweetById(ctx, tweet.ID)
+	require.NoError(t, err, "Denied deletion should keep the tweet")
+	assert.Equal(t, tweet.Content, stored.Content, "Denied update should keep the content")
+
+	_, err = repo.UpdateTweet(ctx, tweet.ID, models.UpdateTweetRequest{Title: "title", Content: "new content", Tags: []string{"golang"}}, nil)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTweet(ctx, tweet.ID, nil))
+
+	// Failed changes record no event
+	_, err = repo.UpdateTweet(ctx, tweet.ID, models.UpdateTweetRequest{Content: "content"}, nil)
+	require.Error(t, err)
+
+	events, err := repo.GetOutboxEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3, "Expected an event per change")
+	assert.Equal(t, messaging.TweetCreatedTopic, events[0].Topic)
+	assert.Equal(t, messaging.TweetUpdatedTopic, events[1].Topic)
+	assert.Equal(t, messaging.TweetDeletedTopic, events[2].Topic)
+
+	var updated messaging.TweetUpdated
+	require.NoError(t, messaging.JSONCodec{}.Decode(events[1].Payload, messaging.TweetUpdatedTopic, &updated))
+	assert.Equal(t, tweet.ID, updated.NewTweet.ID)
+	assert.Equal(t, repositories.TestCreateTweetRequest.Tags, updated.OriginalTweet.Tags, "Updated event should carry the original tweet")
+	assert.Equal(t, []string{"golang"}, updated.NewTweet.Tags)
+
+	// The limit keeps the oldest events
+	firstEvents, err := repo.GetOutboxEvents(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, firstEvents, 2)
+	assert.Equal(t, events[0].ID, firstEvents[0].ID)
+
+	require.NoError(t, repo.DeleteOutboxEvents(ctx, []string{events[0].ID, events[1].ID}))
+	events, err = repo.GetOutboxEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1, "Deleted events should be gone")
+	assert.Equal(t, messaging.TweetDeletedTopic, events[0].Topic)
+
+	// Forwarded events are deleted even when forwarding fails afterwards
+	forwardErr := errors.New("broker unavailable")
+	err = repo.ForwardOutboxEvents(ctx, 10, func(passed []models.OutboxEvent) ([]string, error) {
+		require.Len(t, passed, 1)
+		assert.Equal(t, events[0].ID, passed[0].ID)
+		return []string{passed[0].ID}, forwardErr
+	})
+	assert.ErrorIs(t, err, forwardErr)
+	events, err = repo.GetOutboxEvents(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+// testLockedOutboxEvents checks that a relay forwards no events while another relay holds older ones
+func testLockedOutboxEvents(t *testing.T, repo repositories.TweetRepository) {
+	ctx := context.Background()
+
+	pending, err := repo.GetOutboxEvents(ctx, 1000)
+	require.NoError(t, err)
+	for _, event := range pending {
+		require.NoError(t, repo.DeleteOutboxEvents(ctx, []string{event.ID}))
+	}
+
+	for range 3 {
+		_, err := repo.CreateTweet(ctx, repositories.TestCreateTweetRequest, repositories.TestUser)
+		require.NoError(t, err)
+	}
+
+	holding := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- repo.ForwardOutboxEvents(ctx, 1, func(events []models.OutboxEvent) ([]string, error) {
+			close(holding)
+			<-release
+			return []string{events[0].ID}, nil
+		})
+	}()
+	<-holding
+
+	passed := 0
+	require.NoError(t, repo.ForwardOutboxEvents(ctx, 10, func(events []models.OutboxEvent) ([]string, error) {
+		passed = len(events)
+		return nil, nil
+	}))
+	assert.Zero(t, passed, "Later events should not be passed while the oldest is forwarded")
+
+	close(release)
+	require.NoError(t, <-done)
+
+	require.NoError(t, repo.ForwardOutboxEvents(ctx, 10, func(events []models.OutboxEvent) ([]string, error) {
+		passed = len(events)
+		ids := make([]string, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return ids, nil
+	}))
+	assert.Equal(t, 2, passed)
+
+	pending, err = repo.GetOutboxEvents(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}